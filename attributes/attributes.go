@@ -0,0 +1,144 @@
+package attributes
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/codegangsta/cli"
+	"github.com/flexiant/digger"
+	"github.com/flexiant/kdeploy/fetchers"
+	"github.com/flexiant/kdeploy/outputs"
+	"github.com/flexiant/kdeploy/template"
+	"github.com/flexiant/kdeploy/utils"
+	"github.com/flexiant/kdeploy/webservice"
+	gyml "github.com/ghodss/yaml"
+)
+
+// Attribute is a resolved attribute of a kubeware
+type Attribute struct {
+	Path        string      `json:"path"`
+	Value       interface{} `json:"value"`
+	Description string      `json:"description,omitempty"`
+	Required    bool        `json:"required"`
+	Source      string      `json:"source"`
+}
+
+// CmdAttributes implements the 'attributes' command
+func CmdAttributes(c *cli.Context) {
+	utils.CheckRequiredFlags(c, []string{"kubeware"})
+
+	kubeware := os.Getenv("KDEPLOY_KUBEWARE")
+	fetched, err := fetchers.Fetch(kubeware)
+	if err != nil {
+		log.Fatal(fmt.Errorf("Could not fetch kubeware: '%s' (%v)", kubeware, err))
+	}
+	defer fetched.Close()
+
+	metadata := template.ParseMetadata(fetched.Path)
+	metadata.Instance = os.Getenv("KDEPLOY_INSTANCE")
+	// outputs of the dependencies are read from those deployed, as deploy does
+	var collected map[string]map[string]interface{}
+	if len(metadata.Dependencies) > 0 {
+		kubernetes, err := webservice.NewKubeClient()
+		utils.CheckError(err)
+		collected, err = outputs.DependencyOutputs(kubernetes, os.Getenv("KDEPLOY_NAMESPACE"), metadata)
+		utils.CheckError(err)
+	}
+	resolved, err := template.ResolveAttributesWithOutputs(c.StringSlice("attribute"), c.StringSlice("set"), collected, metadata)
+	utils.CheckError(err)
+	attributes, err := Build(resolved, metadata)
+	utils.CheckError(err)
+
+	switch c.String("output") {
+	case "json":
+		out, err := json.MarshalIndent(attributes, "", "  ")
+		utils.CheckError(err)
+		fmt.Println(utils.MaskSecrets(string(out)))
+	case "yaml":
+		out, err := gyml.Marshal(attributes)
+		utils.CheckError(err)
+		fmt.Print(utils.MaskSecrets(string(out)))
+	default:
+		printTable(os.Stdout, attributes)
+	}
+
+	// values are shown even if invalid, since that's usually why they are being looked at
+	if err := metadata.ValidateAttributes(resolved.Values); err != nil {
+		log.Warn(err)
+	}
+}
+
+// Build lists every attribute declared in the metadata or set by some layer, by its
+// 'type/resource/attribute' path
+func Build(resolved *template.ResolvedAttributes, md template.Metadata) ([]Attribute, error) {
+	d, err := digger.NewMapDigger(resolved.Values)
+	if err != nil {
+		return nil, err
+	}
+	declared := map[string]template.SingleAttributeMetadata{}
+	for resourceType, resources := range md.Attributes {
+		for resourceName, attrs := range resources {
+			for attributeName, attrMetadata := range attrs {
+				declared[fmt.Sprintf("%s/%s/%s", resourceType, resourceName, attributeName)] = attrMetadata
+			}
+		}
+	}
+	paths := map[string]bool{}
+	for path := range declared {
+		paths[path] = true
+	}
+	for _, path := range resolved.Paths() {
+		// values below an attribute, such as the keys of a map, belong to it
+		parts := strings.SplitN(path, "/", 4)
+		if len(parts) > 3 {
+			parts = parts[:3]
+		}
+		paths[strings.Join(parts, "/")] = true
+	}
+
+	attributes := []Attribute{}
+	for path := range paths {
+		value, _ := d.Get(path)
+		attributes = append(attributes, Attribute{
+			Path:        path,
+			Value:       value,
+			Description: declared[path].Description,
+			Required:    declared[path].Required,
+			Source:      resolved.Source(path),
+		})
+	}
+	sort.Sort(byPath(attributes))
+	return attributes, nil
+}
+
+func printTable(out io.Writer, attributes []Attribute) {
+	w := tabwriter.NewWriter(out, 10, 1, 3, ' ', 0)
+	fmt.Fprintln(w, "ATTRIBUTE\tVALUE\tREQUIRED\tSOURCE\tDESCRIPTION")
+	for _, a := range attributes {
+		value := ""
+		if s, ok := a.Value.(string); ok {
+			value = s
+		} else if a.Value != nil {
+			v, _ := json.Marshal(a.Value)
+			value = string(v)
+		}
+		source := a.Source
+		if source == "" {
+			source = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%t\t%s\t%s\n", a.Path, utils.MaskSecrets(value), a.Required, source, a.Description)
+	}
+	w.Flush()
+}
+
+type byPath []Attribute
+
+func (a byPath) Len() int           { return len(a) }
+func (a byPath) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a byPath) Less(i, j int) bool { return a[i].Path < a[j].Path }
@@ -0,0 +1,83 @@
+package attributes
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/flexiant/kdeploy/template"
+	"gopkg.in/yaml.v2"
+)
+
+const metadataSample = `
+name: "Guestbook"
+version: "0.0.1"
+attributes:
+  svc:
+    frontend:
+      port:
+        description: "Defines expose port for the Frontend Service"
+        default: 80
+        required: true
+  rc:
+    frontend:
+      number:
+        default: 2
+      image:
+        required: true
+`
+
+func TestBuild(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kdeploy-attributes")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "prod.yaml")
+	ioutil.WriteFile(file, []byte("rc:\n  frontend:\n    env:\n      TIER: frontend\n"), 0644)
+
+	var md template.Metadata
+	err = yaml.Unmarshal([]byte(metadataSample), &md)
+	if err != nil {
+		t.Fatalf("error parsing metadata: %v", err)
+	}
+	resolved, err := template.ResolveAttributes([]string{file}, []string{"rc/frontend/number=4"}, md)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	attributes, err := Build(resolved, md)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []Attribute{
+		{Path: "rc/frontend/env", Source: file},
+		{Path: "rc/frontend/image", Required: true},
+		{Path: "rc/frontend/number", Value: 4, Source: template.SourceSet},
+		{Path: "svc/frontend/port", Value: 80, Required: true, Source: template.SourceDefault, Description: "Defines expose port for the Frontend Service"},
+	}
+	if len(attributes) != len(expected) {
+		t.Fatalf("expected %d attributes, got %v", len(expected), attributes)
+	}
+	for i, e := range expected {
+		a := attributes[i]
+		if a.Path != e.Path || a.Source != e.Source || a.Required != e.Required || a.Description != e.Description {
+			t.Errorf("expected %+v, got %+v", e, a)
+		}
+		if e.Value != nil && a.Value != e.Value {
+			t.Errorf("expected %s to be %#v, got %#v", e.Path, e.Value, a.Value)
+		}
+	}
+	if attributes[1].Value != nil {
+		t.Errorf("expected rc/frontend/image to be unset, got %#v", attributes[1].Value)
+	}
+
+	var out bytes.Buffer
+	printTable(&out, attributes)
+	if !strings.Contains(out.String(), `{"TIER":"frontend"}`) {
+		t.Errorf("expected map values to be printed as JSON:\n%s", out.String())
+	}
+}
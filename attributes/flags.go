@@ -0,0 +1,85 @@
+package attributes
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/codegangsta/cli"
+	"github.com/flexiant/kdeploy/fetchers"
+	"github.com/flexiant/kdeploy/utils"
+)
+
+// Flags builds a spec of the flags available for the command
+func Flags() []cli.Flag {
+	return []cli.Flag{
+		cli.StringSliceFlag{
+			Name:   "attribute, a",
+			Usage:  "Attributes file, JSON or YAML. Repeat to merge several, left to right",
+			EnvVar: "KDEPLOY_ATTRIBUTE",
+		},
+		cli.StringSliceFlag{
+			Name:  "set",
+			Usage: "Attribute override applied last, as path=value (e.g. rc/frontend/number=3)",
+		},
+		cli.StringFlag{
+			Name:   "kubeware, k",
+			Usage:  "Kubeware path",
+			EnvVar: "KDEPLOY_KUBEWARE",
+		},
+		cli.StringFlag{
+			Name:   "sha256",
+			Usage:  "Expected SHA-256 checksum of the kubeware archive",
+			EnvVar: "KDEPLOY_SHA256",
+		},
+		cli.StringFlag{
+			Name:   "namespace, n",
+			Usage:  "Namespace the dependencies of the Kubeware are deployed in, to read their outputs",
+			Value:  "default",
+			EnvVar: "KDEPLOY_NAMESPACE",
+		},
+		cli.StringFlag{
+			Name:   "instance, i",
+			Usage:  "Instance of the Kubeware, whose dependencies are deployed as the same instance",
+			EnvVar: "KDEPLOY_INSTANCE",
+		},
+		cli.StringFlag{
+			Name:  "output, o",
+			Usage: "Output format: table, json or yaml",
+			Value: "table",
+		},
+	}
+}
+
+// PrepareFlags processes the flags
+func PrepareFlags(c *cli.Context) error {
+	if len(c.StringSlice("attribute")) > 0 {
+		os.Setenv("KDEPLOY_ATTRIBUTE", strings.Join(c.StringSlice("attribute"), ","))
+	}
+
+	if c.String("kubeware") != "" {
+		kubeware, err := fetchers.WithChecksum(c.String("kubeware"), c.String("sha256"))
+		if err != nil {
+			return err
+		}
+		os.Setenv("KDEPLOY_KUBEWARE", kubeware)
+	}
+
+	if c.String("instance") != "" {
+		err := utils.ValidateInstance(c.String("instance"))
+		if err != nil {
+			return err
+		}
+		os.Setenv("KDEPLOY_INSTANCE", c.String("instance"))
+	}
+
+	os.Setenv("KDEPLOY_NAMESPACE", c.String("namespace"))
+
+	switch c.String("output") {
+	case "table", "json", "yaml":
+	default:
+		return fmt.Errorf("unknown output format '%s'", c.String("output"))
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package cache
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/codegangsta/cli"
+	"github.com/flexiant/kdeploy/fetchers"
+	"github.com/flexiant/kdeploy/utils"
+)
+
+// CmdList implements 'cache list' command
+func CmdList(c *cli.Context) {
+	cache, err := fetchers.DefaultCache()
+	utils.CheckError(err)
+	entries, err := cache.Entries()
+	utils.CheckError(err)
+
+	if len(entries) == 0 {
+		log.Infof("No Kubeware cached in %s", cache.Dir)
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 10, 1, 5, ' ', 0)
+	fmt.Fprintln(w, "SOURCE\tCOMMIT\tDIGEST\tFETCHED\tEXPIRED\r")
+	for _, entry := range entries {
+		expired := "no"
+		if cache.Expired(&entry) {
+			expired = "yes"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", entry.Source, shorten(entry.Commit), shorten(entry.Digest), age(entry.Fetched), expired)
+	}
+	w.Flush()
+}
+
+// CmdPrune implements 'cache prune' command
+func CmdPrune(c *cli.Context) {
+	cache, err := fetchers.DefaultCache()
+	utils.CheckError(err)
+	removed, err := cache.Prune(c.Bool("all"))
+	utils.CheckError(err)
+
+	for _, source := range removed {
+		log.Debugf("Removed %s from cache", source)
+	}
+	log.Infof("Removed %d Kubewares from cache", len(removed))
+}
+
+func shorten(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
+
+func age(fetched string) string {
+	t, err := time.Parse(time.RFC3339, fetched)
+	if err != nil {
+		return fetched
+	}
+	return fmt.Sprintf("%s ago", time.Since(t).Round(time.Second))
+}
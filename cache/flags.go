@@ -0,0 +1,13 @@
+package cache
+
+import "github.com/codegangsta/cli"
+
+// PruneFlags builds a spec of the flags available for the prune subcommand
+func PruneFlags() []cli.Flag {
+	return []cli.Flag{
+		cli.BoolFlag{
+			Name:  "all, a",
+			Usage: "Remove every cached Kubeware, not only expired ones",
+		},
+	}
+}
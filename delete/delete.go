@@ -3,12 +3,16 @@ package delete
 import (
 	"fmt"
 	"os"
+	"sort"
+	"strings"
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/codegangsta/cli"
 	"github.com/flexiant/kdeploy/delete/strategies"
+	"github.com/flexiant/kdeploy/dependencies"
 	"github.com/flexiant/kdeploy/fetchers"
 	"github.com/flexiant/kdeploy/models"
+	"github.com/flexiant/kdeploy/repository"
 	"github.com/flexiant/kdeploy/template"
 	"github.com/flexiant/kdeploy/utils"
 	"github.com/flexiant/kdeploy/webservice"
@@ -21,53 +25,284 @@ func CmdDelete(c *cli.Context) {
 	var kubewareName string
 	var kubewareVersion string
 	var labelSelector string
+	var kinds []models.Kind
 	var err error
 
 	namespace := os.Getenv("KDEPLOY_NAMESPACE")
+	instance := os.Getenv("KDEPLOY_INSTANCE")
 	kubeware := os.Getenv("KDEPLOY_KUBEWARE")
-	localKubePath, err := fetchers.Fetch(kubeware)
-	if err != nil {
-		log.Fatal(fmt.Errorf("Could not fetch kubeware: '%s' (%v)", kubeware, err))
-	}
-
-	if localKubePath != "" {
-		labelSelector, kubewareName, kubewareVersion = labelSelectorFromKubeware(localKubePath)
-	} else {
+	fetched, err := fetchers.Fetch(kubeware)
+	if _, noFetcher := err.(*fetchers.ErrNoFetcher); noFetcher {
 		// could not be fetched so we will interpret it as a name
+		log.Debugf("%v", err)
 		kubewareName, err = utils.NormalizeName(kubeware)
 		utils.CheckError(err)
-		labelSelector = labelSelectorFromName(kubewareName)
+		labelSelector = labelSelectorFromName(kubewareName, instance)
+	} else if err != nil {
+		log.Fatal(fmt.Errorf("Could not fetch kubeware: '%s' (%v)", kubeware, err))
+	} else {
+		defer fetched.Close()
+		labelSelector, kubewareName, kubewareVersion, kinds = labelSelectorFromKubeware(fetched.Path, instance)
+		if resolvedByName(kubeware) {
+			// names resolve to the newest version in the repositories, which may not be the one
+			// deployed, so any version is deleted
+			normalizedName, err := utils.NormalizeName(kubewareName)
+			utils.CheckError(err)
+			labelSelector = labelSelectorFromName(normalizedName, instance)
+			kubewareVersion = ""
+		}
 	}
 
 	kubernetes, err := webservice.NewKubeClient()
 	utils.CheckError(err)
 
+	deleted, err := deleteKubeware(kubernetes, namespace, labelSelector, kinds)
+	utils.CheckError(err)
+	if !deleted {
+		var version string
+		if kubewareVersion != "" {
+			version = fmt.Sprintf(" (%s)", kubewareVersion)
+		}
+		log.Warnf("Could not delete kubeware '%s'%s since it is not currently deployed", kubewareName, version)
+		return
+	}
+	log.Infof("Kubeware '%s.%s' has been deleted", namespace, kubewareName)
+
+	if c.Bool("dependencies") {
+		if fetched == nil {
+			log.Warnf("Dependencies of '%s' can only be told when it is fetched, not deleting them", kubewareName)
+			return
+		}
+		err = deleteDependencies(kubernetes, namespace, instance, template.ParseMetadata(fetched.Path))
+		utils.CheckError(err)
+	}
+}
+
+// deleteKubeware deletes the resources matching the label selector, telling if there was any
+func deleteKubeware(kubernetes webservice.KubeClient, namespace, labelSelector string, kinds []models.Kind) (bool, error) {
 	// get services which are currently deployed as part of the kube
 	serviceList, err := kubernetes.GetServicesForNamespace(namespace, labelSelector)
-	utils.CheckError(err)
+	if err != nil {
+		return false, err
+	}
 	log.Debugf("Services: %v", serviceList)
 
 	// get controllers which are currently deployed as part of the kube
 	controllerList, err := kubernetes.GetControllersForNamespace(namespace, labelSelector)
-	utils.CheckError(err)
+	if err != nil {
+		return false, err
+	}
 	log.Debugf("Controllers: %v", controllerList)
 
-	// If no resources found that means it's not deployed
-	if len(*serviceList) == 0 || len(*controllerList) == 0 {
-		var version string
-		if kubewareVersion != "" {
-			version = fmt.Sprintf(" (%s)", kubewareVersion)
+	// get deployments, config maps and secrets which are currently deployed as part of the kube
+	deploymentList, err := kubernetes.GetDeploymentsForNamespace(namespace, labelSelector)
+	if err != nil {
+		return false, err
+	}
+	log.Debugf("Deployments: %v", deploymentList)
+	configMapList, err := kubernetes.GetConfigMapsForNamespace(namespace, labelSelector)
+	if err != nil {
+		return false, err
+	}
+	secretList, err := kubernetes.GetSecretsForNamespace(namespace, labelSelector)
+	if err != nil {
+		return false, err
+	}
+
+	// get resources of the other kinds the kubeware declares, which can only be known when
+	// the kubeware is fetched
+	resourceList := []models.Resource{}
+	for _, kind := range kinds {
+		resources, err := kubernetes.GetResourcesForNamespace(namespace, kind, labelSelector)
+		if err != nil {
+			return false, err
 		}
-		log.Warnf("Could not delete kubeware '%s'%s since it is not currently deployed", kubewareName, version)
-		return
+		resourceList = append(resourceList, *resources...)
+	}
+	log.Debugf("Resources: %v", resourceList)
+
+	// If no resources found that means it's not deployed
+	if len(*serviceList)+len(*controllerList)+len(*deploymentList)+len(*configMapList)+len(*secretList)+len(resourceList) == 0 {
+		return false, nil
 	}
 
 	// delete them
 	ds := deletionStrategies.WaitZeroReplicasDeletionStrategy(kubernetes)
 	err = ds.Delete(namespace, svcNames(serviceList), rcNames(controllerList))
-	utils.CheckError(err)
+	if err != nil {
+		return false, err
+	}
 
-	log.Infof("Kubeware '%s.%s' has been deleted", namespace, kubewareName)
+	// deployments take their replica sets and pods with them, and config maps and secrets go
+	// once nothing uses them
+	for _, d := range *deploymentList {
+		err = kubernetes.DeleteDeployment(namespace, d.Metadata.Name)
+		if err != nil {
+			return false, err
+		}
+	}
+	for _, r := range resourceList {
+		err = kubernetes.DeleteResource(namespace, r.Kind, r.Metadata.Name)
+		if err != nil {
+			return false, err
+		}
+	}
+	for _, cm := range *configMapList {
+		err = kubernetes.DeleteConfigMap(namespace, cm.Metadata.Name)
+		if err != nil {
+			return false, err
+		}
+	}
+	for _, s := range *secretList {
+		err = kubernetes.DeleteSecret(namespace, s.Metadata.Name)
+		if err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// deleteDependencies deletes the dependencies of a deleted instance of a kubeware that no other
+// kubeware of the instance depends on, dependents before their own dependencies
+func deleteDependencies(kubernetes webservice.KubeClient, namespace, instance string, md template.Metadata) error {
+	deps, err := dependencies.Resolve(md, fetchers.Fetch)
+	if err != nil {
+		return err
+	}
+	defer dependencies.Close(deps)
+	if len(deps) == 0 {
+		return nil
+	}
+
+	// kubewares depending on them may be made of resources of any kind
+	kinds, err := md.ResourceKinds()
+	if err != nil {
+		return err
+	}
+	for _, dep := range deps {
+		depKinds, err := dep.Metadata.ResourceKinds()
+		if err != nil {
+			return err
+		}
+		kinds = append(kinds, depKinds...)
+	}
+	kubes, err := deployedKubes(kubernetes, namespace, kinds)
+	if err != nil {
+		return err
+	}
+	name, err := utils.NormalizeName(md.Name)
+	if err != nil {
+		return err
+	}
+	removed := map[string]bool{name: true}
+	for i := len(deps) - 1; i >= 0; i-- {
+		dep := deps[i]
+		if needed := neededBy(dep.Name, instance, kubes, removed); len(needed) > 0 {
+			log.Infof("Keeping dependency %s, which %s depends on", dep.Name, strings.Join(needed, ", "))
+			continue
+		}
+		kinds, err := dep.Metadata.ResourceKinds()
+		if err != nil {
+			return err
+		}
+		deleted, err := deleteKubeware(kubernetes, namespace, labelSelectorFromName(dep.Name, instance), kinds)
+		if err != nil {
+			return err
+		}
+		removed[dep.Name] = true
+		if deleted {
+			log.Infof("Dependency '%s.%s' has been deleted", namespace, dep.Name)
+		}
+	}
+	return nil
+}
+
+// builtinKinds are listed by deployedKubes along with the kubewares they belong to, by group and kind
+var builtinKinds = map[string]bool{
+	"/Service":               true,
+	"/ReplicationController": true,
+	"/ConfigMap":             true,
+	"/Secret":                true,
+	"apps/Deployment":        true,
+	"extensions/Deployment":  true,
+}
+
+// deployedKubes lists the kubewares deployed in the namespace, made of resources of the built-in
+// kinds, the given ones or any other served by the API server, failing if any of them can't be
+// listed
+func deployedKubes(kubernetes webservice.KubeClient, namespace string, kinds []models.Kind) (map[string]models.Kube, error) {
+	serviceList, err := kubernetes.GetServicesForNamespace(namespace, "kubeware")
+	if err != nil {
+		return nil, err
+	}
+	controllerList, err := kubernetes.GetControllersForNamespace(namespace, "kubeware")
+	if err != nil {
+		return nil, err
+	}
+	deploymentList, err := kubernetes.GetDeploymentsForNamespace(namespace, "kubeware")
+	if err != nil {
+		return nil, err
+	}
+	configMapList, err := kubernetes.GetConfigMapsForNamespace(namespace, "kubeware")
+	if err != nil {
+		return nil, err
+	}
+	secretList, err := kubernetes.GetSecretsForNamespace(namespace, "kubeware")
+	if err != nil {
+		return nil, err
+	}
+	kubes := models.BuildKubeList(serviceList, controllerList, deploymentList, configMapList, secretList)
+
+	served, err := kubernetes.NamespacedKinds()
+	if err != nil {
+		return nil, err
+	}
+	listed := map[string]bool{}
+	for k := range builtinKinds {
+		listed[k] = true
+	}
+	list := func(kind models.Kind) error {
+		group := ""
+		if i := strings.Index(kind.APIVersion, "/"); i > -1 {
+			group = kind.APIVersion[:i]
+		}
+		if listed[group+"/"+kind.Kind] {
+			return nil
+		}
+		listed[group+"/"+kind.Kind] = true
+		resources, err := kubernetes.GetResourcesForNamespace(namespace, kind, "kubeware")
+		if err != nil {
+			return err
+		}
+		models.AddResources(kubes, *resources)
+		return nil
+	}
+	// a kind that can't be listed may hide kubewares that still need a dependency, so nothing
+	// is told unless every kind is
+	for _, kind := range append(kinds, served...) {
+		if err := list(kind); err != nil {
+			return nil, fmt.Errorf("could not tell which kubewares are deployed: listing %s: %v", kind, err)
+		}
+	}
+	return kubes, nil
+}
+
+// neededBy returns the deployed kubewares of the instance, other than the removed ones, that
+// depend on name
+func neededBy(name, instance string, kubes map[string]models.Kube, removed map[string]bool) []string {
+	needed := []string{}
+	for _, kube := range kubes {
+		if kube.Instance != instance || removed[kube.Name] {
+			continue
+		}
+		for _, dep := range kube.Dependencies {
+			if dep == name {
+				needed = append(needed, kube.Name)
+			}
+		}
+	}
+	sort.Strings(needed)
+	return needed
 }
 
 func rcNames(rcl *[]models.ReplicaController) []string {
@@ -86,16 +321,32 @@ func svcNames(sl *[]models.Service) []string {
 	return names
 }
 
-func labelSelectorFromName(name string) string {
-	return fmt.Sprintf("kubeware=%s", name)
+// resolvedByName tells if the kubeware is given by a name, without a version, resolved through
+// the repositories rather than by its path
+func resolvedByName(kubeware string) bool {
+	if repository.Constrained(kubeware) {
+		return false
+	}
+	fetcher, err := fetchers.Resolve(kubeware)
+	if err != nil {
+		return false
+	}
+	_, ok := fetcher.(*repository.Fetcher)
+	return ok
 }
 
-func labelSelectorFromKubeware(localKubePath string) (string, string, string) {
+func labelSelectorFromName(name, instance string) string {
+	return fmt.Sprintf("kubeware=%s,%s", name, models.InstanceSelector(instance))
+}
+
+func labelSelectorFromKubeware(localKubePath, instance string) (string, string, string, []models.Kind) {
 	md := template.ParseMetadata(localKubePath)
 
 	normalizedName, err := utils.NormalizeName(md.Name)
 	utils.CheckError(err)
-	labelSelector := fmt.Sprintf("kubeware=%s,kubeware-version=%s", normalizedName, md.Version)
+	labelSelector := fmt.Sprintf("kubeware=%s,kubeware-version=%s,%s", normalizedName, md.Version, models.InstanceSelector(instance))
+	kinds, err := md.ResourceKinds()
+	utils.CheckError(err)
 
-	return labelSelector, md.Name, md.Version
+	return labelSelector, md.Name, md.Version, kinds
 }
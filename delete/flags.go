@@ -4,6 +4,8 @@ import (
 	"os"
 
 	"github.com/codegangsta/cli"
+	"github.com/flexiant/kdeploy/fetchers"
+	"github.com/flexiant/kdeploy/utils"
 )
 
 // Flags builds a spec of the flags available for the command
@@ -14,12 +16,26 @@ func Flags() []cli.Flag {
 			Usage:  "Kubeware path",
 			EnvVar: "KDEPLOY_KUBEWARE",
 		},
+		cli.StringFlag{
+			Name:   "sha256",
+			Usage:  "Expected SHA-256 checksum of the kubeware archive",
+			EnvVar: "KDEPLOY_SHA256",
+		},
 		cli.StringFlag{
 			Name:   "namespace, n",
 			Usage:  "Namespace which to deploy Kubeware",
 			Value:  "default",
 			EnvVar: "KDEPLOY_NAMESPACE",
 		},
+		cli.StringFlag{
+			Name:   "instance, i",
+			Usage:  "Instance of the Kubeware to delete, the default one if not given",
+			EnvVar: "KDEPLOY_INSTANCE",
+		},
+		cli.BoolFlag{
+			Name:  "dependencies",
+			Usage: "Also delete the dependencies of the Kubeware that no other deployed Kubeware depends on",
+		},
 		cli.BoolFlag{
 			Name:   "dry-run, d",
 			Usage:  "Dry Run of Deploy used for debugging options",
@@ -35,13 +51,25 @@ func PrepareFlags(c *cli.Context) error {
 	}
 
 	if c.String("kubeware") != "" {
-		os.Setenv("KDEPLOY_KUBEWARE", c.String("kubeware"))
+		kubeware, err := fetchers.WithChecksum(c.String("kubeware"), c.String("sha256"))
+		if err != nil {
+			return err
+		}
+		os.Setenv("KDEPLOY_KUBEWARE", kubeware)
 	}
 
 	if c.Bool("dry-run") {
 		os.Setenv("KDEPLOY_DRYRUN", "1")
 	}
 
+	if c.String("instance") != "" {
+		err := utils.ValidateInstance(c.String("instance"))
+		if err != nil {
+			return err
+		}
+		os.Setenv("KDEPLOY_INSTANCE", c.String("instance"))
+	}
+
 	os.Setenv("KDEPLOY_NAMESPACE", c.String("namespace"))
 
 	return nil
@@ -0,0 +1,158 @@
+package dependencies
+
+import (
+	"fmt"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/flexiant/kdeploy/fetchers"
+	"github.com/flexiant/kdeploy/template"
+	"github.com/flexiant/kdeploy/utils"
+	"github.com/hashicorp/go-version"
+	"github.com/mafraba/deeply"
+)
+
+// FetchFunc fetches a kubeware, as fetchers.Fetch does
+type FetchFunc func(kpath string) (*fetchers.Kubeware, error)
+
+// Kubeware is a kubeware some other kubeware depends on
+type Kubeware struct {
+	Name        string                 // Normalized name
+	Source      string                 // Kubeware path it was fetched from
+	Metadata    template.Metadata      // Metadata of the fetched kubeware
+	Constraints []string               // Version constraints of the kubewares depending on it
+	Attributes  map[string]interface{} // Overrides of the kubewares depending on it, merged
+	RequiredBy  []string               // Normalized names of the kubewares depending on it
+	fetched     *fetchers.Kubeware
+}
+
+// Satisfies tells if a deployed version of the kubeware satisfies every constraint on it
+func (k *Kubeware) Satisfies(deployed string) (bool, error) {
+	v, err := version.NewVersion(deployed)
+	if err != nil {
+		return false, fmt.Errorf("invalid version '%s' of %s deployed: %v", deployed, k.Name, err)
+	}
+	return satisfies(v, k.Constraints)
+}
+
+// Close releases the fetched kubeware
+func (k *Kubeware) Close() error {
+	return k.fetched.Close()
+}
+
+// Resolve fetches the dependencies of the kubeware, and theirs, returning them in the order they
+// must be deployed: every kubeware after those it depends on. Kubewares that several others
+// depend on are fetched once, and must satisfy all their version constraints
+func Resolve(md template.Metadata, fetch FetchFunc) ([]*Kubeware, error) {
+	name, err := utils.NormalizeName(md.Name)
+	if err != nil {
+		return nil, err
+	}
+	r := &resolver{fetch: fetch, resolved: map[string]*Kubeware{}}
+	err = r.visit(name, md, []string{name})
+	if err != nil {
+		for _, k := range r.resolved {
+			k.Close()
+		}
+		return nil, err
+	}
+	return r.order, nil
+}
+
+// Close releases every fetched kubeware
+func Close(kubewares []*Kubeware) {
+	for _, k := range kubewares {
+		k.Close()
+	}
+}
+
+type resolver struct {
+	fetch    FetchFunc
+	resolved map[string]*Kubeware
+	order    []*Kubeware
+}
+
+// visit resolves the dependencies of a kubeware, given the path of kubewares depending on it
+func (r *resolver) visit(name string, md template.Metadata, path []string) error {
+	for _, dep := range md.Dependencies {
+		if dep.Name == "" || dep.Source == "" {
+			return fmt.Errorf("dependencies of %s must have a name and a source", name)
+		}
+		depName, err := utils.NormalizeName(dep.Name)
+		if err != nil {
+			return err
+		}
+		for i, n := range path {
+			if n == depName {
+				cycle := append(append([]string{}, path[i:]...), depName)
+				return fmt.Errorf("kubewares depend on each other: %s", strings.Join(cycle, " -> "))
+			}
+		}
+
+		k, ok := r.resolved[depName]
+		if !ok {
+			log.Debugf("Fetching %s, which %s depends on, from %s", depName, name, dep.Source)
+			fetched, err := r.fetch(dep.Source)
+			if err != nil {
+				return fmt.Errorf("could not fetch dependency %s of %s from '%s' (%v)", depName, name, dep.Source, err)
+			}
+			depMetadata, err := template.ReadMetadata(fetched.Path)
+			if err != nil {
+				fetched.Close()
+				return err
+			}
+			if fetchedName, _ := utils.NormalizeName(depMetadata.Name); fetchedName != depName {
+				fetched.Close()
+				return fmt.Errorf("dependency %s of %s fetched from '%s' is named '%s'", depName, name, dep.Source, depMetadata.Name)
+			}
+			k = &Kubeware{Name: depName, Source: dep.Source, Metadata: depMetadata, Attributes: map[string]interface{}{}, fetched: fetched}
+			r.resolved[depName] = k
+		}
+		if dep.Version != "" {
+			k.Constraints = append(k.Constraints, dep.Version)
+		}
+		k.RequiredBy = append(k.RequiredBy, name)
+		k.Attributes = deeply.Merge(k.Attributes, dep.Attributes)
+
+		v, err := version.NewVersion(k.Metadata.Version)
+		if err != nil {
+			return fmt.Errorf("invalid version '%s' of %s: %v", k.Metadata.Version, depName, err)
+		}
+		if ok, err := satisfies(v, k.Constraints); err != nil {
+			return fmt.Errorf("invalid version constraint of dependency %s of %s: %v", depName, name, err)
+		} else if !ok {
+			return fmt.Errorf("%s %s does not satisfy '%s', required by %s", depName, v, strings.Join(k.Constraints, "', '"), strings.Join(k.RequiredBy, ", "))
+		}
+
+		if !contains(r.order, k) {
+			err = r.visit(depName, k.Metadata, append(path, depName))
+			if err != nil {
+				return err
+			}
+			r.order = append(r.order, k)
+		}
+	}
+	return nil
+}
+
+func satisfies(v *version.Version, constraints []string) (bool, error) {
+	for _, constraint := range constraints {
+		c, err := version.NewConstraint(constraint)
+		if err != nil {
+			return false, err
+		}
+		if !c.Check(v) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func contains(kubewares []*Kubeware, k *Kubeware) bool {
+	for _, item := range kubewares {
+		if item == k {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,127 @@
+package dependencies
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/flexiant/kdeploy/fetchers"
+	"github.com/flexiant/kdeploy/template"
+)
+
+// kubewares writes kubewares with the given metadata, by source, and returns a function fetching
+// them along with the sources fetched
+func kubewares(t *testing.T, metadata map[string]string) (FetchFunc, *[]string, func()) {
+	root, _ := ioutil.TempDir("", "kdeploy-dependencies")
+	fetched := []string{}
+	for source, md := range metadata {
+		os.MkdirAll(filepath.Join(root, source), 0755)
+		ioutil.WriteFile(filepath.Join(root, source, "metadata.yaml"), []byte(md), 0644)
+	}
+	fetch := func(kpath string) (*fetchers.Kubeware, error) {
+		fetched = append(fetched, kpath)
+		if _, ok := metadata[kpath]; !ok {
+			return nil, fmt.Errorf("no kubeware at %s", kpath)
+		}
+		return fetchers.NewKubeware(filepath.Join(root, kpath), ""), nil
+	}
+	return fetch, &fetched, func() { os.RemoveAll(root) }
+}
+
+func names(kubewares []*Kubeware) []string {
+	n := []string{}
+	for _, k := range kubewares {
+		n = append(n, k.Name)
+	}
+	return n
+}
+
+const guestbook = `name: guestbook
+version: "1.0.0"
+dependencies:
+- name: redis
+  source: redis
+  version: "~> 1.2"
+  attributes:
+    rc:
+      redis-slave:
+        number: 2
+- name: frontend-cache
+  source: cache
+`
+
+func TestResolve(t *testing.T) {
+	fetch, fetched, cleanup := kubewares(t, map[string]string{
+		"redis":   "name: redis\nversion: \"1.2.3\"\ndependencies:\n- name: volumes\n  source: volumes\n",
+		"cache":   "name: Frontend Cache\nversion: \"0.1.0\"\ndependencies:\n- name: redis\n  source: redis\n  version: \">= 1.0\"\n  attributes:\n    rc:\n      redis-master:\n        number: 1\n",
+		"volumes": "name: volumes\nversion: \"2.0.0\"\n",
+	})
+	defer cleanup()
+
+	dir, _ := ioutil.TempDir("", "kdeploy-guestbook")
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "metadata.yaml"), []byte(guestbook), 0644)
+	md, err := template.ReadMetadata(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	resolved, err := Resolve(md, fetch)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer Close(resolved)
+	if !reflect.DeepEqual(names(resolved), []string{"volumes", "redis", "frontend-cache"}) {
+		t.Errorf("unexpected order %v", names(resolved))
+	}
+	if !reflect.DeepEqual(*fetched, []string{"redis", "volumes", "cache"}) {
+		t.Errorf("expected each kubeware to be fetched once, got %v", *fetched)
+	}
+
+	redis := resolved[1]
+	if !reflect.DeepEqual(redis.RequiredBy, []string{"guestbook", "frontend-cache"}) || !reflect.DeepEqual(redis.Constraints, []string{"~> 1.2", ">= 1.0"}) {
+		t.Errorf("unexpected dependents %v and constraints %v", redis.RequiredBy, redis.Constraints)
+	}
+	rcs := redis.Attributes["rc"].(map[string]interface{})
+	if rcs["redis-slave"].(map[string]interface{})["number"] != 2 || rcs["redis-master"].(map[string]interface{})["number"] != 1 {
+		t.Errorf("expected overrides of every dependent to be merged, got %v", redis.Attributes)
+	}
+
+	for deployed, expected := range map[string]bool{"1.2.0": true, "1.3.1": true, "1.1.0": false, "2.0.0": false} {
+		if ok, err := redis.Satisfies(deployed); err != nil || ok != expected {
+			t.Errorf("expected %s satisfying to be %t, got %t (%v)", deployed, expected, ok, err)
+		}
+	}
+}
+
+func TestResolveErrors(t *testing.T) {
+	fetch, _, cleanup := kubewares(t, map[string]string{
+		"a":        "name: a\nversion: \"1.0.0\"\ndependencies:\n- name: b\n  source: b\n",
+		"b":        "name: b\nversion: \"1.0.0\"\ndependencies:\n- name: a\n  source: a\n",
+		"old":      "name: old\nversion: \"0.9.0\"\n",
+		"renamed":  "name: other\nversion: \"1.0.0\"\n",
+		"unnamed":  "name: unnamed\nversion: \"1.0.0\"\ndependencies:\n- source: a\n",
+		"conflict": "name: conflict\nversion: \"1.0.0\"\ndependencies:\n- name: old\n  source: old\n  version: \">= 0.5\"\n- name: c\n  source: c\n",
+		"c":        "name: c\nversion: \"1.0.0\"\ndependencies:\n- name: old\n  source: old\n  version: \">= 1.0\"\n",
+	})
+	defer cleanup()
+
+	tests := map[string]template.Metadata{
+		"kubewares depend on each other: a -> b -> a":                            {Name: "root", Dependencies: []template.Dependency{{Name: "a", Source: "a"}}},
+		"old 0.9.0 does not satisfy '~> 1.0', required by root":                  {Name: "root", Dependencies: []template.Dependency{{Name: "old", Source: "old", Version: "~> 1.0"}}},
+		"fetched from 'renamed' is named 'other'":                                {Name: "root", Dependencies: []template.Dependency{{Name: "renamed", Source: "renamed"}}},
+		"could not fetch dependency missing of root":                             {Name: "root", Dependencies: []template.Dependency{{Name: "missing", Source: "missing"}}},
+		"dependencies of unnamed must have a name and a source":                  {Name: "root", Dependencies: []template.Dependency{{Name: "unnamed", Source: "unnamed"}}},
+		"old 0.9.0 does not satisfy '>= 0.5', '>= 1.0', required by conflict, c": {Name: "conflict", Dependencies: []template.Dependency{{Name: "old", Source: "old", Version: ">= 0.5"}, {Name: "c", Source: "c"}}},
+	}
+	for expected, md := range tests {
+		_, err := Resolve(md, fetch)
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected error '%s', got %v", expected, err)
+		}
+	}
+}
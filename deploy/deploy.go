@@ -3,15 +3,29 @@ package deploy
 import (
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/codegangsta/cli"
+	"github.com/flexiant/kdeploy/dependencies"
 	"github.com/flexiant/kdeploy/fetchers"
+	"github.com/flexiant/kdeploy/outputs"
 	"github.com/flexiant/kdeploy/template"
 	"github.com/flexiant/kdeploy/utils"
 	"github.com/flexiant/kdeploy/webservice"
 )
 
+// specs holds the resources of a kubeware, parsed and ready to be created
+type specs struct {
+	services    map[string]string
+	controllers map[string]string
+	deployments map[string]string
+	configMaps  map[string]string
+	secrets     map[string]string
+	resources   map[string]string
+}
+
 // CmdDeploy implements the 'deploy' command
 func CmdDeploy(c *cli.Context) {
 	utils.CheckRequiredFlags(c, []string{"kubeware"})
@@ -21,50 +35,265 @@ func CmdDeploy(c *cli.Context) {
 	var err error
 
 	kubeware = os.Getenv("KDEPLOY_KUBEWARE")
-	localKubePath, err = fetchers.Fetch(kubeware)
+	fetched, err := fetchers.Fetch(kubeware)
 	if err != nil {
 		log.Fatal(fmt.Errorf("Could not fetch kubeware: '%s' (%v)", kubeware, err))
 	}
+	defer fetched.Close()
+	localKubePath = fetched.Path
 
 	log.Debugf("Going to parse kubeware in %s", localKubePath)
 
 	metadata := template.ParseMetadata(localKubePath)
-	defaults, err := metadata.AttributeDefaults()
-	utils.CheckError(err)
-	// build attributes merging "role list" to defaults
-	log.Debugf("Building attributes")
-	attributes := template.BuildAttributes(c.String("attribute"), defaults)
-	// get list of services and parse each one
-	log.Debugf("Parsing services")
-	servicesSpecs, err := metadata.ParseServices(attributes)
+	metadata.Instance = os.Getenv("KDEPLOY_INSTANCE")
+	kinds, err := metadata.Kinds()
 	utils.CheckError(err)
-	// get list of replica controllers and parse each one
-	log.Debugf("Parsing controllers")
-	controllersSpecs, err := metadata.ParseControllers(attributes)
+	// fetch the kubewares it depends on, in the order they have to be deployed
+	log.Debugf("Resolving dependencies")
+	deps, err := dependencies.Resolve(metadata, fetchers.Fetch)
 	utils.CheckError(err)
+	defer dependencies.Close(deps)
 	// creates Kubernetes client
 	kubernetes, err := webservice.NewKubeClient()
 	utils.CheckError(err)
+	namespace := os.Getenv("KDEPLOY_NAMESPACE")
 	// check if kubeware already exists
 	log.Debugf("Checking if already deployed")
-	deployedVersion, err := kubernetes.FindDeployedKubewareVersion(os.Getenv("KDEPLOY_NAMESPACE"), metadata.Name)
+	deployedVersion, err := kubernetes.FindDeployedKubewareVersion(namespace, metadata.Name, metadata.Instance, kinds...)
 	utils.CheckError(err)
 	if deployedVersion != "" {
 		log.Errorf("Can not deploy '%s' since version '%s' is already deployed", metadata.Name, deployedVersion)
 		return
 	}
-	// create each of the services
-	log.Debugf("Creating services")
-	err = kubernetes.CreateServices(utils.Values(servicesSpecs))
+	// parse the dependencies not deployed yet and the kubeware, so that nothing is created if
+	// any fails. Outputs of the dependencies not deployed yet are blank until they are created
+	collected := map[string]map[string]interface{}{}
+	pending := []*dependencies.Kubeware{}
+	for _, dep := range deps {
+		// dependencies are deployed as the same instance
+		dep.Metadata.Instance = metadata.Instance
+		depKinds, err := dep.Metadata.Kinds()
+		utils.CheckError(err)
+		deployed, err := kubernetes.FindDeployedKubewareVersion(namespace, dep.Metadata.Name, dep.Metadata.Instance, depKinds...)
+		utils.CheckError(err)
+		if deployed != "" {
+			ok, err := dep.Satisfies(deployed)
+			utils.CheckError(err)
+			if !ok {
+				log.Fatalf("Dependency %s %s is deployed, but '%s' is required by %s", dep.Name, deployed, strings.Join(dep.Constraints, "', '"), strings.Join(dep.RequiredBy, ", "))
+			}
+			log.Infof("Dependency %s %s is already deployed", dep.Name, deployed)
+			values, err := outputs.Collect(kubernetes, namespace, dep.Metadata)
+			utils.CheckError(err)
+			collected[dep.Name] = outputs.WithBlanks(dep.Metadata, values)
+			continue
+		}
+		_, err = parseDependency(dep, collected, c.BoolT("strict"))
+		utils.CheckError(err)
+		pending = append(pending, dep)
+		collected[dep.Name] = outputs.WithBlanks(dep.Metadata, nil)
+	}
+	_, err = parseKubeware(c, metadata, collected)
+	utils.CheckError(err)
+	// every wait, for the resources others depend on and with --wait, shares the timeout
+	timeout := DefaultTimeout
+	if os.Getenv("KDEPLOY_TIMEOUT") != "" {
+		timeout, err = time.ParseDuration(os.Getenv("KDEPLOY_TIMEOUT"))
+		utils.CheckError(err)
+	}
+	wait := os.Getenv("KDEPLOY_WAIT") == "1"
+	w := newWaiter(kubernetes, namespace, timeout)
+	// deploy the dependencies first, each with the outputs of those deployed before it
+	for _, dep := range pending {
+		log.Debugf("Deploying dependency %s", dep.Name)
+		depSpecs, err := parseDependency(dep, collected, c.BoolT("strict"))
+		utils.CheckError(err)
+		err = create(kubernetes, dep.Metadata, depSpecs, w)
+		utils.CheckError(err)
+		if wait {
+			err = waitAll(dep.Metadata, depSpecs, w)
+			utils.CheckError(err)
+		}
+		log.Infof("Dependency %s %s from %s has been deployed", dep.Name, dep.Metadata.Version, dep.Source)
+		values, err := outputs.Collect(kubernetes, namespace, dep.Metadata)
+		utils.CheckError(err)
+		collected[dep.Name] = outputs.WithBlanks(dep.Metadata, values)
+	}
+	kubewareSpecs, err := parseKubeware(c, metadata, collected)
 	utils.CheckError(err)
-	// create each of the controllers
-	log.Debugf("Creating controllers")
-	err = kubernetes.CreateReplicaControllers(utils.Values(controllersSpecs))
+	err = create(kubernetes, metadata, kubewareSpecs, w)
 	utils.CheckError(err)
+	if wait {
+		err = waitAll(metadata, kubewareSpecs, w)
+		utils.CheckError(err)
+	}
 
 	log.Infof("Kubeware %s from %s has been deployed", metadata.Name, os.Getenv("KDEPLOY_KUBEWARE"))
 }
 
+// parseKubeware builds the attributes of the kubeware being deployed and parses it
+func parseKubeware(c *cli.Context, metadata template.Metadata, collected map[string]map[string]interface{}) (*specs, error) {
+	// build attributes merging "role list" to defaults
+	log.Debugf("Building attributes")
+	attributes, err := template.BuildAttributesWithOutputs(c.StringSlice("attribute"), c.StringSlice("set"), outputsFor(metadata, collected), metadata)
+	if err != nil {
+		return nil, err
+	}
+	return parse(metadata, attributes, c.BoolT("strict"))
+}
+
+// parseDependency builds the attributes of a dependency and parses it
+func parseDependency(dep *dependencies.Kubeware, collected map[string]map[string]interface{}, strict bool) (*specs, error) {
+	log.Debugf("Building attributes of dependency %s", dep.Name)
+	attributes, err := template.BuildDependencyAttributes(dep.Attributes, outputsFor(dep.Metadata, collected), dep.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	return parse(dep.Metadata, attributes, strict)
+}
+
+// outputsFor picks the outputs of the kubewares a kubeware depends on directly
+func outputsFor(md template.Metadata, collected map[string]map[string]interface{}) map[string]map[string]interface{} {
+	picked := map[string]map[string]interface{}{}
+	for _, dep := range md.Dependencies {
+		if values, ok := collected[dep.Name]; ok {
+			picked[dep.Name] = values
+		}
+	}
+	return picked
+}
+
+// parse parses every resource of the kubeware
+func parse(metadata template.Metadata, attributes map[string]interface{}, strict bool) (*specs, error) {
+	var s specs
+	var err error
+	// check templates don't refer to undefined attributes, which would be rendered blank
+	if strict {
+		err = metadata.CheckTemplates(attributes)
+		if err != nil {
+			return nil, err
+		}
+	}
+	// get list of services and parse each one
+	log.Debugf("Parsing services")
+	s.services, err = metadata.ParseServices(attributes)
+	if err != nil {
+		return nil, err
+	}
+	// get list of replica controllers and parse each one
+	log.Debugf("Parsing controllers")
+	s.controllers, err = metadata.ParseControllers(attributes)
+	if err != nil {
+		return nil, err
+	}
+	// get list of deployments and parse each one
+	log.Debugf("Parsing deployments")
+	s.deployments, err = metadata.ParseDeployments(attributes)
+	if err != nil {
+		return nil, err
+	}
+	// get config maps and secrets and parse each one
+	log.Debugf("Parsing config maps and secrets")
+	s.configMaps, err = metadata.ParseConfigMaps(attributes)
+	if err != nil {
+		return nil, err
+	}
+	s.secrets, err = metadata.ParseSecrets(attributes)
+	if err != nil {
+		return nil, err
+	}
+	// get resources of any other kind and parse each one
+	log.Debugf("Parsing resources")
+	s.resources, err = metadata.ParseResources(attributes)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// create creates every resource of a kubeware in its creation order, waiting for the
+// controllers and deployments a resource depends on to be ready before creating it
+func create(kubernetes webservice.KubeClient, md template.Metadata, s *specs, w *waiter) error {
+	order, err := md.CreationOrder()
+	if err != nil {
+		return err
+	}
+	for _, id := range order {
+		for _, dep := range md.DependsOn[id] {
+			section, name := template.SplitResourceID(dep)
+			err = w.waitReady(section, md.ResourceName(name), s.spec(section, md.ResourceName(name)))
+			if err != nil {
+				return err
+			}
+		}
+		section, name := template.SplitResourceID(id)
+		log.Debugf("Creating %s", id)
+		err = s.create(kubernetes, w.namespace, section, md.ResourceName(name))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// spec returns the spec of a resource of the kubeware, given by its section and its name as deployed
+func (s *specs) spec(section, name string) string {
+	switch section {
+	case "cm":
+		return s.configMaps[name]
+	case "secret":
+		return s.secrets[name]
+	case "resources":
+		return s.resources[name]
+	case "svc":
+		return s.services[name]
+	case "rc":
+		return s.controllers[name]
+	case "deploy":
+		return s.deployments[name]
+	}
+	return ""
+}
+
+// create creates a resource of the kubeware, given by its section and its name as deployed
+func (s *specs) create(kubernetes webservice.KubeClient, namespace, section, name string) error {
+	spec := []byte(s.spec(section, name))
+	var err error
+	switch section {
+	case "cm":
+		_, err = kubernetes.CreateConfigMap(namespace, spec)
+	case "secret":
+		_, err = kubernetes.CreateSecret(namespace, spec)
+	case "resources":
+		_, err = kubernetes.CreateResource(namespace, spec)
+	case "svc":
+		_, err = kubernetes.CreateService(namespace, spec)
+	case "rc":
+		_, err = kubernetes.CreateReplicaController(namespace, spec)
+	case "deploy":
+		_, err = kubernetes.CreateDeployment(namespace, spec)
+	default:
+		err = fmt.Errorf("unknown resource type '%s'", section)
+	}
+	return err
+}
+
+// waitAll waits for every controller and deployment of a kubeware to be ready
+func waitAll(md template.Metadata, s *specs, w *waiter) error {
+	order, err := md.CreationOrder()
+	if err != nil {
+		return err
+	}
+	for _, id := range order {
+		section, name := template.SplitResourceID(id)
+		err = w.waitReady(section, md.ResourceName(name), s.spec(section, md.ResourceName(name)))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // func isLocalURL(kube string) bool  {
 //   kubewareURL, err := url.Parse(kube)
 // 	return (err == nil && kubewareURL.Scheme == "file")
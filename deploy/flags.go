@@ -1,29 +1,69 @@
 package deploy
 
 import (
+	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/codegangsta/cli"
+	"github.com/flexiant/kdeploy/fetchers"
+	"github.com/flexiant/kdeploy/utils"
 )
 
 func Flags() []cli.Flag {
 	return []cli.Flag{
-		cli.StringFlag{
+		cli.StringSliceFlag{
 			Name:   "attribute, a",
-			Usage:  "Attribute List",
+			Usage:  "Attributes file, JSON or YAML. Repeat to merge several, left to right",
 			EnvVar: "KDEPLOY_ATTRIBUTE",
 		},
+		cli.StringSliceFlag{
+			Name:  "set",
+			Usage: "Attribute override applied last, as path=value (e.g. rc/frontend/number=3)",
+		},
+		cli.BoolTFlag{
+			Name:   "strict",
+			Usage:  "Fail on template variables not resolved by the attributes, use --strict=false to render them blank",
+			EnvVar: "KDEPLOY_STRICT",
+		},
 		cli.StringFlag{
 			Name:   "kubeware, k",
 			Usage:  "Kubeware path",
 			EnvVar: "KDEPLOY_KUBEWARE",
 		},
+		cli.StringFlag{
+			Name:   "sha256",
+			Usage:  "Expected SHA-256 checksum of the kubeware archive",
+			EnvVar: "KDEPLOY_SHA256",
+		},
+		cli.BoolFlag{
+			Name:   "require-signed",
+			Usage:  "Refuse Kubewares not signed with a trusted key",
+			EnvVar: "KDEPLOY_REQUIRE_SIGNED",
+		},
 		cli.StringFlag{
 			Name:   "namespace, n",
 			Usage:  "Namespace which to deploy Kubeware",
 			Value:  "default",
 			EnvVar: "KDEPLOY_NAMESPACE",
 		},
+		cli.StringFlag{
+			Name:   "instance, i",
+			Usage:  "Instance of the Kubeware, to deploy it several times in a namespace. Prefixes the names of its resources",
+			EnvVar: "KDEPLOY_INSTANCE",
+		},
+		cli.BoolFlag{
+			Name:   "wait, w",
+			Usage:  "Wait for every controller and deployment to have its replicas ready, failing if they aren't within the timeout",
+			EnvVar: "KDEPLOY_WAIT",
+		},
+		cli.StringFlag{
+			Name:   "timeout",
+			Usage:  "How long to wait for controllers and deployments to be ready, when other resources depend on them or with --wait",
+			Value:  DefaultTimeout.String(),
+			EnvVar: "KDEPLOY_TIMEOUT",
+		},
 		cli.BoolFlag{
 			Name:   "dry-run, d",
 			Usage:  "Dry Run of Deploy used for debugging options",
@@ -33,18 +73,47 @@ func Flags() []cli.Flag {
 }
 
 func PrepareFlags(c *cli.Context) error {
-	if c.String("attribute") != "" {
-		os.Setenv("KDEPLOY_ATTRIBUTE", c.String("attribute"))
+	if len(c.StringSlice("attribute")) > 0 {
+		os.Setenv("KDEPLOY_ATTRIBUTE", strings.Join(c.StringSlice("attribute"), ","))
 	}
 
 	if c.String("kubeware") != "" {
-		os.Setenv("KDEPLOY_KUBEWARE", c.String("kubeware"))
+		// the checksum goes with the kubeware, so that it isn't checked against its dependencies
+		kubeware, err := fetchers.WithChecksum(c.String("kubeware"), c.String("sha256"))
+		if err != nil {
+			return err
+		}
+		os.Setenv("KDEPLOY_KUBEWARE", kubeware)
+	}
+
+	if c.Bool("require-signed") {
+		os.Setenv("KDEPLOY_REQUIRE_SIGNED", "1")
 	}
 
 	if c.Bool("dry-run") {
 		os.Setenv("KDEPLOY_DRYRUN", "1")
 	}
 
+	if c.Bool("wait") {
+		os.Setenv("KDEPLOY_WAIT", "1")
+	}
+
+	if c.String("timeout") != "" {
+		_, err := time.ParseDuration(c.String("timeout"))
+		if err != nil {
+			return fmt.Errorf("invalid timeout '%s': %v", c.String("timeout"), err)
+		}
+		os.Setenv("KDEPLOY_TIMEOUT", c.String("timeout"))
+	}
+
+	if c.String("instance") != "" {
+		err := utils.ValidateInstance(c.String("instance"))
+		if err != nil {
+			return err
+		}
+		os.Setenv("KDEPLOY_INSTANCE", c.String("instance"))
+	}
+
 	os.Setenv("KDEPLOY_NAMESPACE", c.String("namespace"))
 
 	return nil
@@ -0,0 +1,98 @@
+package deploy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/flexiant/kdeploy/models"
+	"github.com/flexiant/kdeploy/webservice"
+)
+
+// DefaultTimeout is how long deploy waits for controllers and deployments to be ready
+const DefaultTimeout = 5 * time.Minute
+
+// waiter waits for the controllers and deployments of a namespace to be ready, all of them
+// within the same deadline
+type waiter struct {
+	kubernetes webservice.KubeClient
+	namespace  string
+	deadline   time.Time
+}
+
+func newWaiter(kubernetes webservice.KubeClient, namespace string, timeout time.Duration) *waiter {
+	return &waiter{
+		kubernetes: kubernetes,
+		namespace:  namespace,
+		deadline:   time.Now().Add(timeout),
+	}
+}
+
+// waitReady waits until every replica of a controller or deployment, given by its section, its
+// name as deployed and its spec, is ready. Resources of other sections are ready once created
+func (w *waiter) waitReady(section, name, spec string) error {
+	var ready func() (bool, error)
+	switch section {
+	case "rc":
+		ready = func() (bool, error) { return w.controllerReady(name) }
+	case "deploy":
+		// deployments are looked up as the kind they were created as, which discovery maps to
+		// the API serving it
+		kind, err := webservice.SpecKind([]byte(spec))
+		if err != nil {
+			return fmt.Errorf("deployment %s: %v", name, err)
+		}
+		ready = func() (bool, error) { return w.deploymentReady(kind, name) }
+	default:
+		return nil
+	}
+	if os.Getenv("KDEPLOY_DRYRUN") == "1" {
+		log.Infof("Would wait for %s %s to be ready", section, name)
+		return nil
+	}
+	for ; ; time.Sleep(1 * time.Second) {
+		ok, err := ready()
+		if err != nil {
+			return err
+		}
+		if ok {
+			log.Infof("%s %s is ready", section, name)
+			return nil
+		}
+		if time.Now().After(w.deadline) {
+			return fmt.Errorf("timed out waiting for %s %s to be ready", section, name)
+		}
+		log.Debugf("Waiting for %s.%s to be ready", w.namespace, name)
+	}
+}
+
+// controllerReady tells if the ready pods of a replication controller reach its spec replicas
+func (w *waiter) controllerReady(name string) (bool, error) {
+	specReplicas, err := w.kubernetes.GetSpecReplicas(w.namespace, name)
+	if err != nil {
+		return false, err
+	}
+	readyReplicas, err := w.kubernetes.GetReadyReplicas(w.namespace, name)
+	if err != nil {
+		return false, err
+	}
+	log.Debugf("Controller %s.%s has %d of %d replicas ready", w.namespace, name, readyReplicas, specReplicas)
+	return readyReplicas >= specReplicas, nil
+}
+
+// deploymentReady tells if the available replicas of a deployment reach its spec replicas
+func (w *waiter) deploymentReady(kind models.Kind, name string) (bool, error) {
+	object, err := w.kubernetes.GetObject(w.namespace, kind, name)
+	if err != nil {
+		return false, err
+	}
+	var d models.Deployment
+	err = json.Unmarshal(object, &d)
+	if err != nil {
+		return false, fmt.Errorf("error parsing deployment %s: %v", name, err)
+	}
+	log.Debugf("Deployment %s.%s has %d of %d replicas available", w.namespace, name, d.Status.AvailableReplicas, d.Spec.Replicas)
+	return d.Status.AvailableReplicas >= d.Spec.Replicas, nil
+}
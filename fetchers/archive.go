@@ -0,0 +1,179 @@
+package fetchers
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/flexiant/kdeploy/template"
+	"github.com/flexiant/kdeploy/utils"
+	"github.com/flexiant/kdeploy/webservice"
+)
+
+// archiveExtensions maps the supported archive extensions to the function extracting them
+var archiveExtensions = map[string]func(src, dest string) error{
+	".zip":    utils.Unzip,
+	".tar.gz": utils.Untar,
+	".tgz":    utils.Untar,
+}
+
+// ArchiveFetcher downloads and extracts a kubeware packaged as a .zip, .tar.gz or .tgz archive
+// from any http(s) URL or local path. The expected SHA-256 checksum of the archive can be given in the URL
+// fragment ('#sha256=<hex>')
+type ArchiveFetcher struct{}
+
+// CanHandle tells if the URL can be handled by this resolver
+func (a *ArchiveFetcher) CanHandle(kpath string) bool {
+	uri, err := url.Parse(kpath)
+	if err != nil {
+		return false
+	}
+	if archiveExtension(uri.Path) == "" {
+		return false
+	}
+	if uri.Scheme == "http" || uri.Scheme == "https" {
+		return true
+	}
+	return uri.Scheme == "" && utils.FileExists(uri.Path)
+}
+
+// Fetch downloads the archive into a temporal local directory if it is remote, checks its checksum if one
+// was given, extracts it and returns the path of the kubeware in it
+func (a *ArchiveFetcher) Fetch(kpath string) (*Kubeware, error) {
+	if !a.CanHandle(kpath) {
+		return nil, fmt.Errorf("URL can't be handled by ArchiveFetcher: '%s'", kpath)
+	}
+	uri, err := url.Parse(kpath)
+	if err != nil {
+		return nil, err
+	}
+	checksum, err := expectedChecksum(uri)
+	if err != nil {
+		return nil, err
+	}
+	uri.Fragment = ""
+
+	return fetchInto(func(tmpDir string) (string, error) {
+		return fetchArchive(uri, checksum, tmpDir)
+	})
+}
+
+// fetchArchive downloads the archive into tmpDir if it is remote, and extracts it there
+func fetchArchive(uri *url.URL, checksum, tmpDir string) (string, error) {
+	archive := uri.Path
+	if uri.Scheme != "" {
+		client, err := webservice.NewSimpleWebClient(uri.String())
+		if err != nil {
+			return "", err
+		}
+		archive, err = client.GetFile(uri.RequestURI(), tmpDir)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if checksum != "" {
+		err := verifySHA256(archive, checksum)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	extractDir := filepath.Join(tmpDir, "src")
+	extract := archiveExtensions[archiveExtension(uri.Path)]
+	err := extract(archive, extractDir)
+	if err != nil {
+		return "", err
+	}
+
+	return findKubewareDir(extractDir)
+}
+
+// archiveExtension returns the supported archive extension the path ends with, if any
+func archiveExtension(path string) string {
+	for ext := range archiveExtensions {
+		if strings.HasSuffix(path, ext) {
+			return ext
+		}
+	}
+	return ""
+}
+
+// WithChecksum returns the path of a kubeware archive pinned to the given SHA-256 checksum, so
+// that it applies to that archive only and not to the others fetched along with it
+func WithChecksum(kpath, checksum string) (string, error) {
+	if checksum == "" {
+		return kpath, nil
+	}
+	uri, err := url.Parse(kpath)
+	if err != nil || archiveExtension(uri.Path) == "" {
+		return "", fmt.Errorf("a checksum can only be given for .zip, .tar.gz or .tgz archives, got '%s'", kpath)
+	}
+	if uri.Fragment != "" {
+		return "", fmt.Errorf("kubeware '%s' already has a fragment, can't add a checksum", kpath)
+	}
+	return fmt.Sprintf("%s#sha256=%s", kpath, checksum), nil
+}
+
+// expectedChecksum returns the SHA-256 checksum the archive should match, taken from the URL
+// fragment, if any
+func expectedChecksum(uri *url.URL) (string, error) {
+	if uri.Fragment != "" {
+		values, err := url.ParseQuery(uri.Fragment)
+		if err != nil {
+			return "", fmt.Errorf("could not parse URL fragment '%s': %v", uri.Fragment, err)
+		}
+		if values.Get("sha256") == "" {
+			return "", fmt.Errorf("unsupported URL fragment '%s', expected 'sha256=<checksum>'", uri.Fragment)
+		}
+		return strings.ToLower(values.Get("sha256")), nil
+	}
+	return "", nil
+}
+
+// verifySHA256 checks that the file matches the expected SHA-256 checksum
+func verifySHA256(file, expected string) error {
+	actual, err := template.FileDigest(file)
+	if err != nil {
+		return err
+	}
+	if actual != expected {
+		return fmt.Errorf("checksum mismatch for %s: expected sha256 %s, got %s", filepath.Base(file), expected, actual)
+	}
+	return nil
+}
+
+// findKubewareDir returns the shallowest directory under root holding a kubeware metadata file
+func findKubewareDir(root string) (string, error) {
+	var found []string
+	depth := -1
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || info.Name() != "metadata.yaml" {
+			return nil
+		}
+		dir := filepath.Dir(path)
+		d := strings.Count(dir, string(filepath.Separator))
+		switch {
+		case depth == -1 || d < depth:
+			found, depth = []string{dir}, d
+		case d == depth:
+			found = append(found, dir)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(found) == 0 {
+		return "", fmt.Errorf("no kubeware metadata found in archive")
+	}
+	if len(found) > 1 {
+		return "", fmt.Errorf("archive holds more than one kubeware: %s", strings.Join(found, ", "))
+	}
+	return found[0], nil
+}
@@ -0,0 +1,217 @@
+package fetchers
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var archiveFiles = [][2]string{
+	{"guestbook-0.0.1/metadata.yaml", "name: \"Guestbook\"\n"},
+	{"guestbook-0.0.1/frontend-service.yaml", "kind: Service\n"},
+	{"guestbook-0.0.1/examples/metadata.yaml", "name: \"Example\"\n"},
+}
+
+func TestArchiveCanHandle(t *testing.T) {
+	archiveFetcher := &ArchiveFetcher{}
+	for _, uri := range []string{
+		"https://example.com/kubewares/guestbook-0.0.1.tgz",
+		"http://example.com/guestbook.tar.gz",
+		"https://example.com/guestbook.zip#sha256=abc",
+	} {
+		if !archiveFetcher.CanHandle(uri) {
+			t.Errorf("ArchiveFetcher should handle '%s'", uri)
+		}
+	}
+	for _, uri := range []string{
+		"https://example.com/guestbook",
+		"ftp://example.com/guestbook.zip",
+		"./guestbook.zip",
+	} {
+		if archiveFetcher.CanHandle(uri) {
+			t.Errorf("ArchiveFetcher should not handle '%s'", uri)
+		}
+	}
+}
+
+func TestArchiveFetchZip(t *testing.T) {
+	server := archiveServer(t)
+	defer server.Close()
+
+	archiveFetcher := &ArchiveFetcher{}
+	kubeware, err := archiveFetcher.Fetch(server.URL + "/guestbook-0.0.1.zip")
+	if err != nil {
+		t.Fatalf("ArchiveFetcher could not fetch zip: %v", err)
+	}
+	defer kubeware.Close()
+
+	if filepath.Base(kubeware.Path) != "guestbook-0.0.1" {
+		t.Errorf("unexpected kubeware path: %s", kubeware.Path)
+	}
+}
+
+func TestArchiveFetchSignedURL(t *testing.T) {
+	server := archiveServer(t)
+	defer server.Close()
+
+	archiveFetcher := &ArchiveFetcher{}
+	kubeware, err := archiveFetcher.Fetch(server.URL + "/signed.zip?token=s3cr3t")
+	if err != nil {
+		t.Fatalf("ArchiveFetcher could not fetch signed URL: %v", err)
+	}
+	defer kubeware.Close()
+}
+
+func TestArchiveFetchTarball(t *testing.T) {
+	server := archiveServer(t)
+	defer server.Close()
+
+	archiveFetcher := &ArchiveFetcher{}
+	for _, name := range []string{"guestbook-0.0.1.tgz", "guestbook-0.0.1.tar.gz"} {
+		kubeware, err := archiveFetcher.Fetch(server.URL + "/" + name)
+		if err != nil {
+			t.Fatalf("ArchiveFetcher could not fetch %s: %v", name, err)
+		}
+		defer kubeware.Close()
+
+		if _, err := os.Stat(filepath.Join(kubeware.Path, "frontend-service.yaml")); err != nil {
+			t.Errorf("kubeware files not extracted from %s: %v", name, err)
+		}
+	}
+}
+
+func TestArchiveFetchChecksum(t *testing.T) {
+	server := archiveServer(t)
+	defer server.Close()
+
+	sum := sha256.Sum256(buildZip(t))
+	checksum := hex.EncodeToString(sum[:])
+
+	archiveFetcher := &ArchiveFetcher{}
+	kubeware, err := archiveFetcher.Fetch(server.URL + "/guestbook-0.0.1.zip#sha256=" + checksum)
+	if err != nil {
+		t.Fatalf("ArchiveFetcher should accept matching checksum: %v", err)
+	}
+	kubeware.Close()
+
+	_, err = archiveFetcher.Fetch(server.URL + "/guestbook-0.0.1.zip#sha256=0123456789abcdef")
+	if err == nil {
+		t.Errorf("ArchiveFetcher should reject mismatching checksum")
+	}
+
+	pinned, err := WithChecksum(server.URL+"/guestbook-0.0.1.zip", "0123456789abcdef")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = archiveFetcher.Fetch(pinned)
+	if err == nil {
+		t.Errorf("ArchiveFetcher should reject mismatching checksum given with WithChecksum")
+	}
+	if _, err := WithChecksum("https://github.com/flexiant/kubeware-guestbook", checksum); err == nil {
+		t.Errorf("checksums should only be given for archives")
+	}
+	if _, err := WithChecksum(pinned, checksum); err == nil {
+		t.Errorf("checksums should not be added to paths with a fragment")
+	}
+}
+
+func TestArchiveFetchLocal(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "kdeploy-archive")
+	defer os.RemoveAll(dir)
+	archive := filepath.Join(dir, "guestbook-0.0.1.tgz")
+	ioutil.WriteFile(archive, buildTarball(t), 0644)
+
+	archiveFetcher := &ArchiveFetcher{}
+	if !archiveFetcher.CanHandle(archive) {
+		t.Fatalf("ArchiveFetcher should handle local archive '%s'", archive)
+	}
+	kubeware, err := archiveFetcher.Fetch(archive)
+	if err != nil {
+		t.Fatalf("ArchiveFetcher could not fetch local archive: %v", err)
+	}
+	defer kubeware.Close()
+
+	if _, err := os.Stat(filepath.Join(kubeware.Path, "frontend-service.yaml")); err != nil {
+		t.Errorf("kubeware files not extracted: %v", err)
+	}
+}
+
+func TestArchiveFetchNotFound(t *testing.T) {
+	server := archiveServer(t)
+	defer server.Close()
+
+	archiveFetcher := &ArchiveFetcher{}
+	_, err := archiveFetcher.Fetch(server.URL + "/missing.zip")
+	if err == nil {
+		t.Errorf("ArchiveFetcher should have failed for missing archive")
+	}
+}
+
+func archiveServer(t *testing.T) *httptest.Server {
+	zipData := buildZip(t)
+	tgzData := buildTarball(t)
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch filepath.Ext(r.URL.Path) {
+		case ".zip":
+			// pre-authenticated URLs carry their signature in the query
+			if r.URL.Path == "/signed.zip" && r.URL.Query().Get("token") == "s3cr3t" {
+				w.Write(zipData)
+				return
+			}
+			if r.URL.Path != "/guestbook-0.0.1.zip" {
+				http.NotFound(w, r)
+				return
+			}
+			w.Write(zipData)
+		case ".tgz", ".gz":
+			w.Write(tgzData)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func buildZip(t *testing.T) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, file := range archiveFiles {
+		name, content := file[0], file[1]
+		f, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte(content))
+	}
+	zw.Close()
+	return buf.Bytes()
+}
+
+func buildTarball(t *testing.T) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, file := range archiveFiles {
+		name, content := file[0], file[1]
+		hdr := &tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write([]byte(content))
+	}
+	tw.Close()
+	gz.Close()
+	data, err := ioutil.ReadAll(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
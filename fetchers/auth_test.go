@@ -0,0 +1,84 @@
+package fetchers
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestArchiveFetchWithTokenFromEnv(t *testing.T) {
+	server := privateArchiveServer(t, "s3cr3t")
+	defer server.Close()
+
+	archiveFetcher := &ArchiveFetcher{}
+	_, err := archiveFetcher.Fetch(server.URL + "/guestbook-0.0.1.zip")
+	if err == nil {
+		t.Fatalf("ArchiveFetcher should fail without credentials")
+	}
+
+	os.Setenv("KDEPLOY_TOKEN_127_0_0_1", "s3cr3t")
+	defer os.Unsetenv("KDEPLOY_TOKEN_127_0_0_1")
+	kubeware, err := archiveFetcher.Fetch(server.URL + "/guestbook-0.0.1.zip")
+	if err != nil {
+		t.Fatalf("ArchiveFetcher should authenticate with token from environment: %v", err)
+	}
+	kubeware.Close()
+}
+
+func TestArchiveFetchWithNetrc(t *testing.T) {
+	server := privateArchiveServer(t, "n3trc")
+	defer server.Close()
+
+	netrc, _ := ioutil.TempFile("", "netrc")
+	defer os.Remove(netrc.Name())
+	netrc.WriteString("machine example.com login other password wrong\n")
+	netrc.WriteString("machine 127.0.0.1\n  login kdeploy\n  password n3trc\n")
+	netrc.Close()
+	os.Setenv("NETRC", netrc.Name())
+	defer os.Unsetenv("NETRC")
+
+	archiveFetcher := &ArchiveFetcher{}
+	kubeware, err := archiveFetcher.Fetch(server.URL + "/guestbook-0.0.1.zip")
+	if err != nil {
+		t.Fatalf("ArchiveFetcher should authenticate with netrc credentials: %v", err)
+	}
+	kubeware.Close()
+}
+
+func TestGitAuthEnvKeepsTokenOutOfArgs(t *testing.T) {
+	os.Setenv("KDEPLOY_TOKEN_GIT_EXAMPLE_COM", "s3cr3t")
+	defer os.Unsetenv("KDEPLOY_TOKEN_GIT_EXAMPLE_COM")
+
+	env, err := gitAuthEnv("https://git.example.com/ops/kubewares.git")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(env) != 3 || env[1] != "GIT_CONFIG_KEY_0=http.extraHeader" {
+		t.Errorf("unexpected git auth environment: %v", env)
+	}
+
+	os.Setenv("KDEPLOY_SSH_KEY", "/keys/deploy")
+	defer os.Unsetenv("KDEPLOY_SSH_KEY")
+	env, err = gitAuthEnv("git@git.example.com:ops/kubewares.git")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(env) != 1 || env[0] != "GIT_SSH_COMMAND=ssh -i '/keys/deploy' -o IdentitiesOnly=yes" {
+		t.Errorf("unexpected git ssh environment: %v", env)
+	}
+}
+
+// privateArchiveServer serves the test archive only to requests carrying the token
+func privateArchiveServer(t *testing.T, token string) *httptest.Server {
+	zipData := buildZip(t)
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, password, basic := r.BasicAuth()
+		if r.Header.Get("Authorization") != "Bearer "+token && !(basic && password == token) {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Write(zipData)
+	}))
+}
@@ -0,0 +1,301 @@
+package fetchers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/flexiant/kdeploy/utils"
+	"gopkg.in/yaml.v2"
+)
+
+// DefaultCacheTTL is how long a cached kubeware is reused before fetching it again
+const DefaultCacheTTL = 1 * time.Hour
+
+// commitRef matches refs that pin a kubeware to a full commit id, which never change
+var commitRef = regexp.MustCompile(`[@#][0-9a-f]{40}$`)
+
+// Cache keeps fetched kubewares in a local directory. Contents are stored once per digest
+// under 'objects', and every fetched source keeps an entry under 'refs' pointing to them
+type Cache struct {
+	Dir     string
+	TTL     time.Duration
+	Offline bool // Serve only from cache, never fetch
+}
+
+// CacheEntry describes a fetched source kept in the cache. Credentials in the source are redacted
+type CacheEntry struct {
+	Source  string `yaml:"source"`
+	Commit  string `yaml:"commit,omitempty"`
+	Digest  string `yaml:"digest"`
+	Fetched string `yaml:"fetched"`
+	file    string
+}
+
+// DefaultCache builds the cache kept in the kdeploy directory, configured from the environment
+func DefaultCache() (*Cache, error) {
+	dir, err := utils.KdeployDir()
+	if err != nil {
+		return nil, err
+	}
+	ttl := DefaultCacheTTL
+	if v := os.Getenv("KDEPLOY_CACHE_TTL"); v != "" {
+		ttl, err = time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cache TTL '%s': %v", v, err)
+		}
+	}
+	return &Cache{
+		Dir:     filepath.Join(dir, "cache"),
+		TTL:     ttl,
+		Offline: os.Getenv("KDEPLOY_OFFLINE") == "1",
+	}, nil
+}
+
+// Fetch returns the cached copy of the kubeware if it is still fresh, or fetches it again
+// and stores it otherwise. Whatever the fetcher created is removed once stored
+func (c *Cache) Fetch(fetcher KubewareFetchers, kpath string) (*Kubeware, error) {
+	entry, err := c.lookup(kpath)
+	if err != nil {
+		return nil, err
+	}
+	if entry != nil && (c.Offline || !c.Expired(entry)) {
+		log.Debugf("Using cached kubeware for %s (%s)", utils.RedactURL(kpath), entry.Digest)
+		return c.kubeware(entry), nil
+	}
+	if c.Offline {
+		return nil, fmt.Errorf("kubeware '%s' is not cached and kdeploy is running offline", utils.RedactURL(kpath))
+	}
+
+	fetched, err := fetcher.Fetch(kpath)
+	if err != nil {
+		return nil, err
+	}
+	defer fetched.Close()
+	digest, err := utils.DirDigest(fetched.Path)
+	if err != nil {
+		return nil, err
+	}
+	err = c.store(fetched.Path, digest)
+	if err != nil {
+		return nil, err
+	}
+
+	entry = &CacheEntry{
+		Source:  utils.RedactURL(kpath),
+		Commit:  fetched.Commit,
+		Digest:  digest,
+		Fetched: time.Now().UTC().Format(time.RFC3339),
+		file:    c.refPath(kpath),
+	}
+	err = c.save(entry)
+	if err != nil {
+		return nil, err
+	}
+	// kubewares fetched at a moving ref are also kept by the commit it resolved to, so that
+	// later fetches pinned to it are served from the cache
+	if fetched.Commit != "" {
+		source, _ := splitRef(kpath)
+		pinned := fmt.Sprintf("%s@%s", source, fetched.Commit)
+		if c.refPath(pinned) != entry.file {
+			err = c.save(&CacheEntry{
+				Source:  utils.RedactURL(pinned),
+				Commit:  fetched.Commit,
+				Digest:  digest,
+				Fetched: entry.Fetched,
+				file:    c.refPath(pinned),
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return c.kubeware(entry), nil
+}
+
+// Expired tells if a cached entry is older than the cache TTL. Entries pinned to a
+// commit never expire
+func (c *Cache) Expired(entry *CacheEntry) bool {
+	if commitRef.MatchString(entry.Source) {
+		return false
+	}
+	fetched, err := time.Parse(time.RFC3339, entry.Fetched)
+	if err != nil {
+		return true
+	}
+	return time.Since(fetched) > c.TTL
+}
+
+// Entries returns all the cached entries sorted by source
+func (c *Cache) Entries() ([]CacheEntry, error) {
+	files, err := ioutil.ReadDir(c.refsDir())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	entries := []CacheEntry{}
+	for _, f := range files {
+		entry, err := readCacheEntry(filepath.Join(c.refsDir(), f.Name()))
+		if err != nil {
+			log.Warnf("Ignoring unreadable cache entry %s: %v", f.Name(), err)
+			continue
+		}
+		entries = append(entries, *entry)
+	}
+	sort.Sort(entriesBySource(entries))
+	return entries, nil
+}
+
+// Prune removes expired entries, or every entry if all is set, and then any cached
+// contents no longer referenced. It returns the sources removed
+func (c *Cache) Prune(all bool) ([]string, error) {
+	entries, err := c.Entries()
+	if err != nil {
+		return nil, err
+	}
+	removed := []string{}
+	referenced := map[string]bool{}
+	for _, entry := range entries {
+		if all || c.Expired(&entry) {
+			err = os.Remove(entry.file)
+			if err != nil {
+				return nil, err
+			}
+			removed = append(removed, entry.Source)
+			continue
+		}
+		referenced[entry.Digest] = true
+	}
+
+	objects, err := ioutil.ReadDir(c.objectsDir())
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, o := range objects {
+		if !referenced[o.Name()] {
+			log.Debugf("Removing unreferenced cached contents %s", o.Name())
+			err = os.RemoveAll(filepath.Join(c.objectsDir(), o.Name()))
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return removed, nil
+}
+
+func (c *Cache) lookup(kpath string) (*CacheEntry, error) {
+	path := c.refPath(kpath)
+	if !utils.FileExists(path) {
+		return nil, nil
+	}
+	entry, err := readCacheEntry(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read cache entry for '%s': %v", utils.RedactURL(kpath), err)
+	}
+	if !utils.FileExists(c.objectPath(entry.Digest)) {
+		log.Debugf("Cached contents for %s are gone", utils.RedactURL(kpath))
+		return nil, nil
+	}
+	return entry, nil
+}
+
+// store copies the fetched kubeware into the cache, unless its contents are already there
+func (c *Cache) store(fetched, digest string) error {
+	object := c.objectPath(digest)
+	if utils.FileExists(object) {
+		return nil
+	}
+	err := os.MkdirAll(c.objectsDir(), 0755)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempDir(c.objectsDir(), ".tmp")
+	if err != nil {
+		return err
+	}
+	err = utils.CopyDir(fetched, tmp)
+	if err != nil {
+		os.RemoveAll(tmp)
+		return err
+	}
+	return os.Rename(tmp, object)
+}
+
+func (c *Cache) save(entry *CacheEntry) error {
+	err := os.MkdirAll(c.refsDir(), 0755)
+	if err != nil {
+		return err
+	}
+	data, err := yaml.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(entry.file, data, 0600)
+}
+
+func (c *Cache) refsDir() string {
+	return filepath.Join(c.Dir, "refs")
+}
+
+func (c *Cache) objectsDir() string {
+	return filepath.Join(c.Dir, "objects")
+}
+
+func (c *Cache) refPath(kpath string) string {
+	key := sha256.Sum256([]byte(refKey(kpath)))
+	return filepath.Join(c.refsDir(), fmt.Sprintf("%s.yaml", hex.EncodeToString(key[:])))
+}
+
+// refKey returns the key a kubeware is cached by. Kubewares pinned to a commit are keyed by their
+// source and commit, whether the commit was given after '@' or '#', and others by their path
+func refKey(kpath string) string {
+	if !commitRef.MatchString(kpath) {
+		return kpath
+	}
+	source, commit := splitRef(kpath)
+	return fmt.Sprintf("%s@%s", source, commit)
+}
+
+// kubeware returns a handle to the cached contents of an entry, which are kept when it is closed
+func (c *Cache) kubeware(entry *CacheEntry) *Kubeware {
+	kubeware := NewKubeware(c.objectPath(entry.Digest), "")
+	kubeware.Commit = entry.Commit
+	return kubeware
+}
+
+func (c *Cache) objectPath(digest string) string {
+	return filepath.Join(c.objectsDir(), digest)
+}
+
+func readCacheEntry(path string) (*CacheEntry, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var entry CacheEntry
+	err = yaml.Unmarshal(data, &entry)
+	if err != nil {
+		return nil, err
+	}
+	if entry.Source == "" || entry.Digest == "" || strings.ContainsAny(entry.Digest, `/\.`) {
+		return nil, fmt.Errorf("malformed cache entry")
+	}
+	entry.file = path
+	return &entry, nil
+}
+
+type entriesBySource []CacheEntry
+
+func (e entriesBySource) Len() int           { return len(e) }
+func (e entriesBySource) Swap(i, j int)      { e[i], e[j] = e[j], e[i] }
+func (e entriesBySource) Less(i, j int) bool { return e[i].Source < e[j].Source }
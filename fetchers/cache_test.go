@@ -0,0 +1,175 @@
+package fetchers
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// countingFetcher fetches a fake kubeware and counts how many times it did
+type countingFetcher struct {
+	fetches int
+	dirs    []string
+}
+
+func (f *countingFetcher) CanHandle(kpath string) bool {
+	return true
+}
+
+func (f *countingFetcher) Fetch(kpath string) (*Kubeware, error) {
+	f.fetches++
+	dir, _ := ioutil.TempDir("", "kdeploy")
+	ioutil.WriteFile(filepath.Join(dir, "metadata.yaml"), []byte{}, 0644)
+	f.dirs = append(f.dirs, dir)
+	return NewKubeware(dir, dir), nil
+}
+
+func (f *countingFetcher) cleanup() {
+	for _, dir := range f.dirs {
+		os.RemoveAll(dir)
+	}
+}
+
+func TestCacheReusesFreshEntries(t *testing.T) {
+	cache := tempCache(time.Hour)
+	defer os.RemoveAll(cache.Dir)
+	fetcher := &countingFetcher{}
+	defer fetcher.cleanup()
+
+	first, err := cache.Fetch(fetcher, "https://example.com/kubeware.tgz")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	second, err := cache.Fetch(fetcher, "https://example.com/kubeware.tgz")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fetcher.fetches != 1 {
+		t.Errorf("expected a single fetch, got %d", fetcher.fetches)
+	}
+	if first.Path != second.Path {
+		t.Errorf("expected the same cached path, got %s and %s", first.Path, second.Path)
+	}
+	first.Close()
+	if _, err := os.Stat(filepath.Join(first.Path, "metadata.yaml")); err != nil {
+		t.Errorf("kubeware not stored in cache: %v", err)
+	}
+	if _, err := os.Stat(fetcher.dirs[0]); !os.IsNotExist(err) {
+		t.Errorf("fetched kubeware should be removed once cached")
+	}
+}
+
+func TestCacheFetchesExpiredEntries(t *testing.T) {
+	cache := tempCache(0)
+	defer os.RemoveAll(cache.Dir)
+	fetcher := &countingFetcher{}
+	defer fetcher.cleanup()
+
+	cache.Fetch(fetcher, "https://example.com/kubeware.tgz")
+	cache.Fetch(fetcher, "https://example.com/kubeware.tgz")
+	if fetcher.fetches != 2 {
+		t.Errorf("expected two fetches, got %d", fetcher.fetches)
+	}
+
+	// pinned commits never expire
+	pinned := "https://github.com/org/kubeware#4b825dc642cb6eb9a060e54bf8d69288fbee4904"
+	cache.Fetch(fetcher, pinned)
+	cache.Fetch(fetcher, pinned)
+	if fetcher.fetches != 3 {
+		t.Errorf("expected pinned commit to be fetched once, got %d fetches", fetcher.fetches-2)
+	}
+}
+
+// cloningFetcher fetches a fake kubeware as a git clone would, with metadata differing on
+// every clone, at a fixed commit
+type cloningFetcher struct {
+	countingFetcher
+	commit string
+}
+
+func (f *cloningFetcher) Fetch(kpath string) (*Kubeware, error) {
+	kubeware, _ := f.countingFetcher.Fetch(kpath)
+	os.MkdirAll(filepath.Join(kubeware.Path, ".git"), 0755)
+	ioutil.WriteFile(filepath.Join(kubeware.Path, ".git", "index"), []byte(time.Now().String()), 0644)
+	kubeware.Commit = f.commit
+	return kubeware, nil
+}
+
+func TestCacheKeysByCommit(t *testing.T) {
+	cache := tempCache(0)
+	defer os.RemoveAll(cache.Dir)
+	commit := "4b825dc642cb6eb9a060e54bf8d69288fbee4904"
+	fetcher := &cloningFetcher{commit: commit}
+	defer fetcher.cleanup()
+
+	// clones of the same contents are stored once, without their git metadata
+	first, _ := cache.Fetch(fetcher, "https://example.com/kubeware.git@master")
+	second, _ := cache.Fetch(fetcher, "https://example.com/kubeware.git@master")
+	if fetcher.fetches != 2 {
+		t.Errorf("expected the moving ref to be fetched again, got %d fetches", fetcher.fetches)
+	}
+	if first.Path != second.Path {
+		t.Errorf("expected the same cached contents, got %s and %s", first.Path, second.Path)
+	}
+	if _, err := os.Stat(filepath.Join(first.Path, ".git")); !os.IsNotExist(err) {
+		t.Errorf("git metadata should not be cached")
+	}
+
+	// the commit the ref resolved to is served from the cache however it is pinned
+	cache.Fetch(fetcher, "https://example.com/kubeware.git@"+commit)
+	cache.Fetch(fetcher, "https://example.com/kubeware.git#"+commit)
+	if fetcher.fetches != 2 {
+		t.Errorf("expected the pinned commit to be served from the cache, got %d fetches", fetcher.fetches-2)
+	}
+}
+
+func TestCacheOffline(t *testing.T) {
+	cache := tempCache(0)
+	defer os.RemoveAll(cache.Dir)
+	fetcher := &countingFetcher{}
+	defer fetcher.cleanup()
+
+	cache.Fetch(fetcher, "https://example.com/kubeware.tgz")
+	cache.Offline = true
+
+	_, err := cache.Fetch(fetcher, "https://example.com/kubeware.tgz")
+	if err != nil {
+		t.Errorf("expired entries should be served offline: %v", err)
+	}
+	_, err = cache.Fetch(fetcher, "https://example.com/other.tgz")
+	if err == nil {
+		t.Errorf("should not fetch uncached kubewares offline")
+	}
+	if fetcher.fetches != 1 {
+		t.Errorf("expected a single fetch, got %d", fetcher.fetches)
+	}
+}
+
+func TestCachePrune(t *testing.T) {
+	cache := tempCache(time.Hour)
+	defer os.RemoveAll(cache.Dir)
+	fetcher := &countingFetcher{}
+	defer fetcher.cleanup()
+
+	cache.Fetch(fetcher, "https://example.com/kubeware.tgz")
+	removed, err := cache.Prune(false)
+	if err != nil || len(removed) != 0 {
+		t.Fatalf("no fresh entry should be pruned: %v %v", removed, err)
+	}
+	removed, err = cache.Prune(true)
+	if err != nil || len(removed) != 1 {
+		t.Fatalf("every entry should be pruned: %v %v", removed, err)
+	}
+	entries, _ := cache.Entries()
+	objects, _ := ioutil.ReadDir(cache.objectsDir())
+	if len(entries) != 0 || len(objects) != 0 {
+		t.Errorf("cache should be empty, found %d entries and %d objects", len(entries), len(objects))
+	}
+}
+
+func tempCache(ttl time.Duration) *Cache {
+	dir, _ := ioutil.TempDir("", "kdeploy-cache")
+	return &Cache{Dir: dir, TTL: ttl}
+}
@@ -1,31 +1,153 @@
 package fetchers
 
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
 // KubewareFetchers objects will handle the download and extraction of a kubeware into a local directory. There
 // will be different implementations to manage different kinds of supported sources (e.g. github repo, local dir)
 type KubewareFetchers interface {
 	CanHandle(kpath string) bool
-	Fetch(kpath string) (string, error)
+	Fetch(kpath string) (*Kubeware, error)
+}
+
+// Kubeware is a handle to a fetched kubeware. Closing it removes any temporal files created
+// to fetch it
+type Kubeware struct {
+	Path   string // Local path of the kubeware
+	Commit string // Commit the kubeware was checked out at, for kubewares fetched from git
+	tmpDir string
+}
+
+// NewKubeware returns a handle to a kubeware fetched into path. The temporal directory, if
+// any, is removed when the handle is closed
+func NewKubeware(path, tmpDir string) *Kubeware {
+	return &Kubeware{Path: path, tmpDir: tmpDir}
+}
+
+// Close removes the temporal files created to fetch the kubeware. It is safe to call more than once
+func (k *Kubeware) Close() error {
+	if k == nil || k.tmpDir == "" {
+		return nil
+	}
+	err := os.RemoveAll(k.tmpDir)
+	k.tmpDir = ""
+	return err
 }
 
-// allFetchers is the collection of available fetchers
-var allFetchers = []KubewareFetchers{
-	&GithubFetcher{},
-	&LocaldirFetcher{},
+// Priorities of the built-in fetchers. Fetchers with lower values are tried first
+const (
+	PriorityGit     = 10
+	PriorityArchive = 20
+	PriorityGithub  = 30
+	PriorityLocal   = 40
+)
+
+func init() {
+	Register(PriorityGit, "git", &GitFetcher{})
+	Register(PriorityArchive, "archive", &ArchiveFetcher{})
+	Register(PriorityGithub, "github", &GithubFetcher{})
+	Register(PriorityLocal, "local", &LocaldirFetcher{})
 }
 
-// Fetch the indicated kubeware
-func Fetch(kpath string) (string, error) {
-	var localKubePath string
-	var err error
+// Fetch the indicated kubeware using the first registered fetcher that can handle it, and
+// verify its integrity. Kubewares from remote sources are served through the local cache.
+// The returned kubeware must be closed once it is no longer needed
+func Fetch(kpath string) (*Kubeware, error) {
+	fetcher, err := defaultRegistry.resolve(kpath)
+	if err != nil {
+		return nil, err
+	}
 
-	for _, fetcher := range allFetchers {
-		if fetcher.CanHandle(kpath) {
-			localKubePath, err = fetcher.Fetch(kpath)
-			if err != nil {
-				return "", err
-			}
+	var kubeware *Kubeware
+	if _, local := fetcher.(*LocaldirFetcher); local {
+		kubeware, err = fetcher.Fetch(kpath)
+	} else {
+		var cache *Cache
+		cache, err = DefaultCache()
+		if err != nil {
+			return nil, err
 		}
+		kubeware, err = cache.Fetch(fetcher, kpath)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = verify(kubeware.Path)
+	if err != nil {
+		kubeware.Close()
+		return nil, err
 	}
+	return kubeware, nil
+}
 
-	return localKubePath, nil
+// fetchInto creates a temporal directory, fetches the kubeware into it and returns a handle
+// removing the directory when closed. The directory is removed right away if the fetch fails
+func fetchInto(fetch func(tmpDir string) (string, error)) (*Kubeware, error) {
+	tmpDir, err := ioutil.TempDir("", "kdeploy")
+	if err != nil {
+		return nil, err
+	}
+	path, err := fetch(tmpDir)
+	if err != nil {
+		os.RemoveAll(tmpDir)
+		return nil, err
+	}
+	return NewKubeware(path, tmpDir), nil
+}
+
+// splitRef separates the source of a kubeware from the branch, tag or commit it is pinned to,
+// which can be given either as 'source@ref' or 'source#ref'
+func splitRef(kpath string) (string, string) {
+	if i := strings.LastIndex(kpath, "#"); i > -1 {
+		return kpath[:i], kpath[i+1:]
+	}
+	// an '@' before the last path separator belongs to the user info (e.g. git@github.com:org/repo)
+	i := strings.LastIndex(kpath, "@")
+	if i > -1 && i > strings.LastIndexAny(kpath, "/:") {
+		return kpath[:i], kpath[i+1:]
+	}
+	return kpath, ""
+}
+
+// splitSubdir separates the source of a repository from the subdirectory holding the kubeware,
+// which is given after a double slash (e.g. 'https://example.com/kubewares.git//redis')
+func splitSubdir(source string) (string, string) {
+	start := 0
+	if i := strings.Index(source, "://"); i > -1 {
+		start = i + len("://")
+	}
+	i := strings.Index(source[start:], "//")
+	if i == -1 {
+		return source, ""
+	}
+	return source[:start+i], strings.Trim(source[start+i+2:], "/")
+}
+
+// resolveSubdir returns the path of the kubeware inside a fetched repository, checking that it
+// neither escapes the repository nor lacks a metadata file
+func resolveSubdir(root, subdir string) (string, error) {
+	kpath := filepath.Join(root, filepath.FromSlash(subdir))
+	rel, err := filepath.Rel(root, kpath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("kubeware subdirectory '%s' is outside the repository", subdir)
+	}
+	if !isKubewareDir(kpath) {
+		return "", fmt.Errorf("no kubeware metadata found at '%s'", subdir)
+	}
+	return kpath, nil
+}
+
+// isKubewareDir tells if the directory holds a kubeware metadata file
+func isKubewareDir(path string) bool {
+	metadata := filepath.Join(path, "metadata.yaml")
+	if _, err := os.Stat(metadata); os.IsNotExist(err) {
+		return false
+	}
+	return true
 }
@@ -0,0 +1,186 @@
+package fetchers
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/flexiant/kdeploy/utils"
+	"github.com/mitchellh/go-homedir"
+)
+
+// scpLikeURL matches git remotes written as 'user@host:path'
+var scpLikeURL = regexp.MustCompile(`^[\w.-]+@[\w.-]+:[^/]`)
+
+// GitFetcher clones a kubeware from a git remote, optionally pinned to a branch, tag or commit
+// with 'remote@ref' or 'remote#ref'. Kubewares kept in a subdirectory of the repository are
+// addressed with 'remote//subdir'. Credentials found for the remote host are used for https
+// remotes, and KDEPLOY_SSH_KEY or the host's configured key for ssh ones
+type GitFetcher struct{}
+
+// CanHandle tells if the remote can be handled by this resolver
+func (g *GitFetcher) CanHandle(kpath string) bool {
+	remote, _ := splitRef(kpath)
+	remote, _ = splitSubdir(remote)
+	if scpLikeURL.MatchString(remote) {
+		return true
+	}
+	uri, err := url.Parse(remote)
+	if err != nil {
+		return false
+	}
+	switch uri.Scheme {
+	case "git", "ssh", "file", "git+ssh", "git+http", "git+https":
+		return uri.Path != ""
+	case "http", "https":
+		return strings.HasSuffix(uri.Path, ".git")
+	}
+	return false
+}
+
+// Fetch clones the remote into a temporal local directory, checks out the requested
+// ref and returns the kubeware in it, along with the commit it was checked out at
+func (g *GitFetcher) Fetch(kpath string) (*Kubeware, error) {
+	if !g.CanHandle(kpath) {
+		return nil, fmt.Errorf("URL can't be handled by GitFetcher: '%s'", kpath)
+	}
+	remote, ref := splitRef(kpath)
+	remote, subdir := splitSubdir(remote)
+	remote = strings.TrimPrefix(remote, "git+")
+
+	var commit string
+	kubeware, err := fetchInto(func(tmpDir string) (string, error) {
+		var path string
+		var err error
+		path, commit, err = cloneRemote(remote, ref, subdir, tmpDir)
+		return path, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	kubeware.Commit = commit
+	return kubeware, nil
+}
+
+// cloneRemote clones the remote into tmpDir and checks out the ref, returning the path of the
+// kubeware and the commit it was checked out at
+func cloneRemote(remote, ref, subdir, tmpDir string) (string, string, error) {
+	repoDir := filepath.Join(tmpDir, "repo")
+
+	env, err := gitAuthEnv(remote)
+	if err != nil {
+		return "", "", err
+	}
+	_, err = runGitWithEnv("", env, "clone", "--quiet", remote, repoDir)
+	if err != nil {
+		return "", "", err
+	}
+	if ref != "" {
+		_, err = runGit(repoDir, "checkout", "--quiet", ref)
+		if err != nil {
+			return "", "", err
+		}
+	}
+	commit, err := runGit(repoDir, "rev-parse", "HEAD")
+	if err != nil {
+		return "", "", err
+	}
+	log.Infof("Fetched %s at commit %s", utils.RedactURL(remote), commit)
+
+	path, err := resolveSubdir(repoDir, subdir)
+	return path, commit, err
+}
+
+// gitAuthEnv builds the environment git needs to authenticate against the remote host. Credentials
+// are passed through the environment rather than the command line, so they are neither logged
+// nor visible to other processes
+func gitAuthEnv(remote string) ([]string, error) {
+	host, sshRemote := remoteHost(remote)
+	if host == "" {
+		return nil, nil
+	}
+	creds, err := utils.CredentialsFor(host)
+	if err != nil {
+		return nil, err
+	}
+	if sshRemote {
+		key := os.Getenv("KDEPLOY_SSH_KEY")
+		if key == "" && creds != nil {
+			key = creds.SSHKey
+		}
+		if key == "" {
+			return nil, nil
+		}
+		key, err = homedir.Expand(key)
+		if err != nil {
+			return nil, err
+		}
+		return []string{fmt.Sprintf("GIT_SSH_COMMAND=ssh -i '%s' -o IdentitiesOnly=yes", key)}, nil
+	}
+	if creds == nil || creds.Token == "" {
+		return nil, nil
+	}
+	username := creds.Username
+	if username == "" {
+		username = "x-access-token"
+	}
+	auth := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%s", username, creds.Token)))
+	return []string{
+		"GIT_CONFIG_COUNT=1",
+		"GIT_CONFIG_KEY_0=http.extraHeader",
+		fmt.Sprintf("GIT_CONFIG_VALUE_0=Authorization: Basic %s", auth),
+	}, nil
+}
+
+// remoteHost returns the host of a git remote and whether it is reached through ssh
+func remoteHost(remote string) (string, bool) {
+	if scpLikeURL.MatchString(remote) {
+		hostPath := remote[strings.Index(remote, "@")+1:]
+		return hostPath[:strings.Index(hostPath, ":")], true
+	}
+	uri, err := url.Parse(remote)
+	if err != nil {
+		return "", false
+	}
+	switch uri.Scheme {
+	case "ssh", "git+ssh":
+		return uri.Hostname(), true
+	case "http", "https", "git+http", "git+https":
+		return uri.Hostname(), false
+	}
+	return "", false
+}
+
+// runGit executes a git command inside dir and returns its trimmed output
+func runGit(dir string, args ...string) (string, error) {
+	return runGitWithEnv(dir, nil, args...)
+}
+
+// runGitWithEnv executes a git command inside dir with additional environment variables,
+// which are not logged, and returns its trimmed output
+func runGitWithEnv(dir string, env []string, args ...string) (string, error) {
+	redacted := make([]string, len(args))
+	for i, arg := range args {
+		redacted[i] = utils.RedactURL(arg)
+	}
+	log.Debugf("Running git %s", strings.Join(redacted, " "))
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s failed: %v (%s)", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/url"
+	"path/filepath"
 	"strings"
 
 	"github.com/asaskevich/govalidator"
@@ -11,72 +12,111 @@ import (
 	"github.com/flexiant/kdeploy/webservice"
 )
 
-// GithubFetcher downloads and extracts a kubeware from a github repo
+// GithubFetcher downloads and extracts a kubeware from a github repo, optionally pinned to a
+// branch, tag or commit with 'repo@ref' or 'repo#ref'. Kubewares kept in a subdirectory of the
+// repo are addressed as in github's web UI ('repo/tree/ref/subdir') or with 'repo//subdir'.
+// Private repos are fetched through the github API when there are credentials for github.com
 type GithubFetcher struct{}
 
+// githubSource identifies a kubeware inside a github repo
+type githubSource struct {
+	owner  string
+	repo   string
+	ref    string
+	subdir string
+}
+
 // CanHandle tells if the URL can be handled by this resolver
 func (gh *GithubFetcher) CanHandle(kubeware string) bool {
+	_, err := gh.parse(kubeware)
+	return err == nil
+}
+
+func (gh *GithubFetcher) parse(kubeware string) (*githubSource, error) {
+	kubeware, ref := splitRef(kubeware)
+	kubeware, subdir := splitSubdir(kubeware)
 	if !govalidator.IsURL(kubeware) {
-		return false
+		return nil, fmt.Errorf("not a valid URL: '%s'", kubeware)
 	}
 	kubewareURL, err := url.Parse(kubeware)
 	if err != nil {
-		return false
+		return nil, err
+	}
+	if kubewareURL.Host != "github.com" {
+		return nil, fmt.Errorf("not a github URL: '%s'", kubeware)
 	}
-	return gh.canHandleURL(kubewareURL)
+	path := strings.Split(strings.TrimSuffix(kubewareURL.Path, "/"), "/")
+	// git remotes are left to GitFetcher
+	if len(path) < 3 || strings.HasSuffix(path[2], ".git") {
+		return nil, fmt.Errorf("not a github repo URL: '%s'", kubeware)
+	}
+	src := &githubSource{owner: path[1], repo: path[2], ref: ref, subdir: subdir}
+	switch {
+	case len(path) == 3:
+	case len(path) >= 5 && path[3] == "tree" && ref == "" && subdir == "":
+		src.ref = path[4]
+		src.subdir = strings.Join(path[5:], "/")
+	default:
+		return nil, fmt.Errorf("not a github repo URL: '%s'", kubeware)
+	}
+	if src.ref == "" {
+		src.ref = "master"
+	}
+	return src, nil
 }
 
-func (gh *GithubFetcher) canHandleURL(uri *url.URL) bool {
-	if uri.Host == "github.com" {
-		path := strings.Split(uri.Path, "/")
-		if len(path) != 3 {
-			return false
-		}
-		return true
+// Fetch downloads and extract the archive zip for the requested ref (master by default)
+// from github repo into a temporal local directory, and returns the path of the kubeware in it
+func (gh *GithubFetcher) Fetch(kware string) (*Kubeware, error) {
+	src, err := gh.parse(kware)
+	if err != nil {
+		return nil, fmt.Errorf("URL can't be handled by GithubFetcher: '%s' (%v)", kware, err)
 	}
-	return false
+	return fetchInto(func(tmpDir string) (string, error) {
+		return gh.fetchSource(src, tmpDir)
+	})
 }
 
-// Fetch downloads and extract archive/master zip from github repo into
-// a temporal local directory, and returns its path
-func (gh *GithubFetcher) Fetch(kware string) (string, error) {
-	if !gh.CanHandle(kware) {
-		return "", fmt.Errorf("URL can't be handled by GithubFetcher: '%s'", kware)
-	}
-	kubewareURL, err := url.Parse(kware)
+func (gh *GithubFetcher) fetchSource(src *githubSource, tmpDir string) (string, error) {
+	credentials, err := utils.CredentialsFor("github.com")
 	if err != nil {
 		return "", err
 	}
-	return gh.fetchURL(kubewareURL)
-}
-
-func (gh *GithubFetcher) fetchURL(uri *url.URL) (string, error) {
-
-	path := strings.Split(uri.Path, "/")
-	kubewareName := path[2]
-	newPath := append([]string{""}, path[1], path[2], "archive", "master.zip")
-
-	kubewareURL := *uri
-	kubewareURL.Path = strings.Join(newPath, "/")
-	client, err := webservice.NewSimpleWebClient(kubewareURL.String())
+	kubewareURL := url.URL{
+		Scheme: "https",
+		Host:   "github.com",
+		Path:   strings.Join([]string{"", src.owner, src.repo, "archive", fmt.Sprintf("%s.zip", src.ref)}, "/"),
+	}
+	if credentials != nil && credentials.Token != "" {
+		// archives of private repos are only served through the API
+		kubewareURL.Host = "api.github.com"
+		kubewareURL.Path = strings.Join([]string{"", "repos", src.owner, src.repo, "zipball", src.ref}, "/")
+	}
+	client, err := webservice.NewSimpleWebClientWithCredentials(kubewareURL.String(), credentials)
 	if err != nil {
 		return "", err
 	}
 
-	tmpDir, err := ioutil.TempDir("", "kdeploy")
+	zipFileLocation, err := client.GetFile(kubewareURL.Path, tmpDir)
 	if err != nil {
 		return "", err
 	}
 
-	zipFileLocation, err := client.GetFile(kubewareURL.Path, tmpDir)
+	extractDir := filepath.Join(tmpDir, "src")
+	err = utils.Unzip(zipFileLocation, extractDir)
 	if err != nil {
 		return "", err
 	}
 
-	err = utils.Unzip(zipFileLocation, tmpDir)
+	// github names the top level directory after the repo and the ref, with some
+	// transformations on tags, so we just pick the only one there is
+	dirs, err := ioutil.ReadDir(extractDir)
 	if err != nil {
 		return "", err
 	}
+	if len(dirs) != 1 || !dirs[0].IsDir() {
+		return "", fmt.Errorf("unexpected layout for github archive %s", kubewareURL.String())
+	}
 
-	return fmt.Sprintf("%s/%s-master/", tmpDir, kubewareName), nil
+	return resolveSubdir(filepath.Join(extractDir, dirs[0].Name()), src.subdir)
 }
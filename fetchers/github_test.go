@@ -51,24 +51,53 @@ func TestGithubCanNotHandleIncorrectGithubURLs(t *testing.T) {
 	}
 }
 
+func TestGithubParseRefsAndSubdirs(t *testing.T) {
+	githubFetcher := &GithubFetcher{}
+	cases := map[string]githubSource{
+		"https://github.com/org/kubewares":                      {"org", "kubewares", "master", ""},
+		"https://github.com/org/kubewares@v1.2.0":               {"org", "kubewares", "v1.2.0", ""},
+		"https://github.com/org/kubewares/tree/v1.2.0/redis":    {"org", "kubewares", "v1.2.0", "redis"},
+		"https://github.com/org/kubewares/tree/master/apps/web": {"org", "kubewares", "master", "apps/web"},
+		"https://github.com/org/kubewares//redis#4b825dc":       {"org", "kubewares", "4b825dc", "redis"},
+	}
+	for uri, expected := range cases {
+		src, err := githubFetcher.parse(uri)
+		if err != nil {
+			t.Errorf("GithubFetcher should handle '%s': %v", uri, err)
+			continue
+		}
+		if *src != expected {
+			t.Errorf("unexpected source for '%s': %+v", uri, *src)
+		}
+	}
+}
+
+func TestGithubCanNotHandleGitRemotes(t *testing.T) {
+	uri := "https://github.com/org/kubewares.git"
+	githubFetcher := &GithubFetcher{}
+	if githubFetcher.CanHandle(uri) {
+		t.Fatalf("GithubFetcher should not handle '%s'", uri)
+	}
+}
+
 func TestFetch(t *testing.T) {
 	githubFetcher := &GithubFetcher{}
 
 	uri := "https://github.com/flexiant/kubeware-spark"
-	localPath, err := githubFetcher.Fetch(uri)
-	if err != nil || localPath == "" {
+	kubeware, err := githubFetcher.Fetch(uri)
+	if err != nil || kubeware.Path == "" {
 		t.Fatalf("GithubFetcher could not resolve '%s'", uri)
 	}
-	t.Logf("-> %s", localPath)
+	t.Logf("-> %v", kubeware)
 }
 
 func TestFetchWithInvalidURL(t *testing.T) {
 	githubFetcher := &GithubFetcher{}
 
 	uri := "https://qwlidg.com"
-	localPath, err := githubFetcher.Fetch(uri)
+	kubeware, err := githubFetcher.Fetch(uri)
 	if err == nil {
 		t.Fatalf("GithubFetcher should have returned error for '%s'", uri)
-		t.Logf("-> %s", localPath)
+		t.Logf("-> %v", kubeware)
 	}
 }
@@ -2,7 +2,6 @@ package fetchers
 
 import (
 	"fmt"
-	"os"
 	"path/filepath"
 )
 
@@ -15,17 +14,17 @@ func (gh *LocaldirFetcher) CanHandle(kpath string) bool {
 	if err != nil || abs == "" {
 		return false
 	}
-	metadata := filepath.Join(abs, "metadata.yaml")
-	if _, err := os.Stat(metadata); os.IsNotExist(err) {
-		return false
-	}
-	return true
+	return isKubewareDir(abs)
 }
 
-// Fetch simply returns its absolute path
-func (gh *LocaldirFetcher) Fetch(kpath string) (string, error) {
+// Fetch simply returns its absolute path. Closing the kubeware leaves the directory untouched
+func (gh *LocaldirFetcher) Fetch(kpath string) (*Kubeware, error) {
 	if !gh.CanHandle(kpath) {
-		return "", fmt.Errorf("URL can't be handled by LocaldirFetcher: '%s'", kpath)
+		return nil, fmt.Errorf("URL can't be handled by LocaldirFetcher: '%s'", kpath)
+	}
+	abs, err := filepath.Abs(kpath)
+	if err != nil {
+		return nil, err
 	}
-	return filepath.Abs(kpath)
+	return NewKubeware(abs, ""), nil
 }
@@ -49,11 +49,16 @@ func TestLocaldirFetch(t *testing.T) {
 	kurl := tempFakeKubeware()
 	defer os.RemoveAll(kurl)
 
-	localPath, err := githubFetcher.Fetch(kurl)
-	if err != nil || localPath == "" {
+	kubeware, err := githubFetcher.Fetch(kurl)
+	if err != nil || kubeware.Path == "" {
 		t.Errorf("LocaldirFetcher could not resolve '%s'", kurl)
 	}
-	t.Logf("-> %s", localPath)
+	t.Logf("-> %v", kubeware)
+
+	kubeware.Close()
+	if _, err := os.Stat(kurl); err != nil {
+		t.Errorf("closing a local kubeware should leave it in place: %v", err)
+	}
 }
 
 func TestLocaldirFetchRelativePath(t *testing.T) {
@@ -62,11 +67,11 @@ func TestLocaldirFetchRelativePath(t *testing.T) {
 	defer os.RemoveAll(abs)
 
 	path, _ := filepath.Rel(".", abs)
-	localPath, err := githubFetcher.Fetch(path)
-	if err != nil || localPath == "" {
+	kubeware, err := githubFetcher.Fetch(path)
+	if err != nil || kubeware.Path == "" {
 		t.Errorf("LocaldirFetcher should resolve '%s'", path)
 	}
-	t.Logf("-> %s", localPath)
+	t.Logf("-> %v", kubeware)
 }
 
 func TestLocaldirFetchNoMetadata(t *testing.T) {
@@ -74,11 +79,11 @@ func TestLocaldirFetchNoMetadata(t *testing.T) {
 	kurl := tempEmptyDir()
 	defer os.RemoveAll(kurl)
 
-	localPath, err := githubFetcher.Fetch(kurl)
-	if err == nil || localPath != "" {
+	kubeware, err := githubFetcher.Fetch(kurl)
+	if err == nil || kubeware != nil {
 		t.Errorf("LocaldirFetcher should not resolve '%s'", kurl)
 	}
-	t.Logf("-> %s", localPath)
+	t.Logf("-> %v", kubeware)
 }
 
 func tempFakeKubeware() string {
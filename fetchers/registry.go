@@ -0,0 +1,68 @@
+package fetchers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrNoFetcher is returned when none of the registered fetchers can handle a kubeware
+type ErrNoFetcher struct {
+	Kubeware string
+	Schemes  []string // Schemes of the fetchers tried, in order
+}
+
+func (e *ErrNoFetcher) Error() string {
+	return fmt.Sprintf("no fetcher can handle kubeware '%s' (tried: %s)", e.Kubeware, strings.Join(e.Schemes, ", "))
+}
+
+// registration holds a fetcher together with its scheme and priority
+type registration struct {
+	priority int
+	scheme   string
+	fetcher  KubewareFetchers
+}
+
+// registry keeps fetchers sorted by priority
+type registry struct {
+	registrations []registration
+}
+
+// defaultRegistry holds the built-in fetchers and any registered by third parties
+var defaultRegistry = &registry{}
+
+// Register makes a fetcher available to Fetch under a scheme name. Fetchers are tried in increasing
+// order of priority, and in registration order when they share it. The first one that can
+// handle a kubeware fetches it
+func Register(priority int, scheme string, fetcher KubewareFetchers) {
+	defaultRegistry.register(priority, scheme, fetcher)
+}
+
+// Resolve returns the first registered fetcher that can handle the kubeware, or an ErrNoFetcher
+// if none can. Fetchers resolving a kubeware to another source use it to delegate the fetch
+func Resolve(kpath string) (KubewareFetchers, error) {
+	return defaultRegistry.resolve(kpath)
+}
+
+func (r *registry) register(priority int, scheme string, fetcher KubewareFetchers) {
+	r.registrations = append(r.registrations, registration{priority, scheme, fetcher})
+	sort.Stable(byPriority(r.registrations))
+}
+
+// resolve returns the first fetcher that can handle the kubeware
+func (r *registry) resolve(kpath string) (KubewareFetchers, error) {
+	schemes := []string{}
+	for _, reg := range r.registrations {
+		if reg.fetcher.CanHandle(kpath) {
+			return reg.fetcher, nil
+		}
+		schemes = append(schemes, reg.scheme)
+	}
+	return nil, &ErrNoFetcher{Kubeware: kpath, Schemes: schemes}
+}
+
+type byPriority []registration
+
+func (r byPriority) Len() int           { return len(r) }
+func (r byPriority) Swap(i, j int)      { r[i], r[j] = r[j], r[i] }
+func (r byPriority) Less(i, j int) bool { return r[i].priority < r[j].priority }
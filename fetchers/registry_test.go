@@ -0,0 +1,55 @@
+package fetchers
+
+import "testing"
+
+// staticFetcher handles kubewares with a fixed name, returning the fetcher name as path
+type staticFetcher struct {
+	name     string
+	kubeware string
+}
+
+func (f *staticFetcher) CanHandle(kpath string) bool {
+	return kpath == f.kubeware
+}
+
+func (f *staticFetcher) Fetch(kpath string) (*Kubeware, error) {
+	return NewKubeware(f.name, ""), nil
+}
+
+func TestRegistryFirstMatchWins(t *testing.T) {
+	r := &registry{}
+	r.register(20, "second", &staticFetcher{"second", "redis"})
+	r.register(10, "first", &staticFetcher{"first", "redis"})
+	r.register(10, "first-too", &staticFetcher{"first-too", "redis"})
+
+	fetcher, err := r.resolve("redis")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	kubeware, _ := fetcher.Fetch("redis")
+	if kubeware.Path != "first" {
+		t.Errorf("expected fetcher 'first' to win, got '%s'", kubeware.Path)
+	}
+}
+
+func TestRegistryNoFetcher(t *testing.T) {
+	r := &registry{}
+	r.register(20, "second", &staticFetcher{"second", "redis"})
+	r.register(10, "first", &staticFetcher{"first", "redis"})
+
+	_, err := r.resolve("guestbook")
+	errNoFetcher, ok := err.(*ErrNoFetcher)
+	if !ok {
+		t.Fatalf("expected ErrNoFetcher, got %v", err)
+	}
+	if len(errNoFetcher.Schemes) != 2 || errNoFetcher.Schemes[0] != "first" || errNoFetcher.Schemes[1] != "second" {
+		t.Errorf("unexpected tried schemes: %v", errNoFetcher.Schemes)
+	}
+}
+
+func TestFetchUnresolvable(t *testing.T) {
+	_, err := Fetch("not a kubeware anywhere")
+	if _, ok := err.(*ErrNoFetcher); !ok {
+		t.Errorf("expected ErrNoFetcher, got %v", err)
+	}
+}
@@ -0,0 +1,56 @@
+package fetchers
+
+import (
+	"fmt"
+	"os"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/flexiant/kdeploy/template"
+)
+
+// verify checks the integrity of a fetched kubeware: its files must match the digests in its
+// metadata, and its signature must match a trusted key. Unsigned kubewares, or signed with an
+// untrusted key, are only refused when KDEPLOY_REQUIRE_SIGNED is set
+func verify(localKubePath string) error {
+	md, err := template.ReadMetadata(localKubePath)
+	if err != nil {
+		return err
+	}
+	err = md.VerifyDigests()
+	if err != nil {
+		return fmt.Errorf("kubeware integrity check failed: %v", err)
+	}
+
+	requireSigned := os.Getenv("KDEPLOY_REQUIRE_SIGNED") == "1"
+	keys, err := template.TrustedKeys()
+	if err != nil {
+		return err
+	}
+	err = md.VerifySignature(keys)
+	if err == template.ErrUnsigned {
+		if requireSigned {
+			return fmt.Errorf("refusing to use unsigned kubeware '%s'", md.Name)
+		}
+		return nil
+	}
+	if err != nil {
+		if requireSigned {
+			return fmt.Errorf("kubeware signature check failed: %v", err)
+		}
+		log.Warnf("Kubeware signature check failed: %v", err)
+		return nil
+	}
+	digests, err := md.FileDigests()
+	if err != nil {
+		return err
+	}
+	if digests == nil {
+		if requireSigned {
+			return fmt.Errorf("refusing to use kubeware '%s' since its signature does not cover its templates", md.Name)
+		}
+		log.Warnf("Signature of kubeware '%s' does not cover its templates", md.Name)
+		return nil
+	}
+	log.Debugf("Verified signature of kubeware '%s'", md.Name)
+	return nil
+}
@@ -0,0 +1,44 @@
+package fetchers
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFetchRefusesTamperedKubeware(t *testing.T) {
+	dir := tempFakeKubeware()
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "redis-service.yaml"), []byte("kind: Service\n"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "metadata.yaml"), []byte(`
+name: "redis"
+svc:
+  redis: "redis-service.yaml"
+digests:
+  redis-service.yaml: "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+`), 0644)
+
+	_, err := Fetch(dir)
+	if err == nil {
+		t.Fatalf("Fetch should refuse a kubeware not matching its digests")
+	}
+}
+
+func TestFetchRequireSigned(t *testing.T) {
+	dir := tempFakeKubeware()
+	defer os.RemoveAll(dir)
+
+	os.Setenv("KDEPLOY_REQUIRE_SIGNED", "1")
+	defer os.Unsetenv("KDEPLOY_REQUIRE_SIGNED")
+	_, err := Fetch(dir)
+	if err == nil {
+		t.Fatalf("Fetch should refuse an unsigned kubeware")
+	}
+
+	os.Unsetenv("KDEPLOY_REQUIRE_SIGNED")
+	_, err = Fetch(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
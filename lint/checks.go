@@ -0,0 +1,295 @@
+package lint
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/flexiant/digger"
+	"github.com/flexiant/kdeploy/template"
+	"github.com/flexiant/kdeploy/utils"
+	"github.com/hashicorp/go-version"
+)
+
+// Severities of the problems found
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+const metadataFile = "metadata.yaml"
+
+// Problem is something wrong found in a kubeware, in a file given by its path within the
+// kubeware and, when known, at a line of it
+type Problem struct {
+	Severity string `json:"severity"`
+	File     string `json:"file"`
+	Line     int    `json:"line,omitempty"`
+	Message  string `json:"message"`
+}
+
+func (p Problem) String() string {
+	if p.Line > 0 {
+		return fmt.Sprintf("%s:%d: %s: %s", p.File, p.Line, p.Severity, p.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", p.File, p.Severity, p.Message)
+}
+
+// Problems holds every problem found in a kubeware
+type Problems []Problem
+
+// Errors returns the number of problems that are errors rather than warnings
+func (p Problems) Errors() int {
+	n := 0
+	for _, problem := range p {
+		if problem.Severity == SeverityError {
+			n++
+		}
+	}
+	return n
+}
+
+func (p *Problems) add(severity, file string, line int, format string, args ...interface{}) {
+	*p = append(*p, Problem{severity, file, line, fmt.Sprintf(format, args...)})
+}
+
+// section is a group of templates of the metadata, by resource name
+type section struct {
+	key       string
+	templates map[string]string
+}
+
+func sections(md template.Metadata) []section {
+	return []section{
+		{"rc", md.ReplicationControllers},
+		{"svc", md.Services},
+		{"deploy", md.Deployments},
+		{"cm", md.ConfigMaps},
+		{"secret", md.Secrets},
+		{"resources", md.Resources},
+	}
+}
+
+// Lint checks the kubeware in dir without deploying it: the metadata fields, the files it
+// references, the attribute defaults, and the templates as rendered with those defaults
+func Lint(dir string) Problems {
+	problems := Problems{}
+	md, err := template.ReadMetadata(dir)
+	if err != nil {
+		problems.add(SeverityError, metadataFile, 0, "%v", err)
+		return problems
+	}
+	checkMetadata(md, &problems)
+	missing := checkFiles(dir, md, &problems)
+	checkDefaults(md, &problems)
+	if !missing {
+		checkTemplates(md, &problems)
+	}
+	return problems
+}
+
+func checkMetadata(md template.Metadata, problems *Problems) {
+	if md.Name == "" {
+		problems.add(SeverityError, metadataFile, 0, "name is missing")
+	} else if _, err := utils.NormalizeName(md.Name); err != nil {
+		problems.add(SeverityError, metadataFile, 0, "name must be at most 63 characters once normalized, got %d", len(md.Name))
+	}
+	if md.Version == "" {
+		problems.add(SeverityError, metadataFile, 0, "version is missing")
+	} else if _, err := version.NewVersion(md.Version); err != nil {
+		problems.add(SeverityError, metadataFile, 0, "version '%s' is not a semantic version", md.Version)
+	}
+	if len(md.ResourceTemplateFiles()) == 0 {
+		problems.add(SeverityError, metadataFile, 0, "no template is referenced")
+	}
+	for i, dep := range md.Dependencies {
+		if dep.Name == "" || dep.Source == "" {
+			problems.add(SeverityError, metadataFile, 0, "dependency %d must have a name and a source", i+1)
+		}
+		if dep.Version == "" {
+			continue
+		}
+		if _, err := version.NewConstraint(dep.Version); err != nil {
+			problems.add(SeverityError, metadataFile, 0, "version '%s' of dependency %s is not a version constraint", dep.Version, dep.Name)
+		}
+	}
+}
+
+// checkFiles checks that every file referenced by the metadata exists within the kubeware,
+// returning whether some is missing
+func checkFiles(dir string, md template.Metadata, problems *Problems) bool {
+	missing := false
+	check := func(reference, file string) {
+		clean := path.Clean(filepath.ToSlash(file))
+		if path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") {
+			problems.add(SeverityError, metadataFile, 0, "%s references %s, which is outside the kubeware", reference, file)
+			missing = true
+		} else if !utils.FileExists(filepath.Join(dir, filepath.FromSlash(clean))) {
+			problems.add(SeverityError, metadataFile, 0, "%s references missing file %s", reference, file)
+			missing = true
+		}
+	}
+	for _, s := range append(sections(md), section{"partials", md.PartialFiles}) {
+		for _, name := range sortedKeys(s.templates) {
+			check(fmt.Sprintf("%s/%s", s.key, name), s.templates[name])
+		}
+	}
+	if md.Manifest != "" {
+		check("manifest", md.Manifest)
+	}
+	for _, file := range sortedKeys(md.Digests) {
+		check("digests", file)
+	}
+	return missing
+}
+
+func checkDefaults(md template.Metadata, problems *Problems) {
+	for _, attr := range declaredPaths(md) {
+		am := attributeMetadata(md, attr)
+		if am.Default == nil {
+			continue
+		}
+		if am.Required {
+			problems.add(SeverityWarning, metadataFile, 0, "attribute %s is required but has a default", attr)
+		}
+		for _, msg := range am.Validate(am.Default) {
+			problems.add(SeverityError, metadataFile, 0, "default of attribute %s %s", attr, msg)
+		}
+	}
+}
+
+// checkTemplates renders every template with the attribute defaults, and placeholders for
+// the required attributes without one
+func checkTemplates(md template.Metadata, problems *Problems) {
+	attributes, err := md.AttributeDefaults()
+	if err != nil {
+		problems.add(SeverityError, metadataFile, 0, "%v", err)
+		return
+	}
+	for _, attr := range declaredPaths(md) {
+		am := attributeMetadata(md, attr)
+		if am.Default == nil && am.Required {
+			parts := strings.Split(attr, "/")
+			attributes[parts[0]].(map[string]interface{})[parts[1]].(map[string]interface{})[parts[2]] = placeholder(am.Type)
+		}
+	}
+
+	undefined, unused, err := md.AnalyzeTemplates(attributes)
+	if err != nil {
+		problems.add(SeverityError, metadataFile, 0, "%v", err)
+		return
+	}
+	// templates with undefined variables would only fail to parse because of them
+	broken := map[string]bool{}
+	for _, u := range undefined {
+		broken[u.File] = true
+		problems.add(SeverityError, u.File, u.Line, "undefined variable '%s'", u.Name)
+	}
+	for _, attr := range unused {
+		problems.add(SeverityWarning, metadataFile, 0, "attribute %s is declared but no template references it", attr)
+	}
+
+	for _, s := range sections(md) {
+		for _, name := range sortedKeys(s.templates) {
+			file := s.templates[name]
+			if broken[path.Clean(filepath.ToSlash(file))] {
+				continue
+			}
+			spec, err := md.ParseTemplate(file, attributes)
+			if err != nil {
+				problems.add(SeverityError, file, 0, "%v", err)
+				continue
+			}
+			d, err := digger.NewMapDigger(spec)
+			if err != nil {
+				problems.add(SeverityError, file, 0, "%v", err)
+				continue
+			}
+			specName, err := d.GetString("metadata/name")
+			if err != nil {
+				problems.add(SeverityError, file, 0, "metadata.name is missing")
+			} else if specName != name {
+				problems.add(SeverityError, file, 0, "metadata.name '%s' does not match its key '%s' in %s", specName, name, metadataFile)
+			}
+			switch s.key {
+			case "rc":
+				checkSelector(file, spec, []string{"spec", "selector"}, problems)
+			case "deploy":
+				checkSelector(file, spec, []string{"spec", "selector", "matchLabels"}, problems)
+			}
+		}
+	}
+}
+
+// checkSelector checks that the pods of a controller are matched by its selector, since
+// otherwise it would keep creating them
+func checkSelector(file string, spec map[string]interface{}, selectorPath []string, problems *Problems) {
+	selector, ok := lookup(spec, selectorPath).(map[string]interface{})
+	if !ok || len(selector) == 0 {
+		// the selector defaults to the pod template labels
+		return
+	}
+	labels, _ := lookup(spec, []string{"spec", "template", "metadata", "labels"}).(map[string]interface{})
+	for _, key := range sortedKeys(selector) {
+		if label, ok := labels[key]; !ok || !reflect.DeepEqual(label, selector[key]) {
+			problems.add(SeverityError, file, 0, "%s %s=%v does not match the pod template labels", strings.Join(selectorPath, "."), key, selector[key])
+		}
+	}
+}
+
+func lookup(m map[string]interface{}, path []string) interface{} {
+	var value interface{} = m
+	for _, key := range path {
+		node, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = node[key]
+	}
+	return value
+}
+
+// placeholder returns a value of the given attribute type
+func placeholder(typ string) interface{} {
+	switch typ {
+	case template.TypeInt:
+		return 1
+	case template.TypeBool:
+		return true
+	case template.TypeList:
+		return []interface{}{}
+	case template.TypeMap:
+		return map[string]interface{}{}
+	}
+	return "placeholder"
+}
+
+func declaredPaths(md template.Metadata) []string {
+	paths := []string{}
+	for resourceType, resources := range md.Attributes {
+		for resourceName, attrs := range resources {
+			for attributeName := range attrs {
+				paths = append(paths, fmt.Sprintf("%s/%s/%s", resourceType, resourceName, attributeName))
+			}
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func attributeMetadata(md template.Metadata, attr string) template.SingleAttributeMetadata {
+	parts := strings.Split(attr, "/")
+	return md.Attributes[parts[0]][parts[1]][parts[2]]
+}
+
+func sortedKeys(m interface{}) []string {
+	keys := []string{}
+	for _, k := range reflect.ValueOf(m).MapKeys() {
+		keys = append(keys, k.String())
+	}
+	sort.Strings(keys)
+	return keys
+}
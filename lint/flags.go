@@ -0,0 +1,28 @@
+package lint
+
+import (
+	"fmt"
+
+	"github.com/codegangsta/cli"
+)
+
+// Flags builds a spec of the flags available for the lint command
+func Flags() []cli.Flag {
+	return []cli.Flag{
+		cli.StringFlag{
+			Name:  "output, o",
+			Usage: "Output format: text or json",
+			Value: "text",
+		},
+	}
+}
+
+// PrepareFlags processes the flags
+func PrepareFlags(c *cli.Context) error {
+	switch c.String("output") {
+	case "text", "json":
+	default:
+		return fmt.Errorf("unknown output format '%s'", c.String("output"))
+	}
+	return nil
+}
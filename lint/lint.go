@@ -0,0 +1,40 @@
+package lint
+
+import (
+	"encoding/json"
+	"fmt"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/codegangsta/cli"
+	"github.com/flexiant/kdeploy/utils"
+)
+
+// CmdLint implements 'lint' command
+func CmdLint(c *cli.Context) {
+	dir := c.Args().First()
+	if dir == "" {
+		dir = "."
+	}
+	problems := Lint(dir)
+
+	switch c.String("output") {
+	case "json":
+		out, err := json.MarshalIndent(problems, "", "  ")
+		utils.CheckError(err)
+		fmt.Println(string(out))
+	default:
+		for _, p := range problems {
+			fmt.Println(p)
+		}
+	}
+
+	errors := problems.Errors()
+	if errors > 0 {
+		log.Fatalf("%d error(s) and %d warning(s) found in %s", errors, len(problems)-errors, dir)
+	}
+	if len(problems) > 0 {
+		log.Warnf("%d warning(s) found in %s", len(problems), dir)
+		return
+	}
+	log.Infof("No problems found in %s", dir)
+}
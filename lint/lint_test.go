@@ -0,0 +1,204 @@
+package lint
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// tempKubeware creates a kubeware with the given files, by path within it
+func tempKubeware(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "kdeploy-lint")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for file, content := range files {
+		os.MkdirAll(filepath.Dir(filepath.Join(dir, file)), 0755)
+		ioutil.WriteFile(filepath.Join(dir, file), []byte(content), 0644)
+	}
+	return dir
+}
+
+const redisController = `apiVersion: v1
+kind: ReplicationController
+metadata:
+  name: redis
+spec:
+  replicas: {{rc.redis.number}}
+  selector:
+    name: redis
+  template:
+    metadata:
+      labels:
+        name: redis
+    spec:
+      containers:
+      - name: redis
+        image: {{rc.redis.image}}
+`
+
+const redisService = `apiVersion: v1
+kind: Service
+metadata:
+  name: redis
+spec:
+  ports:
+  - port: {{svc.redis.port}}
+`
+
+func TestLint(t *testing.T) {
+	dir := tempKubeware(t, map[string]string{
+		"metadata.yaml": `name: "Redis"
+version: "1.0.0"
+attributes:
+  rc:
+    redis:
+      number:
+        type: int
+        default: 1
+      image:
+        format: image
+        required: true
+  svc:
+    redis:
+      port:
+        format: port
+        default: 6379
+rc:
+  redis: "redis-controller.yaml"
+svc:
+  redis: "redis-service.yaml"`,
+		"redis-controller.yaml": redisController,
+		"redis-service.yaml":    redisService,
+	})
+	defer os.RemoveAll(dir)
+
+	problems := Lint(dir)
+	if len(problems) > 0 {
+		t.Errorf("unexpected problems: %v", problems)
+	}
+}
+
+func TestLintProblems(t *testing.T) {
+	dir := tempKubeware(t, map[string]string{
+		"metadata.yaml": `name: "` + strings.Repeat("redis", 13) + `"
+version: "one"
+attributes:
+  rc:
+    redis:
+      number:
+        type: int
+        default: "one"
+      image:
+        default: "redis"
+  svc:
+    redis:
+      port:
+        default: 6379
+      balancer:
+        default: LoadBalancer
+rc:
+  redis: "redis-controller.yaml"
+  master: "redis-controller.yaml"
+svc:
+  redis: "redis-service.yaml"`,
+		"redis-controller.yaml": strings.Replace(redisController, "        name: redis\n", "        name: redis-pod\n", 1),
+		"redis-service.yaml":    strings.Replace(redisService, "svc.redis.port", "svc.redis.ports", 1),
+	})
+	defer os.RemoveAll(dir)
+
+	problems := Lint(dir)
+	expected := []string{
+		"metadata.yaml: error: name must be at most 63 characters once normalized, got 65",
+		"metadata.yaml: error: version 'one' is not a semantic version",
+		`metadata.yaml: error: default of attribute rc/redis/number must be of type int, got "one"`,
+		"redis-service.yaml:7: error: undefined variable 'svc.redis.ports'",
+		"metadata.yaml: warning: attribute svc/redis/balancer is declared but no template references it",
+		"metadata.yaml: warning: attribute svc/redis/port is declared but no template references it",
+		"redis-controller.yaml: error: metadata.name 'redis' does not match its key 'master' in metadata.yaml",
+		"redis-controller.yaml: error: spec.selector name=redis does not match the pod template labels",
+		"redis-controller.yaml: error: spec.selector name=redis does not match the pod template labels",
+	}
+	if len(problems) != len(expected) {
+		t.Fatalf("expected %d problems, got %d: %v", len(expected), len(problems), problems)
+	}
+	for i, p := range problems {
+		if p.String() != expected[i] {
+			t.Errorf("expected problem '%s', got '%s'", expected[i], p)
+		}
+	}
+	if problems.Errors() != 7 {
+		t.Errorf("expected 7 errors, got %d", problems.Errors())
+	}
+}
+
+func TestLintMissingFiles(t *testing.T) {
+	dir := tempKubeware(t, map[string]string{
+		"metadata.yaml": `name: "Redis"
+version: "1.0.0"
+rc:
+  redis: "redis-controller.yaml"
+svc:
+  redis: "../redis-service.yaml"
+manifest: "MANIFEST"`,
+		"redis-controller.yaml": redisController,
+	})
+	defer os.RemoveAll(dir)
+
+	problems := Lint(dir)
+	expected := []string{
+		"metadata.yaml: error: svc/redis references ../redis-service.yaml, which is outside the kubeware",
+		"metadata.yaml: error: manifest references missing file MANIFEST",
+	}
+	if len(problems) != len(expected) {
+		t.Fatalf("expected %d problems, got %d: %v", len(expected), len(problems), problems)
+	}
+	for i, p := range problems {
+		if p.String() != expected[i] {
+			t.Errorf("expected problem '%s', got '%s'", expected[i], p)
+		}
+	}
+}
+
+func TestLintInvalidMetadata(t *testing.T) {
+	dir := tempKubeware(t, map[string]string{"metadata.yaml": "name: [redis"})
+	defer os.RemoveAll(dir)
+
+	problems := Lint(dir)
+	if len(problems) != 1 || problems.Errors() != 1 || problems[0].File != "metadata.yaml" {
+		t.Errorf("unexpected problems: %v", problems)
+	}
+}
+
+func TestLintDependencies(t *testing.T) {
+	dir := tempKubeware(t, map[string]string{
+		"metadata.yaml": `name: "Guestbook"
+version: "1.0.0"
+svc:
+  redis: "redis-service.yaml"
+dependencies:
+- name: redis
+  source: "stable/redis"
+  version: "~> 1.0"
+- name: volumes
+  version: "latest"`,
+		"redis-service.yaml": strings.Replace(redisService, "{{svc.redis.port}}", "6379", 1),
+	})
+	defer os.RemoveAll(dir)
+
+	problems := Lint(dir)
+	expected := []string{
+		"metadata.yaml: error: dependency 2 must have a name and a source",
+		"metadata.yaml: error: version 'latest' of dependency volumes is not a version constraint",
+	}
+	if len(problems) != len(expected) {
+		t.Fatalf("expected %d problems, got %d: %v", len(expected), len(problems), problems)
+	}
+	for i, p := range problems {
+		if p.String() != expected[i] {
+			t.Errorf("expected problem '%s', got '%s'", expected[i], p)
+		}
+	}
+}
@@ -7,7 +7,7 @@ func Flags() []cli.Flag {
 	return []cli.Flag{
 		cli.BoolFlag{
 			Name:  "all, a",
-			Usage: "List Kubeware, SVC, RC",
+			Usage: "List Kubeware, SVC, RC, Deployments",
 		},
 		cli.BoolFlag{
 			Name:  "services, svc",
@@ -17,6 +17,14 @@ func Flags() []cli.Flag {
 			Name:  "controllers, rc",
 			Usage: "List Replica Controllers",
 		},
+		cli.BoolFlag{
+			Name:  "deployments, deploy",
+			Usage: "List Deployments",
+		},
+		cli.StringFlag{
+			Name:  "instance, i",
+			Usage: "List only the Kubewares deployed as this instance",
+		},
 	}
 }
 
@@ -16,44 +16,46 @@ import (
 // CmdList implements 'list' command
 func CmdList(c *cli.Context) {
 	var fqdns []string
-	up := 0
 	kubernetes, err := webservice.NewKubeClient()
 	utils.CheckError(err)
-	// Get all services to extract their kubeware labels
-	log.Debug("Get all services to extract their kubeware labels ...")
-	serviceList, err := kubernetes.GetServices()
+	// Get the services of kubewares to extract their kubeware labels
+	log.Debug("Get the services of kubewares to extract their kubeware labels ...")
+	serviceList, err := kubernetes.GetServices("kubeware")
 	utils.CheckError(err)
-	// Get all controllers to extract their kubeware labels
-	log.Debug("Get all controllers to extract their kubeware labels ...")
-	controllersList, err := kubernetes.GetControllers()
+	// Get the controllers of kubewares to extract their kubeware labels
+	log.Debug("Get the controllers of kubewares to extract their kubeware labels ...")
+	controllersList, err := kubernetes.GetControllers("kubeware")
+	utils.CheckError(err)
+	// Get the deployments, config maps and secrets of kubewares to extract their kubeware labels
+	log.Debug("Get the deployments, config maps and secrets of kubewares to extract their kubeware labels ...")
+	deploymentsList, err := kubernetes.GetDeployments("kubeware")
+	utils.CheckError(err)
+	configMapsList, err := kubernetes.GetConfigMaps("kubeware")
+	utils.CheckError(err)
+	secretsList, err := kubernetes.GetSecrets("kubeware")
 	utils.CheckError(err)
 	// build the list to be printed
-	kubeList := models.BuildKubeList(serviceList, controllersList)
+	kubeList := models.BuildKubeList(serviceList, controllersList, deploymentsList, configMapsList, secretsList)
+	if c.String("instance") != "" {
+		for id, kubeware := range kubeList {
+			if kubeware.GetInstance() != c.String("instance") {
+				delete(kubeList, id)
+			}
+		}
+	}
 
 	if len(kubeList) > 0 {
 		w := tabwriter.NewWriter(os.Stdout, 10, 1, 5, ' ', 0)
 
-		if c.Bool("all") || (!c.Bool("services") && !c.Bool("controllers")) {
-			fmt.Fprintln(w, "KUBEWARE\tNAMESPACE\tVERSION\tSVC\tRC\tUP\tFQDN\r")
+		if c.Bool("all") || (!c.Bool("services") && !c.Bool("controllers") && !c.Bool("deployments")) {
+			fmt.Fprintln(w, "KUBEWARE\tINSTANCE\tNAMESPACE\tVERSION\tSVC\tRC\tDEPLOY\tCM\tSECRET\tUP\tFQDN\r")
 			for _, kubeware := range kubeList {
 				for _, service := range kubeware.Services {
 					if service.GetFQDN() != "" {
 						fqdns = append(fqdns, service.GetFQDN())
 					}
 				}
-				for _, controller := range kubeware.ReplicaControllers {
-					up = up + controller.GetUpStats()
-				}
-				if len(kubeware.Services) > 0 {
-					up = up / len(kubeware.Services)
-				}
-
-				if len(fqdns) > 0 {
-					fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\t%d%%\t%s\n", kubeware.GetKube(), kubeware.GetNamespace(), kubeware.GetVersion(), len(kubeware.Services), len(kubeware.ReplicaControllers), up, strings.Join(fqdns, ","))
-				} else {
-					fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d%%\t%d\n", kubeware.GetKube(), kubeware.GetNamespace(), kubeware.GetVersion(), len(kubeware.Services), up, len(kubeware.ReplicaControllers))
-				}
-				up = 0
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%d\t%d\t%d\t%d\t%d%%\t%s\n", kubeware.GetKube(), kubeware.GetInstance(), kubeware.GetNamespace(), kubeware.GetVersion(), len(kubeware.Services), len(kubeware.ReplicaControllers), len(kubeware.Deployments), len(kubeware.ConfigMaps), len(kubeware.Secrets), kubeware.GetUpStats(), strings.Join(fqdns, ","))
 				fqdns = []string{}
 			}
 		}
@@ -61,13 +63,13 @@ func CmdList(c *cli.Context) {
 			fmt.Fprintf(w, "\n")
 		}
 		if c.Bool("all") || c.Bool("services") {
-			fmt.Fprintln(w, "KUBEWARE\tNAMESPACE\tSVC\tINTERNAL IP\tFQDN\r")
+			fmt.Fprintln(w, "KUBEWARE\tINSTANCE\tNAMESPACE\tSVC\tINTERNAL IP\tFQDN\r")
 			for _, kubeware := range kubeList {
 				for _, service := range kubeware.Services {
 					if service.GetFQDN() != "" {
-						fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", kubeware.GetKube(), service.GetNamespace(), service.GetName(), service.GetInternalIp(), service.GetFQDN())
+						fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", kubeware.GetKube(), kubeware.GetInstance(), service.GetNamespace(), service.GetName(), service.GetInternalIp(), service.GetFQDN())
 					} else {
-						fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", kubeware.GetKube(), service.GetNamespace(), service.GetName(), service.GetInternalIp())
+						fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", kubeware.GetKube(), kubeware.GetInstance(), service.GetNamespace(), service.GetName(), service.GetInternalIp())
 					}
 				}
 			}
@@ -76,10 +78,21 @@ func CmdList(c *cli.Context) {
 			fmt.Fprintf(w, "\n")
 		}
 		if c.Bool("all") || c.Bool("controllers") {
-			fmt.Fprintln(w, "KUBEWARE\tNAMESPACE\tRC\tREPLICAS\tUP\r")
+			fmt.Fprintln(w, "KUBEWARE\tINSTANCE\tNAMESPACE\tRC\tREPLICAS\tUP\r")
 			for _, kubeware := range kubeList {
 				for _, replicaController := range kubeware.ReplicaControllers {
-					fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d%%\n", kubeware.GetKube(), kubeware.GetNamespace(), replicaController.GetName(), replicaController.GetReplicas(), replicaController.GetUpStats())
+					fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%d%%\n", kubeware.GetKube(), kubeware.GetInstance(), kubeware.GetNamespace(), replicaController.GetName(), replicaController.GetReplicas(), replicaController.GetUpStats())
+				}
+			}
+		}
+		if c.Bool("all") {
+			fmt.Fprintf(w, "\n")
+		}
+		if c.Bool("all") || c.Bool("deployments") {
+			fmt.Fprintln(w, "KUBEWARE\tINSTANCE\tNAMESPACE\tDEPLOY\tREPLICAS\tUP\r")
+			for _, kubeware := range kubeList {
+				for _, deployment := range kubeware.Deployments {
+					fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%d%%\n", kubeware.GetKube(), kubeware.GetInstance(), kubeware.GetNamespace(), deployment.GetName(), deployment.GetReplicas(), deployment.GetUpStats())
 				}
 			}
 		}
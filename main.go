@@ -5,9 +5,19 @@ import (
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/codegangsta/cli"
+	"github.com/flexiant/kdeploy/attributes"
+	"github.com/flexiant/kdeploy/cache"
 	"github.com/flexiant/kdeploy/delete"
 	"github.com/flexiant/kdeploy/deploy"
+	"github.com/flexiant/kdeploy/fetchers"
+	"github.com/flexiant/kdeploy/lint"
 	"github.com/flexiant/kdeploy/list"
+	"github.com/flexiant/kdeploy/outputs"
+	"github.com/flexiant/kdeploy/pack"
+	"github.com/flexiant/kdeploy/repo"
+	"github.com/flexiant/kdeploy/scaffold"
+	"github.com/flexiant/kdeploy/search"
+	"github.com/flexiant/kdeploy/secrets"
 	"github.com/flexiant/kdeploy/show"
 	"github.com/flexiant/kdeploy/upgrade"
 	"github.com/flexiant/kdeploy/utils"
@@ -23,6 +33,18 @@ func cmdNotFound(c *cli.Context, command string) {
 	)
 }
 
+// localCommands don't talk to the kubernetes cluster, so they don't need its configuration
+var localCommands = map[string]bool{
+	"attributes": true,
+	"cache":      true,
+	"init":       true,
+	"lint":       true,
+	"package":    true,
+	"repo":       true,
+	"search":     true,
+	"secrets":    true,
+}
+
 func prepareFlags(c *cli.Context) error {
 
 	if c.Bool("debug") {
@@ -31,6 +53,18 @@ func prepareFlags(c *cli.Context) error {
 		log.SetLevel(log.DebugLevel)
 	}
 
+	if c.Bool("offline") {
+		os.Setenv("KDEPLOY_OFFLINE", "1")
+	}
+
+	if c.String("cache-ttl") != "" {
+		os.Setenv("KDEPLOY_CACHE_TTL", c.String("cache-ttl"))
+	}
+
+	if localCommands[c.Args().First()] {
+		return nil
+	}
+
 	// Initialize cached config
 	utils.InitializeConfig(c)
 
@@ -93,6 +127,17 @@ func main() {
 			Value:  config.Path,
 			Usage:  "Kubeconfig client file",
 		},
+		cli.BoolFlag{
+			EnvVar: "KDEPLOY_OFFLINE",
+			Name:   "offline",
+			Usage:  "Use only Kubewares already in the local cache",
+		},
+		cli.StringFlag{
+			EnvVar: "KDEPLOY_CACHE_TTL",
+			Name:   "cache-ttl",
+			Value:  fetchers.DefaultCacheTTL.String(),
+			Usage:  "How long fetched Kubewares are reused from the local cache",
+		},
 	}
 
 	app.Commands = []cli.Command{
@@ -124,6 +169,20 @@ func main() {
 			Action: show.CmdShow,
 			Flags:  show.Flags(),
 		},
+		{
+			Name:   "attributes",
+			Usage:  "Shows the attributes of a Kubeware resolved as deploy would, and where each value came from",
+			Before: attributes.PrepareFlags,
+			Action: attributes.CmdAttributes,
+			Flags:  attributes.Flags(),
+		},
+		{
+			Name:   "outputs",
+			Usage:  "Prints the outputs of a deployed Kubeware, as JSON or as NAME=value lines",
+			Before: outputs.PrepareFlags,
+			Action: outputs.CmdOutputs,
+			Flags:  outputs.Flags(),
+		},
 		{
 			Name:   "upgrade",
 			Usage:  "Upgrades a Kubeware to a new version",
@@ -131,6 +190,100 @@ func main() {
 			Action: upgrade.CmdUpgrade,
 			Flags:  upgrade.Flags(),
 		},
+		{
+			Name:  "cache",
+			Usage: "Manages the local cache of fetched Kubewares",
+			Subcommands: []cli.Command{
+				{
+					Name:   "list",
+					Usage:  "Lists cached Kubewares",
+					Action: cache.CmdList,
+				},
+				{
+					Name:   "prune",
+					Usage:  "Removes expired Kubewares from the cache",
+					Action: cache.CmdPrune,
+					Flags:  cache.PruneFlags(),
+				},
+			},
+		},
+		{
+			Name:   "init",
+			Usage:  "Creates a starter Kubeware, or converts a directory of Kubernetes manifests into one: init <dir>",
+			Action: scaffold.CmdInit,
+			Flags:  scaffold.Flags(),
+		},
+		{
+			Name:   "lint",
+			Usage:  "Checks a local Kubeware for mistakes before it is deployed: lint <dir>",
+			Action: lint.CmdLint,
+			Before: lint.PrepareFlags,
+			Flags:  lint.Flags(),
+		},
+		{
+			Name:   "package",
+			Usage:  "Packages a local Kubeware into a versioned archive: package <dir>",
+			Action: pack.CmdPackage,
+			Flags:  pack.Flags(),
+		},
+		{
+			Name:  "repo",
+			Usage: "Manages the repositories Kubewares can be searched and deployed from by name",
+			Subcommands: []cli.Command{
+				{
+					Name:   "add",
+					Usage:  "Adds a repository: repo add <name> <url>",
+					Action: repo.CmdAdd,
+				},
+				{
+					Name:   "list",
+					Usage:  "Lists the configured repositories",
+					Action: repo.CmdList,
+				},
+				{
+					Name:   "update",
+					Usage:  "Downloads the latest index of the given repositories, or of all of them",
+					Action: repo.CmdUpdate,
+				},
+				{
+					Name:   "index",
+					Usage:  "Writes the index of the Kubewares in a directory, to serve it as a repository",
+					Action: repo.CmdIndex,
+				},
+			},
+		},
+		{
+			Name:  "secrets",
+			Usage: "Manages attribute files encrypted with the secrets key",
+			Subcommands: []cli.Command{
+				{
+					Name:   "encrypt",
+					Usage:  "Encrypts an attributes file: secrets encrypt <file>",
+					Before: secrets.PrepareFlags,
+					Action: secrets.CmdEncrypt,
+					Flags:  secrets.EncryptFlags(),
+				},
+				{
+					Name:   "decrypt",
+					Usage:  "Decrypts an encrypted attributes file: secrets decrypt <file>",
+					Before: secrets.PrepareFlags,
+					Action: secrets.CmdDecrypt,
+					Flags:  secrets.DecryptFlags(),
+				},
+				{
+					Name:   "edit",
+					Usage:  "Edits an encrypted attributes file in $EDITOR: secrets edit <file>",
+					Before: secrets.PrepareFlags,
+					Action: secrets.CmdEdit,
+					Flags:  secrets.EditFlags(),
+				},
+			},
+		},
+		{
+			Name:   "search",
+			Usage:  "Searches Kubewares by name or description in the configured repositories",
+			Action: search.CmdSearch,
+		},
 	}
 
 	app.Run(os.Args)
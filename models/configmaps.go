@@ -0,0 +1,66 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/flexiant/kdeploy/utils"
+)
+
+type ConfigMap struct {
+	Metadata struct {
+		CreationTimestamp string
+		Name              string
+		Labels            map[string]string
+		Annotations       map[string]string
+		Namespace         string
+		ResourceVersion   string
+	}
+}
+
+func (cm *ConfigMap) IsKubware() bool {
+	if cm.GetKube() != "" && cm.GetVersion() != "" {
+		return true
+	}
+	return false
+}
+
+func (cm *ConfigMap) uuid() string {
+	return utils.GetMD5Hash(fmt.Sprintf("%s%s%s%s", cm.Metadata.Namespace, cm.Metadata.Labels["kubeware"], cm.Metadata.Labels[InstanceLabel], cm.Metadata.Labels["kubeware-version"]))
+}
+
+func (cm *ConfigMap) GetNamespace() string {
+	return cm.Metadata.Namespace
+}
+
+func (cm *ConfigMap) GetVersion() string {
+	return cm.Metadata.Labels["kubeware-version"]
+}
+
+func (cm *ConfigMap) GetKube() string {
+	return cm.Metadata.Labels["kubeware"]
+}
+
+func (cm *ConfigMap) GetInstance() string {
+	return cm.Metadata.Labels[InstanceLabel]
+}
+
+func (cm *ConfigMap) GetDependencies() []string {
+	return dependencies(cm.Metadata.Annotations)
+}
+
+func (cm *ConfigMap) GetName() string {
+	return cm.Metadata.Name
+}
+
+func NewConfigMapsJSON(jsonStr string) (*[]ConfigMap, error) {
+	type ConfigMapList struct {
+		Items []ConfigMap
+	}
+	var l ConfigMapList
+	err := json.Unmarshal([]byte(jsonStr), &l)
+	if err != nil {
+		return nil, err
+	}
+	return &l.Items, nil
+}
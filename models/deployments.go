@@ -0,0 +1,83 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/flexiant/kdeploy/utils"
+)
+
+type Deployment struct {
+	Metadata struct {
+		Name        string
+		Labels      map[string]string
+		Annotations map[string]string
+		Namespace   string
+	}
+	Spec struct {
+		Replicas int
+	}
+	Status struct {
+		Replicas          int
+		AvailableReplicas int
+	}
+}
+
+func (d *Deployment) IsKubware() bool {
+	if d.GetKube() != "" && d.GetVersion() != "" {
+		return true
+	}
+	return false
+}
+
+func (d *Deployment) uuid() string {
+	return utils.GetMD5Hash(fmt.Sprintf("%s%s%s%s", d.Metadata.Namespace, d.Metadata.Labels["kubeware"], d.Metadata.Labels[InstanceLabel], d.Metadata.Labels["kubeware-version"]))
+}
+
+func (d *Deployment) GetNamespace() string {
+	return d.Metadata.Namespace
+}
+
+func (d *Deployment) GetVersion() string {
+	return d.Metadata.Labels["kubeware-version"]
+}
+
+func (d *Deployment) GetKube() string {
+	return d.Metadata.Labels["kubeware"]
+}
+
+func (d *Deployment) GetInstance() string {
+	return d.Metadata.Labels[InstanceLabel]
+}
+
+func (d *Deployment) GetDependencies() []string {
+	return dependencies(d.Metadata.Annotations)
+}
+
+func (d *Deployment) GetName() string {
+	return d.Metadata.Name
+}
+
+func (d *Deployment) GetReplicas() int {
+	return d.Status.Replicas
+}
+
+// GetUpStats returns the percentage of desired replicas that are available
+func (d *Deployment) GetUpStats() int {
+	if d.Spec.Replicas == 0 {
+		return 100
+	}
+	return d.Status.AvailableReplicas * 100 / d.Spec.Replicas
+}
+
+func NewDeploymentsJSON(jsonStr string) (*[]Deployment, error) {
+	type DeploymentList struct {
+		Items []Deployment
+	}
+	var dl DeploymentList
+	err := json.Unmarshal([]byte(jsonStr), &dl)
+	if err != nil {
+		return nil, err
+	}
+	return &dl.Items, nil
+}
@@ -1,68 +1,140 @@
 package models
 
+import (
+	"fmt"
+	"strings"
+)
+
+// DependenciesAnnotation lists, in every resource of a kubeware, the normalized names of the
+// kubewares it depends on separated by commas
+const DependenciesAnnotation = "kubeware-dependencies"
+
+// InstanceLabel tells the instance a resource belongs to, when a kubeware is deployed several
+// times in a namespace. Resources of the default instance don't have it
+const InstanceLabel = "kubeware-instance"
+
+// InstanceSelector selects the resources of an instance, or those of the default one, which
+// have no instance label, if instance is empty
+func InstanceSelector(instance string) string {
+	if instance == "" {
+		return "!" + InstanceLabel
+	}
+	return fmt.Sprintf("%s=%s", InstanceLabel, instance)
+}
+
 // struct representing an item to be listed
 type Kube struct {
 	Name               string
+	Instance           string
+	Namespace          string
 	Version            string
+	Dependencies       []string
 	Services           []Service
 	ReplicaControllers []ReplicaController
+	Deployments        []Deployment
+	ConfigMaps         []ConfigMap
+	Secrets            []Secret
+	Resources          []Resource
+}
+
+// kubewareResource is implemented by every kind of resource a kubeware is made of
+type kubewareResource interface {
+	IsKubware() bool
+	GetNamespace() string
+	GetKube() string
+	GetInstance() string
+	GetVersion() string
+	GetDependencies() []string
+	uuid() string
 }
 
-func BuildKubeList(svcList *[]Service, rcList *[]ReplicaController) map[string]Kube {
+func dependencies(annotations map[string]string) []string {
+	if annotations[DependenciesAnnotation] == "" {
+		return nil
+	}
+	return strings.Split(annotations[DependenciesAnnotation], ",")
+}
+
+func BuildKubeList(svcList *[]Service, rcList *[]ReplicaController, deployList *[]Deployment, cmList *[]ConfigMap, secretList *[]Secret) map[string]Kube {
 	kmap := make(map[string]Kube)
 	for _, service := range *svcList {
-		if service.IsKubware() {
-			index := service.uuid()
-			if _, ok := kmap[index]; !ok {
-				// if not, create it
-				kmap[index] = Kube{}
-			}
-			// add the service to the kube's list of services
-			kube := kmap[index]
-			kube.Services = append(kube.Services, service)
-			kmap[index] = kube
-		}
+		s := service
+		addToKube(kmap, &s, func(kube *Kube) { kube.Services = append(kube.Services, s) })
 	}
 	for _, replicaController := range *rcList {
-		if replicaController.IsKubware() {
-			index := replicaController.uuid()
-			// check if kube already in map
-			if _, ok := kmap[index]; !ok {
-				// if not, create it
-				kmap[index] = Kube{}
-			}
-			// add the replicaController to the kube's list of controllers
-			kube := kmap[index]
-			kube.ReplicaControllers = append(kube.ReplicaControllers, replicaController)
-			kmap[index] = kube
-		}
+		rc := replicaController
+		addToKube(kmap, &rc, func(kube *Kube) { kube.ReplicaControllers = append(kube.ReplicaControllers, rc) })
+	}
+	for _, deployment := range *deployList {
+		d := deployment
+		addToKube(kmap, &d, func(kube *Kube) { kube.Deployments = append(kube.Deployments, d) })
+	}
+	for _, configMap := range *cmList {
+		cm := configMap
+		addToKube(kmap, &cm, func(kube *Kube) { kube.ConfigMaps = append(kube.ConfigMaps, cm) })
+	}
+	for _, secret := range *secretList {
+		s := secret
+		addToKube(kmap, &s, func(kube *Kube) { kube.Secrets = append(kube.Secrets, s) })
 	}
 	return kmap
 }
 
-func (k *Kube) GetNamespace() string {
-	if len(k.Services) > 0 {
-		return k.Services[0].GetNamespace()
-	} else if len(k.ReplicaControllers) > 0 {
-		return k.ReplicaControllers[0].GetNamespace()
+// AddResources adds resources of any other kind to the kubes in the map, adding the kubes made
+// only of them
+func AddResources(kmap map[string]Kube, resources []Resource) {
+	for _, resource := range resources {
+		r := resource
+		addToKube(kmap, &r, func(kube *Kube) { kube.Resources = append(kube.Resources, r) })
 	}
-	return ""
 }
 
-func (k *Kube) GetKube() string {
-	if len(k.Services) > 0 {
-		return k.Services[0].GetKube()
-	} else if len(k.ReplicaControllers) > 0 {
-		return k.ReplicaControllers[0].GetKube()
+// addToKube adds the resource to its kube, creating it if not already in map
+func addToKube(kmap map[string]Kube, r kubewareResource, addTo func(kube *Kube)) {
+	if !r.IsKubware() {
+		return
+	}
+	index := r.uuid()
+	kube, ok := kmap[index]
+	if !ok {
+		kube = Kube{Name: r.GetKube(), Instance: r.GetInstance(), Namespace: r.GetNamespace(), Version: r.GetVersion()}
 	}
-	return ""
+	// resources created from pod templates, such as pods, don't tell the dependencies
+	if len(kube.Dependencies) == 0 {
+		kube.Dependencies = r.GetDependencies()
+	}
+	addTo(&kube)
+	kmap[index] = kube
+}
+
+func (k *Kube) GetNamespace() string {
+	return k.Namespace
+}
+
+func (k *Kube) GetKube() string {
+	return k.Name
+}
+
+func (k *Kube) GetInstance() string {
+	return k.Instance
 }
 
 func (k *Kube) GetVersion() string {
-	if len(k.Services) > 0 {
-		return k.Services[0].GetVersion()
-	} else if len(k.ReplicaControllers) > 0 {
-		return k.ReplicaControllers[0].GetVersion()
+	return k.Version
+}
+
+// GetUpStats returns the average percentage of replicas up across the kube's controllers and deployments
+func (k *Kube) GetUpStats() int {
+	workloads := len(k.ReplicaControllers) + len(k.Deployments)
+	if workloads == 0 {
+		return 100
+	}
+	up := 0
+	for _, rc := range k.ReplicaControllers {
+		up = up + rc.GetUpStats()
+	}
+	for _, d := range k.Deployments {
+		up = up + d.GetUpStats()
 	}
-	return ""
+	return up / workloads
 }
@@ -18,6 +18,16 @@ type Pod struct {
 	}
 }
 
+// IsReady tells if the pod is ready to serve requests
+func (p *Pod) IsReady() bool {
+	for _, c := range p.Status.Conditions {
+		if c.Type == "Ready" && c.Status == "True" {
+			return true
+		}
+	}
+	return false
+}
+
 // TODO: we should probably just return a slice instead of a pointer, since we are already
 // signaling errors with the error object returned, no need to return nil
 func NewPodsJSON(jsonStr string) (*[]Pod, error) {
@@ -10,9 +10,10 @@ import (
 
 type ReplicaController struct {
 	Metadata struct {
-		Name      string
-		Labels    map[string]string
-		Namespace string
+		Name        string
+		Labels      map[string]string
+		Annotations map[string]string
+		Namespace   string
 	}
 	Spec struct {
 		Replicas int
@@ -31,7 +32,7 @@ func (rc *ReplicaController) IsKubware() bool {
 }
 
 func (rc *ReplicaController) uuid() string {
-	return utils.GetMD5Hash(fmt.Sprintf("%s%s%s", rc.Metadata.Namespace, rc.Metadata.Labels["kubeware"], rc.Metadata.Labels["kubeware-version"]))
+	return utils.GetMD5Hash(fmt.Sprintf("%s%s%s%s", rc.Metadata.Namespace, rc.Metadata.Labels["kubeware"], rc.Metadata.Labels[InstanceLabel], rc.Metadata.Labels["kubeware-version"]))
 }
 
 func (rc *ReplicaController) GetNamespace() string {
@@ -46,6 +47,14 @@ func (rc *ReplicaController) GetKube() string {
 	return rc.Metadata.Labels["kubeware"]
 }
 
+func (rc *ReplicaController) GetInstance() string {
+	return rc.Metadata.Labels[InstanceLabel]
+}
+
+func (rc *ReplicaController) GetDependencies() []string {
+	return dependencies(rc.Metadata.Annotations)
+}
+
 func (rc *ReplicaController) GetName() string {
 	return rc.Metadata.Name
 }
@@ -63,7 +72,10 @@ func (rc *ReplicaController) GetSelectorAsString() string {
 }
 
 func (rc *ReplicaController) GetUpStats() int {
-	return (rc.Status.Replicas / rc.Spec.Replicas) * 100
+	if rc.Spec.Replicas == 0 {
+		return 100
+	}
+	return rc.Status.Replicas * 100 / rc.Spec.Replicas
 }
 
 func NewControllersJSON(jsonStr string) (*[]ReplicaController, error) {
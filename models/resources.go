@@ -0,0 +1,83 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/flexiant/kdeploy/utils"
+)
+
+// Kind identifies a kind of resource by the apiVersion and kind of its manifests
+type Kind struct {
+	APIVersion string
+	Kind       string
+}
+
+func (k Kind) String() string {
+	return fmt.Sprintf("%s/%s", k.APIVersion, k.Kind)
+}
+
+// Resource is a resource of any kind, of which only the metadata is known
+type Resource struct {
+	Kind     Kind `json:"-"`
+	Metadata struct {
+		CreationTimestamp string
+		Name              string
+		Labels            map[string]string
+		Annotations       map[string]string
+		Namespace         string
+		ResourceVersion   string
+	}
+}
+
+func (r *Resource) IsKubware() bool {
+	if r.GetKube() != "" && r.GetVersion() != "" {
+		return true
+	}
+	return false
+}
+
+func (r *Resource) uuid() string {
+	return utils.GetMD5Hash(fmt.Sprintf("%s%s%s%s", r.Metadata.Namespace, r.Metadata.Labels["kubeware"], r.Metadata.Labels[InstanceLabel], r.Metadata.Labels["kubeware-version"]))
+}
+
+func (r *Resource) GetNamespace() string {
+	return r.Metadata.Namespace
+}
+
+func (r *Resource) GetVersion() string {
+	return r.Metadata.Labels["kubeware-version"]
+}
+
+func (r *Resource) GetKube() string {
+	return r.Metadata.Labels["kubeware"]
+}
+
+func (r *Resource) GetInstance() string {
+	return r.Metadata.Labels[InstanceLabel]
+}
+
+func (r *Resource) GetDependencies() []string {
+	return dependencies(r.Metadata.Annotations)
+}
+
+func (r *Resource) GetName() string {
+	return r.Metadata.Name
+}
+
+// NewResourcesJSON parses a list of resources of the given kind. Items in lists don't carry
+// their kind, so it is set from the one requested
+func NewResourcesJSON(jsonStr string, kind Kind) (*[]Resource, error) {
+	type ResourceList struct {
+		Items []Resource
+	}
+	var l ResourceList
+	err := json.Unmarshal([]byte(jsonStr), &l)
+	if err != nil {
+		return nil, err
+	}
+	for i := range l.Items {
+		l.Items[i].Kind = kind
+	}
+	return &l.Items, nil
+}
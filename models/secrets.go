@@ -0,0 +1,66 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/flexiant/kdeploy/utils"
+)
+
+type Secret struct {
+	Metadata struct {
+		CreationTimestamp string
+		Name              string
+		Labels            map[string]string
+		Annotations       map[string]string
+		Namespace         string
+		ResourceVersion   string
+	}
+}
+
+func (s *Secret) IsKubware() bool {
+	if s.GetKube() != "" && s.GetVersion() != "" {
+		return true
+	}
+	return false
+}
+
+func (s *Secret) uuid() string {
+	return utils.GetMD5Hash(fmt.Sprintf("%s%s%s%s", s.Metadata.Namespace, s.Metadata.Labels["kubeware"], s.Metadata.Labels[InstanceLabel], s.Metadata.Labels["kubeware-version"]))
+}
+
+func (s *Secret) GetNamespace() string {
+	return s.Metadata.Namespace
+}
+
+func (s *Secret) GetVersion() string {
+	return s.Metadata.Labels["kubeware-version"]
+}
+
+func (s *Secret) GetKube() string {
+	return s.Metadata.Labels["kubeware"]
+}
+
+func (s *Secret) GetInstance() string {
+	return s.Metadata.Labels[InstanceLabel]
+}
+
+func (s *Secret) GetDependencies() []string {
+	return dependencies(s.Metadata.Annotations)
+}
+
+func (s *Secret) GetName() string {
+	return s.Metadata.Name
+}
+
+func NewSecretsJSON(jsonStr string) (*[]Secret, error) {
+	type SecretList struct {
+		Items []Secret
+	}
+	var l SecretList
+	err := json.Unmarshal([]byte(jsonStr), &l)
+	if err != nil {
+		return nil, err
+	}
+	return &l.Items, nil
+}
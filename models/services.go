@@ -12,6 +12,7 @@ type Service struct {
 		CreationTimestamp string
 		Name              string
 		Labels            map[string]string
+		Annotations       map[string]string
 		Namespace         string
 		ResourceVersion   string
 	}
@@ -47,7 +48,7 @@ func (svc *Service) IsKubware() bool {
 }
 
 func (svc *Service) uuid() string {
-	return utils.GetMD5Hash(fmt.Sprintf("%s%s%s", svc.Metadata.Namespace, svc.Metadata.Labels["kubeware"], svc.Metadata.Labels["kubeware-version"]))
+	return utils.GetMD5Hash(fmt.Sprintf("%s%s%s%s", svc.Metadata.Namespace, svc.Metadata.Labels["kubeware"], svc.Metadata.Labels[InstanceLabel], svc.Metadata.Labels["kubeware-version"]))
 }
 
 func (svc *Service) GetNamespace() string {
@@ -62,6 +63,14 @@ func (svc *Service) GetKube() string {
 	return svc.Metadata.Labels["kubeware"]
 }
 
+func (svc *Service) GetInstance() string {
+	return svc.Metadata.Labels[InstanceLabel]
+}
+
+func (svc *Service) GetDependencies() []string {
+	return dependencies(svc.Metadata.Annotations)
+}
+
 func (svc *Service) GetName() string {
 	return svc.Metadata.Name
 }
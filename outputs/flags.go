@@ -0,0 +1,71 @@
+package outputs
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/codegangsta/cli"
+	"github.com/flexiant/kdeploy/fetchers"
+	"github.com/flexiant/kdeploy/utils"
+)
+
+// Flags builds a spec of the flags available for the command
+func Flags() []cli.Flag {
+	return []cli.Flag{
+		cli.StringFlag{
+			Name:   "kubeware, k",
+			Usage:  "Kubeware path",
+			EnvVar: "KDEPLOY_KUBEWARE",
+		},
+		cli.StringFlag{
+			Name:   "sha256",
+			Usage:  "Expected SHA-256 checksum of the kubeware archive",
+			EnvVar: "KDEPLOY_SHA256",
+		},
+		cli.StringFlag{
+			Name:   "namespace, n",
+			Usage:  "Namespace the Kubeware is deployed in",
+			Value:  "default",
+			EnvVar: "KDEPLOY_NAMESPACE",
+		},
+		cli.StringFlag{
+			Name:   "instance, i",
+			Usage:  "Instance of the Kubeware, the default one if not given",
+			EnvVar: "KDEPLOY_INSTANCE",
+		},
+		cli.StringFlag{
+			Name:  "output, o",
+			Usage: "Output format: json, or env for NAME=value lines",
+			Value: "json",
+		},
+	}
+}
+
+// PrepareFlags processes the flags
+func PrepareFlags(c *cli.Context) error {
+	switch c.String("output") {
+	case "json", "env":
+	default:
+		return fmt.Errorf("unknown output format '%s'", c.String("output"))
+	}
+
+	if c.String("kubeware") != "" {
+		kubeware, err := fetchers.WithChecksum(c.String("kubeware"), c.String("sha256"))
+		if err != nil {
+			return err
+		}
+		os.Setenv("KDEPLOY_KUBEWARE", kubeware)
+	}
+
+	if c.String("instance") != "" {
+		err := utils.ValidateInstance(c.String("instance"))
+		if err != nil {
+			return err
+		}
+		os.Setenv("KDEPLOY_INSTANCE", c.String("instance"))
+	}
+
+	os.Setenv("KDEPLOY_NAMESPACE", c.String("namespace"))
+
+	return nil
+}
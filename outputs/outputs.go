@@ -0,0 +1,242 @@
+package outputs
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/codegangsta/cli"
+	"github.com/flexiant/digger"
+	"github.com/flexiant/kdeploy/dependencies"
+	"github.com/flexiant/kdeploy/fetchers"
+	"github.com/flexiant/kdeploy/models"
+	"github.com/flexiant/kdeploy/template"
+	"github.com/flexiant/kdeploy/utils"
+	"github.com/flexiant/kdeploy/webservice"
+)
+
+// Fields of services that outputs can read by name rather than by path
+const (
+	FieldClusterIP = "clusterIP" // IP of the service within the cluster
+	FieldFQDN      = "fqdn"      // Hostname of the load balancer of the service
+	FieldNodePort  = "nodePort"  // Node port of the first port of the service
+	FieldNodePorts = "nodePorts" // Node ports of every port of the service
+)
+
+var (
+	// unsafeEnvName matches the characters that can't be in the name of an environment variable
+	unsafeEnvName = regexp.MustCompile(`[^A-Z0-9_]`)
+	// safeEnvValue matches values that need no quoting in a shell
+	safeEnvValue = regexp.MustCompile(`^[A-Za-z0-9_./:@%+,=-]*$`)
+)
+
+// CmdOutputs implements the 'outputs' command
+func CmdOutputs(c *cli.Context) {
+	utils.CheckRequiredFlags(c, []string{"kubeware"})
+
+	kubeware := os.Getenv("KDEPLOY_KUBEWARE")
+	fetched, err := fetchers.Fetch(kubeware)
+	if err != nil {
+		log.Fatal(fmt.Errorf("Could not fetch kubeware: '%s' (%v)", kubeware, err))
+	}
+	defer fetched.Close()
+
+	metadata := template.ParseMetadata(fetched.Path)
+	metadata.Instance = os.Getenv("KDEPLOY_INSTANCE")
+	kinds, err := metadata.Kinds()
+	utils.CheckError(err)
+
+	kubernetes, err := webservice.NewKubeClient()
+	utils.CheckError(err)
+	namespace := os.Getenv("KDEPLOY_NAMESPACE")
+	deployedVersion, err := kubernetes.FindDeployedKubewareVersion(namespace, metadata.Name, metadata.Instance, kinds...)
+	utils.CheckError(err)
+	if deployedVersion == "" {
+		log.Fatalf("Kubeware '%s' is not deployed in namespace '%s'", metadata.Name, namespace)
+	}
+
+	values, err := Collect(kubernetes, namespace, metadata)
+	utils.CheckError(err)
+
+	switch c.String("output") {
+	case "json":
+		out, err := json.MarshalIndent(values, "", "  ")
+		utils.CheckError(err)
+		fmt.Println(string(out))
+	case "env":
+		for _, name := range metadata.OutputNames() {
+			if value, ok := values[name]; ok {
+				fmt.Println(EnvLine(name, value))
+			}
+		}
+	}
+}
+
+// Collect reads the outputs of a kubeware from its deployed resources. Outputs whose resource
+// doesn't exist or has no value yet, such as a load balancer still being provisioned, are
+// warned about and left out
+func Collect(kubernetes webservice.KubeClient, namespace string, md template.Metadata) (map[string]interface{}, error) {
+	values := map[string]interface{}{}
+	for _, name := range md.OutputNames() {
+		source, err := md.OutputSource(name)
+		if err != nil {
+			return nil, err
+		}
+		object, err := kubernetes.GetObject(namespace, source.Kind, source.Name)
+		if err == webservice.ErrNotFound {
+			log.Warnf("Output %s of %s is missing since %s '%s' is not deployed", name, md.Name, source.Section, source.Name)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		value, err := Extract(source, object)
+		if err != nil {
+			return nil, fmt.Errorf("output %s: %v", name, err)
+		}
+		if value == nil {
+			log.Warnf("Output %s of %s has no value yet in %s '%s'", name, md.Name, source.Section, source.Name)
+			continue
+		}
+		values[name] = value
+	}
+	return values, nil
+}
+
+// DependencyOutputs collects the outputs of the kubewares the kubeware depends on directly, by
+// name, from those deployed as the same instance. Outputs of dependencies not deployed are blank
+func DependencyOutputs(kubernetes webservice.KubeClient, namespace string, md template.Metadata) (map[string]map[string]interface{}, error) {
+	log.Debugf("Resolving dependencies")
+	deps, err := dependencies.Resolve(md, fetchers.Fetch)
+	if err != nil {
+		return nil, err
+	}
+	defer dependencies.Close(deps)
+	direct := map[string]bool{}
+	for _, dep := range md.Dependencies {
+		direct[dep.Name] = true
+	}
+	collected := map[string]map[string]interface{}{}
+	for _, dep := range deps {
+		if !direct[dep.Name] {
+			continue
+		}
+		dep.Metadata.Instance = md.Instance
+		kinds, err := dep.Metadata.Kinds()
+		if err != nil {
+			return nil, err
+		}
+		deployed, err := kubernetes.FindDeployedKubewareVersion(namespace, dep.Metadata.Name, dep.Metadata.Instance, kinds...)
+		if err != nil {
+			return nil, err
+		}
+		if deployed == "" {
+			log.Warnf("Dependency %s is not deployed, its outputs are left blank", dep.Name)
+			collected[dep.Name] = WithBlanks(dep.Metadata, nil)
+			continue
+		}
+		values, err := Collect(kubernetes, namespace, dep.Metadata)
+		if err != nil {
+			return nil, err
+		}
+		collected[dep.Name] = WithBlanks(dep.Metadata, values)
+	}
+	return collected, nil
+}
+
+// WithBlanks fills the outputs missing from values with blanks, so that templates referring
+// to them render as they would with an attribute left empty
+func WithBlanks(md template.Metadata, values map[string]interface{}) map[string]interface{} {
+	filled := map[string]interface{}{}
+	for _, name := range md.OutputNames() {
+		filled[name] = ""
+		if value, ok := values[name]; ok {
+			filled[name] = value
+		}
+	}
+	return filled
+}
+
+// Extract reads the value of an output from the JSON representation of its resource, returning
+// nil when the value is empty or is a status the resource doesn't have yet. Other fields the
+// resource doesn't have are an error
+func Extract(source template.OutputSource, object []byte) (interface{}, error) {
+	if len(object) == 0 {
+		// dry runs get nothing back from the API server
+		return nil, nil
+	}
+	if source.Section == "svc" {
+		var svc models.Service
+		err := json.Unmarshal(object, &svc)
+		if err != nil {
+			return nil, err
+		}
+		switch source.Field {
+		case FieldClusterIP:
+			return nonEmpty(svc.GetInternalIp()), nil
+		case FieldFQDN:
+			return nonEmpty(svc.GetFQDN()), nil
+		case FieldNodePort:
+			if len(svc.Spec.Ports) == 0 || svc.Spec.Ports[0].NodePort == 0 {
+				return nil, nil
+			}
+			return svc.Spec.Ports[0].NodePort, nil
+		case FieldNodePorts:
+			ports := []interface{}{}
+			for _, port := range svc.Spec.Ports {
+				if port.NodePort != 0 {
+					ports = append(ports, port.NodePort)
+				}
+			}
+			if len(ports) == 0 {
+				return nil, nil
+			}
+			return ports, nil
+		}
+	}
+	d, err := digger.NewJSONDigger(object)
+	if err != nil {
+		return nil, err
+	}
+	value, err := d.Get(source.Field)
+	if err != nil {
+		// the status is filled in by the cluster over time, other fields are there from the start
+		if strings.HasPrefix(source.Field, "status/") {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("%s '%s' has no field %s", source.Section, source.Name, source.Field)
+	}
+	if s, ok := value.(string); ok {
+		return nonEmpty(s), nil
+	}
+	return value, nil
+}
+
+func nonEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// EnvLine renders an output as a NAME=value line a shell can source, its name in upper case
+func EnvLine(name string, value interface{}) string {
+	envName := unsafeEnvName.ReplaceAllString(strings.ToUpper(name), "_")
+	var envValue string
+	switch v := value.(type) {
+	case string:
+		envValue = v
+	case []interface{}, map[string]interface{}:
+		out, _ := json.Marshal(v)
+		envValue = string(out)
+	default:
+		envValue = fmt.Sprint(v)
+	}
+	if !safeEnvValue.MatchString(envValue) {
+		envValue = "'" + strings.Replace(envValue, "'", `'\''`, -1) + "'"
+	}
+	return fmt.Sprintf("%s=%s", envName, envValue)
+}
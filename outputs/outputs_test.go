@@ -0,0 +1,70 @@
+package outputs
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/flexiant/kdeploy/template"
+)
+
+const serviceJSON = `{
+  "metadata": {"name": "frontend"},
+  "spec": {
+    "clusterIP": "10.0.0.12",
+    "ports": [{"port": 80, "nodePort": 30080}, {"port": 443, "nodePort": 30443}]
+  },
+  "status": {"loadBalancer": {"ingress": [{"hostname": "frontend.example.com"}]}}
+}`
+
+func TestExtract(t *testing.T) {
+	for field, expected := range map[string]interface{}{
+		FieldClusterIP: "10.0.0.12",
+		FieldFQDN:      "frontend.example.com",
+		FieldNodePort:  30080,
+		FieldNodePorts: []interface{}{30080, 30443},
+		"spec/ports":   []interface{}{map[string]interface{}{"port": 80.0, "nodePort": 30080.0}, map[string]interface{}{"port": 443.0, "nodePort": 30443.0}},
+		"status/none":  nil,
+	} {
+		value, err := Extract(template.OutputSource{Section: "svc", Name: "frontend", Field: field}, []byte(serviceJSON))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(value, expected) {
+			t.Errorf("expected %#v for %s, got %#v", expected, field, value)
+		}
+	}
+
+	// a load balancer still being provisioned has no hostname yet
+	value, err := Extract(template.OutputSource{Section: "svc", Name: "frontend", Field: FieldFQDN}, []byte(`{"spec": {"clusterIP": "10.0.0.12"}}`))
+	if err != nil || value != nil {
+		t.Errorf("expected no value, got %#v (%v)", value, err)
+	}
+
+	value, err = Extract(template.OutputSource{Section: "rc", Name: "frontend", Field: "spec/replicas"}, []byte(`{"spec": {"replicas": 3}}`))
+	if err != nil || value != 3.0 {
+		t.Errorf("expected 3 replicas, got %#v (%v)", value, err)
+	}
+
+	// mistyped fields are not taken for values still missing
+	if _, err = Extract(template.OutputSource{Section: "rc", Name: "frontend", Field: "spec/replica"}, []byte(`{"spec": {"replicas": 3}}`)); err == nil {
+		t.Errorf("expected an error for a field the resource doesn't have")
+	}
+}
+
+func TestEnvLine(t *testing.T) {
+	for _, c := range []struct {
+		name     string
+		value    interface{}
+		expected string
+	}{
+		{"host", "10.0.0.12", "HOST=10.0.0.12"},
+		{"node-port", 30080, "NODE_PORT=30080"},
+		{"url", "http://frontend.example.com:80/", "URL=http://frontend.example.com:80/"},
+		{"motd", "it's up", `MOTD='it'\''s up'`},
+		{"ports", []interface{}{80, 443}, "PORTS='[80,443]'"},
+	} {
+		if line := EnvLine(c.name, c.value); line != c.expected {
+			t.Errorf("expected %s, got %s", c.expected, line)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package pack
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/flexiant/kdeploy/template"
+	"github.com/flexiant/kdeploy/utils"
+	"github.com/hashicorp/go-version"
+)
+
+// ManifestFile is the name of the digest manifest added to packaged kubewares
+const ManifestFile = "MANIFEST"
+
+// Build packages the kubeware in dir into a '<name>-<version>.tgz' archive in the destination
+// directory, and returns its path. The archive holds the kubeware in a '<name>' directory,
+// with just the metadata, the files it references and a digest manifest. Kubewares that
+// already carry their digests are packaged as they are, signature included
+func Build(dir, destination string) (string, error) {
+	md, err := template.ReadMetadata(dir)
+	if err != nil {
+		return "", err
+	}
+	name, err := validate(md)
+	if err != nil {
+		return "", err
+	}
+
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	contents := map[string][]byte{}
+	for _, file := range md.TemplateFiles() {
+		if path.IsAbs(file) || strings.HasPrefix(file, "../") {
+			return "", fmt.Errorf("template %s is outside the kubeware directory", file)
+		}
+		contents[file], err = ioutil.ReadFile(filepath.Join(root, filepath.FromSlash(file)))
+		if err != nil {
+			return "", fmt.Errorf("could not read template: %v", err)
+		}
+	}
+	contents["metadata.yaml"], err = ioutil.ReadFile(filepath.Join(root, "metadata.yaml"))
+	if err != nil {
+		return "", err
+	}
+
+	if md.Manifest == "" && md.Digests == nil {
+		addManifest(contents)
+	} else {
+		err = md.VerifyDigests()
+		if err != nil {
+			return "", err
+		}
+		signed, err := filepath.Rel(root, md.SignedFile())
+		if err != nil {
+			return "", err
+		}
+		for _, file := range []string{md.Manifest, fmt.Sprintf("%s.sig", signed)} {
+			if file == "" || !utils.FileExists(filepath.Join(root, file)) {
+				continue
+			}
+			contents[path.Clean(filepath.ToSlash(file))], err = ioutil.ReadFile(filepath.Join(root, file))
+			if err != nil {
+				return "", err
+			}
+		}
+	}
+
+	err = os.MkdirAll(destination, 0755)
+	if err != nil {
+		return "", err
+	}
+	archive := filepath.Join(destination, fmt.Sprintf("%s-%s.tgz", name, md.Version))
+	err = writeArchive(archive, name, contents)
+	if err != nil {
+		return "", err
+	}
+	return archive, nil
+}
+
+// validate checks the metadata holds what packaging needs, and returns the normalized
+// kubeware name
+func validate(md template.Metadata) (string, error) {
+	if md.Name == "" {
+		return "", fmt.Errorf("metadata must have a name to package the kubeware")
+	}
+	if md.Version == "" {
+		return "", fmt.Errorf("metadata must have a version to package the kubeware")
+	}
+	if _, err := version.NewVersion(md.Version); err != nil {
+		return "", fmt.Errorf("invalid kubeware version '%s': %v", md.Version, err)
+	}
+	if len(md.ResourceTemplateFiles()) == 0 {
+		return "", fmt.Errorf("metadata does not reference any template")
+	}
+	return utils.NormalizeName(md.Name)
+}
+
+// addManifest adds a manifest with the digests of every file, and points the metadata to it
+func addManifest(contents map[string][]byte) {
+	metadata := contents["metadata.yaml"]
+	if len(metadata) > 0 && metadata[len(metadata)-1] != '\n' {
+		metadata = append(metadata, '\n')
+	}
+	contents["metadata.yaml"] = append(metadata, []byte(fmt.Sprintf("manifest: %q\n", ManifestFile))...)
+
+	digests := map[string]string{}
+	for file, data := range contents {
+		sum := sha256.Sum256(data)
+		digests[file] = hex.EncodeToString(sum[:])
+	}
+	contents[ManifestFile] = template.FormatManifest(digests)
+}
+
+// writeArchive writes the contents into a gzipped tarball, under the dir directory. Entries
+// are sorted and carry no timestamps, so that packaging the same kubeware twice yields the
+// same archive
+func writeArchive(archive, dir string, contents map[string][]byte) error {
+	f, err := os.Create(archive)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+
+	files := make([]string, 0, len(contents))
+	for file := range contents {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	dirs := map[string]bool{}
+	for _, file := range files {
+		for d := path.Dir(path.Join(dir, file)); d != "." && !dirs[d]; d = path.Dir(d) {
+			dirs[d] = true
+		}
+	}
+	sortedDirs := make([]string, 0, len(dirs))
+	for d := range dirs {
+		sortedDirs = append(sortedDirs, d)
+	}
+	sort.Strings(sortedDirs)
+	for _, d := range sortedDirs {
+		err = tw.WriteHeader(&tar.Header{
+			Name:     d + "/",
+			Mode:     0755,
+			Typeflag: tar.TypeDir,
+			ModTime:  time.Unix(0, 0),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, file := range files {
+		err = tw.WriteHeader(&tar.Header{
+			Name:     path.Join(dir, file),
+			Mode:     0644,
+			Size:     int64(len(contents[file])),
+			Typeflag: tar.TypeReg,
+			ModTime:  time.Unix(0, 0),
+		})
+		if err != nil {
+			return err
+		}
+		_, err = tw.Write(contents[file])
+		if err != nil {
+			return err
+		}
+	}
+	err = tw.Close()
+	if err != nil {
+		return err
+	}
+	return gz.Close()
+}
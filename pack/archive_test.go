@@ -0,0 +1,142 @@
+package pack
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/flexiant/kdeploy/template"
+	"github.com/flexiant/kdeploy/utils"
+)
+
+// tempKubeware creates a kubeware with a service, a controller and an unrelated file
+func tempKubeware(t *testing.T, metadata string) string {
+	dir, err := ioutil.TempDir("", "kdeploy-kubeware")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	os.Mkdir(filepath.Join(dir, "templates"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "metadata.yaml"), []byte(metadata), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "redis-service.yaml"), []byte("kind: Service\n"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "templates", "redis-controller.yaml"), []byte("kind: ReplicationController\n"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not part of the kubeware\n"), 0644)
+	return dir
+}
+
+const redisMetadata = `name: "Redis Server"
+version: "1.0.0"
+svc:
+  redis: "redis-service.yaml"
+rc:
+  redis: "templates/redis-controller.yaml"`
+
+func extract(t *testing.T, archive string) string {
+	dir, _ := ioutil.TempDir("", "kdeploy-extract")
+	err := utils.Untar(archive, dir)
+	if err != nil {
+		t.Fatalf("could not extract %s: %v", archive, err)
+	}
+	return dir
+}
+
+func TestBuild(t *testing.T) {
+	dir := tempKubeware(t, redisMetadata)
+	defer os.RemoveAll(dir)
+	destination, _ := ioutil.TempDir("", "kdeploy-packages")
+	defer os.RemoveAll(destination)
+
+	archive, err := Build(dir, destination)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if filepath.Base(archive) != "redis-server-1.0.0.tgz" {
+		t.Errorf("unexpected archive name %s", filepath.Base(archive))
+	}
+
+	extracted := extract(t, archive)
+	defer os.RemoveAll(extracted)
+	kubeware := filepath.Join(extracted, "redis-server")
+	files := []string{}
+	filepath.Walk(kubeware, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			rel, _ := filepath.Rel(kubeware, path)
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	sort.Strings(files)
+	expected := []string{"MANIFEST", "metadata.yaml", "redis-service.yaml", "templates/redis-controller.yaml"}
+	if len(files) != len(expected) {
+		t.Fatalf("expected files %v, got %v", expected, files)
+	}
+	for i := range files {
+		if files[i] != expected[i] {
+			t.Fatalf("expected files %v, got %v", expected, files)
+		}
+	}
+
+	md, err := template.ReadMetadata(kubeware)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if md.Manifest != ManifestFile {
+		t.Errorf("packaged metadata should point to the manifest, got '%s'", md.Manifest)
+	}
+	digests, _ := md.FileDigests()
+	if _, ok := digests["metadata.yaml"]; !ok {
+		t.Errorf("manifest should cover the metadata")
+	}
+	if err := md.VerifyDigests(); err != nil {
+		t.Errorf("packaged kubeware should verify: %v", err)
+	}
+}
+
+func TestBuildIsReproducible(t *testing.T) {
+	dir := tempKubeware(t, redisMetadata)
+	defer os.RemoveAll(dir)
+	destination, _ := ioutil.TempDir("", "kdeploy-packages")
+	defer os.RemoveAll(destination)
+
+	archive, _ := Build(dir, destination)
+	first, _ := template.FileDigest(archive)
+	archive, _ = Build(dir, destination)
+	second, _ := template.FileDigest(archive)
+	if first != second {
+		t.Errorf("packaging twice should yield the same archive")
+	}
+}
+
+func TestBuildKeepsDigests(t *testing.T) {
+	dir := tempKubeware(t, redisMetadata+`
+digests:
+  redis-service.yaml: "8cb2d3a9ed6b6cd6e8c5bbd5b2ed5b2f9dd16a4cd7c4b7d1e54b0e47ff87a37b"`)
+	defer os.RemoveAll(dir)
+	destination, _ := ioutil.TempDir("", "kdeploy-packages")
+	defer os.RemoveAll(destination)
+
+	_, err := Build(dir, destination)
+	if err == nil {
+		t.Errorf("kubewares not matching their digests should not be packaged")
+	}
+}
+
+func TestBuildValidatesMetadata(t *testing.T) {
+	for _, metadata := range []string{
+		"name: \"redis\"\nsvc:\n  redis: \"redis-service.yaml\"\n",
+		"name: \"redis\"\nversion: \"latest\"\nsvc:\n  redis: \"redis-service.yaml\"\n",
+		"name: \"redis\"\nversion: \"1.0.0\"\n",
+		"name: \"redis\"\nversion: \"1.0.0\"\nsvc:\n  redis: \"missing.yaml\"\n",
+		"name: \"redis\"\nversion: \"1.0.0\"\nsvc:\n  redis: \"../redis-service.yaml\"\n",
+	} {
+		dir := tempKubeware(t, metadata)
+		destination, _ := ioutil.TempDir("", "kdeploy-packages")
+		_, err := Build(dir, destination)
+		if err == nil {
+			t.Errorf("metadata should not be packaged:\n%s", metadata)
+		}
+		os.RemoveAll(dir)
+		os.RemoveAll(destination)
+	}
+}
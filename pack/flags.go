@@ -0,0 +1,14 @@
+package pack
+
+import "github.com/codegangsta/cli"
+
+// Flags builds a spec of the flags available for the package command
+func Flags() []cli.Flag {
+	return []cli.Flag{
+		cli.StringFlag{
+			Name:  "destination, d",
+			Usage: "Directory the archive is written to",
+			Value: ".",
+		},
+	}
+}
@@ -0,0 +1,18 @@
+package pack
+
+import (
+	log "github.com/Sirupsen/logrus"
+	"github.com/codegangsta/cli"
+	"github.com/flexiant/kdeploy/utils"
+)
+
+// CmdPackage implements 'package' command
+func CmdPackage(c *cli.Context) {
+	dir := c.Args().First()
+	if dir == "" {
+		dir = "."
+	}
+	archive, err := Build(dir, c.String("destination"))
+	utils.CheckError(err)
+	log.Infof("Kubeware packaged at %s", archive)
+}
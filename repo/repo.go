@@ -0,0 +1,88 @@
+package repo
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/codegangsta/cli"
+	"github.com/flexiant/kdeploy/repository"
+	"github.com/flexiant/kdeploy/utils"
+)
+
+// CmdAdd implements 'repo add' command
+func CmdAdd(c *cli.Context) {
+	if len(c.Args()) != 2 {
+		log.Fatal("Usage: kdeploy repo add <name> <url>")
+	}
+	repo, err := repository.Add(c.Args()[0], c.Args()[1])
+	utils.CheckError(err)
+	log.Infof("Repository %s added", repo.Name)
+}
+
+// CmdList implements 'repo list' command
+func CmdList(c *cli.Context) {
+	repos, err := repository.Repositories()
+	utils.CheckError(err)
+
+	if len(repos) == 0 {
+		log.Infof("No repositories configured, add one with 'kdeploy repo add <name> <url>'")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 10, 1, 5, ' ', 0)
+	fmt.Fprintln(w, "NAME\tURL\tKUBEWARES\r")
+	for _, r := range repos {
+		kubewares := "-"
+		if index, err := r.Index(); err == nil {
+			kubewares = fmt.Sprintf("%d", len(index.Kubewares))
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", r.Name, utils.RedactURL(r.URL), kubewares)
+	}
+	w.Flush()
+}
+
+// CmdUpdate implements 'repo update' command. It updates the given repositories, or all of them
+func CmdUpdate(c *cli.Context) {
+	var repos []repository.Repository
+	if len(c.Args()) > 0 {
+		for _, name := range c.Args() {
+			repo, err := repository.Get(name)
+			utils.CheckError(err)
+			repos = append(repos, *repo)
+		}
+	} else {
+		var err error
+		repos, err = repository.Repositories()
+		utils.CheckError(err)
+	}
+
+	failed := 0
+	for _, r := range repos {
+		err := r.Update()
+		if err != nil {
+			log.Error(err)
+			failed++
+			continue
+		}
+		log.Infof("Repository %s updated", r.Name)
+	}
+	if failed > 0 {
+		log.Fatalf("Could not update %d repositories", failed)
+	}
+}
+
+// CmdIndex implements 'repo index' command, writing the index of the kubewares in a directory
+func CmdIndex(c *cli.Context) {
+	dir := c.Args().First()
+	if dir == "" {
+		dir = "."
+	}
+	index, err := repository.BuildIndex(dir)
+	utils.CheckError(err)
+	err = index.Write(filepath.Join(dir, repository.IndexFile))
+	utils.CheckError(err)
+	log.Infof("Indexed %d Kubewares in %s", len(index.Kubewares), filepath.Join(dir, repository.IndexFile))
+}
@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"fmt"
+	"regexp"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/flexiant/kdeploy/fetchers"
+)
+
+// Priority of the repository fetcher, tried after every built-in fetcher so that
+// kubeware names never shadow local directories or URLs
+const Priority = 50
+
+// reference matches kubewares given as '[repository/]name[@version]', where version can be
+// any version constraint (e.g. 'redis@1.2.0', 'stable/redis@~> 1.2')
+var reference = regexp.MustCompile(`^(?:([a-zA-Z0-9][a-zA-Z0-9._-]*)/)?([a-zA-Z0-9][a-zA-Z0-9._-]*)(?:@(.+))?$`)
+
+func init() {
+	fetchers.Register(Priority, "repository", &Fetcher{})
+}
+
+// Fetcher resolves kubewares by name and version through the configured repositories, and
+// fetches them from the source listed in the repository index
+type Fetcher struct{}
+
+// CanHandle tells if the kubeware is listed in a configured repository
+func (f *Fetcher) CanHandle(kpath string) bool {
+	source, err := Lookup(kpath)
+	return err == nil && source != ""
+}
+
+// Fetch fetches the kubeware from its source using the fetcher that can handle it
+func (f *Fetcher) Fetch(kpath string) (*fetchers.Kubeware, error) {
+	source, err := Lookup(kpath)
+	if err != nil {
+		return nil, err
+	}
+	if source == "" {
+		return nil, fmt.Errorf("kubeware '%s' not found in any repository", kpath)
+	}
+	fetcher, err := fetchers.Resolve(source)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := fetcher.(*Fetcher); ok {
+		return nil, fmt.Errorf("repository source '%s' for kubeware '%s' is not fetchable", source, kpath)
+	}
+	return fetcher.Fetch(source)
+}
+
+// Constrained tells if a kubeware given by reference constrains its version, rather than
+// being the newest one listed
+func Constrained(kpath string) bool {
+	m := reference.FindStringSubmatch(kpath)
+	return m != nil && m[3] != ""
+}
+
+// Lookup returns the source of the newest kubeware matching the reference in the configured
+// repositories, tried in the order they were added. It returns an empty source if none matches
+func Lookup(kpath string) (string, error) {
+	m := reference.FindStringSubmatch(kpath)
+	if m == nil {
+		return "", nil
+	}
+	repoName, name, constraint := m[1], m[2], m[3]
+
+	var repos []Repository
+	if repoName != "" {
+		repo, err := Get(repoName)
+		if err != nil {
+			return "", err
+		}
+		repos = []Repository{*repo}
+	} else {
+		var err error
+		repos, err = Repositories()
+		if err != nil {
+			return "", err
+		}
+	}
+
+	for _, r := range repos {
+		index, err := r.Index()
+		if err != nil {
+			log.Warnf("Skipping repository %s: %v", r.Name, err)
+			continue
+		}
+		entry, err := index.Lookup(name, constraint)
+		if err != nil {
+			return "", err
+		}
+		if entry != nil {
+			source := r.ResolveSource(entry.Source)
+			log.Debugf("Resolved %s to %s %s from repository %s", kpath, entry.Name, entry.Version, r.Name)
+			return source, nil
+		}
+	}
+	return "", nil
+}
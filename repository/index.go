@@ -0,0 +1,245 @@
+package repository
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/flexiant/kdeploy/template"
+	"github.com/flexiant/kdeploy/utils"
+	"github.com/hashicorp/go-version"
+	"gopkg.in/yaml.v2"
+)
+
+// IndexFile is the name of the index file of a repository
+const IndexFile = "index.yaml"
+
+// Index lists the kubewares available in a repository, keyed by name
+type Index struct {
+	APIVersion string                  `yaml:"apiVersion"`
+	Generated  string                  `yaml:"generated,omitempty"`
+	Kubewares  map[string][]IndexEntry `yaml:"kubewares"`
+}
+
+// IndexEntry describes a version of a kubeware in an index. Relative sources are resolved
+// against the location of the index
+type IndexEntry struct {
+	Name        string `yaml:"name"`
+	Version     string `yaml:"version"`
+	Description string `yaml:"description,omitempty"`
+	Maintainer  string `yaml:"maintainer,omitempty"`
+	Source      string `yaml:"source"`
+}
+
+// NewIndex returns an empty index
+func NewIndex() *Index {
+	return &Index{
+		APIVersion: "v1",
+		Generated:  time.Now().UTC().Format(time.RFC3339),
+		Kubewares:  map[string][]IndexEntry{},
+	}
+}
+
+// ParseIndex parses the contents of an index file
+func ParseIndex(data []byte) (*Index, error) {
+	var index Index
+	err := yaml.Unmarshal(data, &index)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse repository index: %v", err)
+	}
+	if index.APIVersion != "v1" {
+		return nil, fmt.Errorf("unsupported repository index version '%s'", index.APIVersion)
+	}
+	if index.Kubewares == nil {
+		index.Kubewares = map[string][]IndexEntry{}
+	}
+	return &index, nil
+}
+
+// ReadIndex reads an index file from disk
+func ReadIndex(path string) (*Index, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseIndex(data)
+}
+
+// BuildIndex indexes every kubeware found in dir, either in an immediate subdirectory or
+// packaged as a '.tgz' archive, using their paths relative to dir as sources. Archive sources
+// carry their SHA-256 checksum
+func BuildIndex(dir string) (*Index, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	index := NewIndex()
+	for _, f := range files {
+		var md template.Metadata
+		var source string
+		switch {
+		case f.IsDir() && utils.FileExists(filepath.Join(dir, f.Name(), "metadata.yaml")):
+			md, err = template.ReadMetadata(filepath.Join(dir, f.Name()))
+			source = f.Name()
+		case !f.IsDir() && strings.HasSuffix(f.Name(), ".tgz"):
+			md, source, err = archiveMetadata(dir, f.Name())
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not index %s: %v", f.Name(), err)
+		}
+		err = index.Add(md, source)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return index, nil
+}
+
+// archiveMetadata reads the metadata of a packaged kubeware, and returns it along with the
+// source of the archive, pinned to its checksum
+func archiveMetadata(dir, name string) (template.Metadata, string, error) {
+	var md template.Metadata
+	archive := filepath.Join(dir, name)
+	checksum, err := template.FileDigest(archive)
+	if err != nil {
+		return md, "", err
+	}
+	tmpDir, err := ioutil.TempDir("", "kdeploy")
+	if err != nil {
+		return md, "", err
+	}
+	defer os.RemoveAll(tmpDir)
+	err = utils.Untar(archive, tmpDir)
+	if err != nil {
+		return md, "", err
+	}
+	found, err := filepath.Glob(filepath.Join(tmpDir, "*", "metadata.yaml"))
+	if err != nil {
+		return md, "", err
+	}
+	if utils.FileExists(filepath.Join(tmpDir, "metadata.yaml")) {
+		found = append(found, filepath.Join(tmpDir, "metadata.yaml"))
+	}
+	if len(found) != 1 {
+		return md, "", fmt.Errorf("expected a single kubeware in the archive, found %d", len(found))
+	}
+	md, err = template.ReadMetadata(filepath.Dir(found[0]))
+	return md, fmt.Sprintf("%s#sha256=%s", name, checksum), err
+}
+
+// Add indexes a version of a kubeware from its metadata, replacing any entry with the same version
+func (i *Index) Add(md template.Metadata, source string) error {
+	if md.Name == "" || md.Version == "" {
+		return fmt.Errorf("kubeware at '%s' must have a name and a version to be indexed", source)
+	}
+	if _, err := version.NewVersion(md.Version); err != nil {
+		return fmt.Errorf("kubeware '%s' has an invalid version '%s': %v", md.Name, md.Version, err)
+	}
+	entry := IndexEntry{
+		Name:        md.Name,
+		Version:     md.Version,
+		Description: md.Description,
+		Maintainer:  md.Maintainer,
+		Source:      source,
+	}
+	entries := []IndexEntry{}
+	for _, e := range i.Kubewares[md.Name] {
+		if e.Version != md.Version {
+			entries = append(entries, e)
+		}
+	}
+	i.Kubewares[md.Name] = sortedByVersion(append(entries, entry))
+	return nil
+}
+
+// Write saves the index to a file
+func (i *Index) Write(path string) error {
+	data, err := yaml.Marshal(i)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+// Lookup returns the newest version of the kubeware matching the version constraint, which
+// can be empty to get the newest one. Names are matched regardless of case
+func (i *Index) Lookup(name, constraint string) (*IndexEntry, error) {
+	var constraints version.Constraints
+	if constraint != "" {
+		var err error
+		constraints, err = version.NewConstraint(constraint)
+		if err != nil {
+			return nil, fmt.Errorf("invalid version constraint '%s': %v", constraint, err)
+		}
+	}
+	for n, entries := range i.Kubewares {
+		if !strings.EqualFold(n, name) {
+			continue
+		}
+		for _, e := range sortedByVersion(entries) {
+			v, err := version.NewVersion(e.Version)
+			if err != nil {
+				log.Debugf("Skipping %s with invalid version '%s'", e.Name, e.Version)
+				continue
+			}
+			if constraints == nil || constraints.Check(v) {
+				entry := e
+				return &entry, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// Search returns the newest version of every kubeware whose name or description contains
+// the term, regardless of case. An empty term matches every kubeware
+func (i *Index) Search(term string) []IndexEntry {
+	term = strings.ToLower(term)
+	found := []IndexEntry{}
+	for name, entries := range i.Kubewares {
+		if len(entries) == 0 {
+			continue
+		}
+		newest := sortedByVersion(entries)[0]
+		if strings.Contains(strings.ToLower(name), term) || strings.Contains(strings.ToLower(newest.Description), term) {
+			found = append(found, newest)
+		}
+	}
+	sort.Sort(entriesByName(found))
+	return found
+}
+
+// sortedByVersion returns a copy of the entries sorted from newest to oldest version. Entries
+// with invalid versions go last
+func sortedByVersion(entries []IndexEntry) []IndexEntry {
+	sorted := make([]IndexEntry, len(entries))
+	copy(sorted, entries)
+	sort.Stable(byVersion(sorted))
+	return sorted
+}
+
+type byVersion []IndexEntry
+
+func (e byVersion) Len() int      { return len(e) }
+func (e byVersion) Swap(i, j int) { e[i], e[j] = e[j], e[i] }
+func (e byVersion) Less(i, j int) bool {
+	vi, erri := version.NewVersion(e[i].Version)
+	vj, errj := version.NewVersion(e[j].Version)
+	if erri != nil || errj != nil {
+		return erri == nil
+	}
+	return vi.GreaterThan(vj)
+}
+
+type entriesByName []IndexEntry
+
+func (e entriesByName) Len() int           { return len(e) }
+func (e entriesByName) Swap(i, j int)      { e[i], e[j] = e[j], e[i] }
+func (e entriesByName) Less(i, j int) bool { return e[i].Name < e[j].Name }
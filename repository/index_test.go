@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/flexiant/kdeploy/template"
+)
+
+func testIndex(t *testing.T) *Index {
+	index := NewIndex()
+	for _, md := range []template.Metadata{
+		{Name: "redis", Version: "1.0.0", Description: "Redis key-value store"},
+		{Name: "redis", Version: "1.10.0", Description: "Redis key-value store"},
+		{Name: "redis", Version: "1.2.0", Description: "Redis key-value store"},
+		{Name: "guestbook", Version: "0.1.0", Description: "Guestbook backed by redis"},
+		{Name: "nginx", Version: "2.0.0", Description: "Web server"},
+	} {
+		err := index.Add(md, md.Name)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	return index
+}
+
+func TestIndexLookup(t *testing.T) {
+	index := testIndex(t)
+	tests := []struct {
+		name, constraint, expected string
+	}{
+		{"redis", "", "1.10.0"},
+		{"Redis", "", "1.10.0"},
+		{"redis", "1.2.0", "1.2.0"},
+		{"redis", "~> 1.0.0", "1.0.0"},
+		{"redis", "< 1.10", "1.2.0"},
+		{"redis", "3.0.0", ""},
+		{"mysql", "", ""},
+	}
+	for _, test := range tests {
+		entry, err := index.Lookup(test.name, test.constraint)
+		if err != nil {
+			t.Fatalf("unexpected error looking up %s@%s: %v", test.name, test.constraint, err)
+		}
+		found := ""
+		if entry != nil {
+			found = entry.Version
+		}
+		if found != test.expected {
+			t.Errorf("expected %s@%s to resolve to '%s', got '%s'", test.name, test.constraint, test.expected, found)
+		}
+	}
+
+	_, err := index.Lookup("redis", "not a version")
+	if err == nil {
+		t.Errorf("invalid constraints should fail")
+	}
+}
+
+func TestIndexSearch(t *testing.T) {
+	index := testIndex(t)
+
+	found := index.Search("REDIS")
+	if len(found) != 2 || found[0].Name != "guestbook" || found[1].Name != "redis" {
+		t.Fatalf("expected guestbook and redis, got %v", found)
+	}
+	if found[1].Version != "1.10.0" {
+		t.Errorf("search should return the newest version, got %s", found[1].Version)
+	}
+	if len(index.Search("")) != 3 {
+		t.Errorf("an empty term should match every kubeware")
+	}
+}
+
+func TestIndexAddRequiresVersion(t *testing.T) {
+	index := NewIndex()
+	if err := index.Add(template.Metadata{Name: "redis"}, "redis"); err == nil {
+		t.Errorf("kubewares without version should not be indexed")
+	}
+	if err := index.Add(template.Metadata{Name: "redis", Version: "latest"}, "redis"); err == nil {
+		t.Errorf("kubewares with invalid versions should not be indexed")
+	}
+}
+
+func TestParseIndex(t *testing.T) {
+	index, err := ParseIndex([]byte(`
+apiVersion: v1
+kubewares:
+  redis:
+  - name: redis
+    version: 1.0.0
+    source: redis-1.0.0.tgz
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	entry, _ := index.Lookup("redis", "")
+	if entry == nil || entry.Source != "redis-1.0.0.tgz" {
+		t.Errorf("unexpected entry %v", entry)
+	}
+
+	_, err = ParseIndex([]byte("apiVersion: v2\n"))
+	if err == nil {
+		t.Errorf("unsupported index versions should fail")
+	}
+}
@@ -0,0 +1,260 @@
+package repository
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/flexiant/kdeploy/utils"
+	"github.com/flexiant/kdeploy/webservice"
+	"gopkg.in/yaml.v2"
+)
+
+// validName matches the names repositories can be added with
+var validName = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]*$`)
+
+// Repository is a kubeware repository, serving an index over http(s) or from local disk
+type Repository struct {
+	Name string `yaml:"name"`
+	URL  string `yaml:"url"`
+}
+
+// repositoriesConfig is the list of configured repositories kept in the kdeploy directory
+type repositoriesConfig struct {
+	Repositories []Repository `yaml:"repositories"`
+}
+
+// Result is a kubeware found in a configured repository
+type Result struct {
+	Repository string
+	IndexEntry
+}
+
+// Repositories returns the configured repositories, in the order they were added
+func Repositories() ([]Repository, error) {
+	file, err := repositoriesFile()
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var cfg repositoriesConfig
+	err = yaml.Unmarshal(data, &cfg)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse %s: %v", file, err)
+	}
+	return cfg.Repositories, nil
+}
+
+// Add configures a new repository and downloads its index
+func Add(name, location string) (*Repository, error) {
+	if !validName.MatchString(name) {
+		return nil, fmt.Errorf("invalid repository name '%s'", name)
+	}
+	repos, err := Repositories()
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range repos {
+		if r.Name == name {
+			return nil, fmt.Errorf("repository '%s' already exists with url %s", name, utils.RedactURL(r.URL))
+		}
+	}
+	if !isRemote(location) && !strings.HasPrefix(location, "file://") {
+		location, err = filepath.Abs(location)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	repo := Repository{Name: name, URL: location}
+	err = repo.Update()
+	if err != nil {
+		return nil, err
+	}
+	err = saveRepositories(append(repos, repo))
+	if err != nil {
+		return nil, err
+	}
+	return &repo, nil
+}
+
+// Get returns the configured repository with the given name
+func Get(name string) (*Repository, error) {
+	repos, err := Repositories()
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range repos {
+		if r.Name == name {
+			repo := r
+			return &repo, nil
+		}
+	}
+	return nil, fmt.Errorf("repository '%s' is not configured", name)
+}
+
+// Search looks for the term in the cached indexes of every configured repository
+func Search(term string) ([]Result, error) {
+	repos, err := Repositories()
+	if err != nil {
+		return nil, err
+	}
+	results := []Result{}
+	for _, r := range repos {
+		index, err := r.Index()
+		if err != nil {
+			log.Warnf("Skipping repository %s: %v", r.Name, err)
+			continue
+		}
+		for _, entry := range index.Search(term) {
+			results = append(results, Result{Repository: r.Name, IndexEntry: entry})
+		}
+	}
+	return results, nil
+}
+
+// Update downloads the repository index and keeps it in the kdeploy directory
+func (r Repository) Update() error {
+	data, err := r.download()
+	if err != nil {
+		return fmt.Errorf("could not get index of repository '%s': %v", r.Name, err)
+	}
+	_, err = ParseIndex(data)
+	if err != nil {
+		return fmt.Errorf("repository '%s': %v", r.Name, err)
+	}
+	file, err := r.indexFile()
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(file), 0755)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, data, 0644)
+}
+
+// Index returns the index of the repository downloaded by the last update
+func (r Repository) Index() (*Index, error) {
+	file, err := r.indexFile()
+	if err != nil {
+		return nil, err
+	}
+	index, err := ReadIndex(file)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("index of repository '%s' not found, run 'kdeploy repo update'", r.Name)
+	}
+	return index, err
+}
+
+// IndexURL returns the location of the repository index. Repositories can be given either
+// as the index file itself or as the directory holding it
+func (r Repository) IndexURL() string {
+	if strings.HasSuffix(r.URL, ".yaml") || strings.HasSuffix(r.URL, ".yml") {
+		return r.URL
+	}
+	if isRemote(r.URL) {
+		return strings.TrimSuffix(r.URL, "/") + "/" + IndexFile
+	}
+	return filepath.Join(strings.TrimPrefix(r.URL, "file://"), IndexFile)
+}
+
+// ResolveSource turns the source of an index entry into one kdeploy can fetch. Relative sources
+// are resolved against the location of the index
+func (r Repository) ResolveSource(source string) string {
+	if u, err := url.Parse(source); err == nil && u.Scheme != "" {
+		return source
+	}
+	if strings.Contains(source, "@") && strings.Contains(source, ":") {
+		// scp-like git remotes
+		return source
+	}
+	index := r.IndexURL()
+	if isRemote(index) {
+		base, err := url.Parse(index)
+		if err != nil {
+			return source
+		}
+		u, err := base.Parse(source)
+		if err != nil {
+			return source
+		}
+		return u.String()
+	}
+	if filepath.IsAbs(source) {
+		return source
+	}
+	return filepath.Join(filepath.Dir(index), filepath.FromSlash(source))
+}
+
+func (r Repository) download() ([]byte, error) {
+	index := r.IndexURL()
+	if !isRemote(index) {
+		return ioutil.ReadFile(index)
+	}
+	client, err := webservice.NewSimpleWebClient(index)
+	if err != nil {
+		return nil, err
+	}
+	tmpDir, err := ioutil.TempDir("", "kdeploy")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+	u, err := url.Parse(index)
+	if err != nil {
+		return nil, err
+	}
+	file, err := client.GetFile(u.RequestURI(), tmpDir)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadFile(file)
+}
+
+func (r Repository) indexFile() (string, error) {
+	dir, err := utils.KdeployDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "repos", fmt.Sprintf("%s-%s", r.Name, IndexFile)), nil
+}
+
+func saveRepositories(repos []Repository) error {
+	file, err := repositoriesFile()
+	if err != nil {
+		return err
+	}
+	data, err := yaml.Marshal(repositoriesConfig{Repositories: repos})
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(file), 0755)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, data, 0600)
+}
+
+func repositoriesFile() (string, error) {
+	dir, err := utils.KdeployDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "repositories.yaml"), nil
+}
+
+func isRemote(location string) bool {
+	return strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://")
+}
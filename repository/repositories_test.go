@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/flexiant/kdeploy/fetchers"
+	"github.com/flexiant/kdeploy/pack"
+)
+
+// tempRepository creates a local repository holding a redis kubeware, and points the
+// kdeploy directory to a temporal one
+func tempRepository(t *testing.T) (string, func()) {
+	home, _ := ioutil.TempDir("", "kdeploy-home")
+	os.Setenv("KDEPLOY_HOME", home)
+	dir, _ := ioutil.TempDir("", "kdeploy-repo")
+	os.Mkdir(filepath.Join(dir, "redis"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "redis", "metadata.yaml"), []byte(`
+name: "redis"
+version: "1.0.0"
+description: "Redis key-value store"
+svc:
+  redis: "redis-service.yaml"
+`), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "redis", "redis-service.yaml"), []byte("kind: Service\n"), 0644)
+
+	index, err := BuildIndex(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err = index.Write(filepath.Join(dir, IndexFile))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return dir, func() {
+		os.Unsetenv("KDEPLOY_HOME")
+		os.RemoveAll(home)
+		os.RemoveAll(dir)
+	}
+}
+
+func TestAddRepository(t *testing.T) {
+	dir, cleanup := tempRepository(t)
+	defer cleanup()
+
+	_, err := Add("local", dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = Add("local", dir)
+	if err == nil {
+		t.Errorf("repositories should not be added twice")
+	}
+	_, err = Add("bad/name", dir)
+	if err == nil {
+		t.Errorf("invalid repository names should fail")
+	}
+
+	repos, _ := Repositories()
+	if len(repos) != 1 || repos[0].Name != "local" {
+		t.Fatalf("unexpected repositories %v", repos)
+	}
+	results, _ := Search("key-value")
+	if len(results) != 1 || results[0].Repository != "local" || results[0].Name != "redis" {
+		t.Errorf("unexpected search results %v", results)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	dir, cleanup := tempRepository(t)
+	defer cleanup()
+	Add("local", dir)
+
+	for _, kpath := range []string{"redis", "redis@1.0.0", "local/redis", "local/redis@>= 1.0"} {
+		source, err := Lookup(kpath)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if source != filepath.Join(dir, "redis") {
+			t.Errorf("expected %s to resolve to %s, got '%s'", kpath, filepath.Join(dir, "redis"), source)
+		}
+	}
+	for _, kpath := range []string{"redis@2.0.0", "mysql", "./redis", "https://example.com/redis.tgz"} {
+		source, _ := Lookup(kpath)
+		if source != "" {
+			t.Errorf("%s should not resolve, got '%s'", kpath, source)
+		}
+	}
+	for kpath, constrained := range map[string]bool{"redis": false, "local/redis": false, "redis@1.0.0": true, "local/redis@>= 1.0": true, "./redis": false} {
+		if Constrained(kpath) != constrained {
+			t.Errorf("expected %s to be constrained: %v", kpath, constrained)
+		}
+	}
+}
+
+func TestFetchByName(t *testing.T) {
+	dir, cleanup := tempRepository(t)
+	defer cleanup()
+	Add("local", dir)
+
+	kubeware, err := fetchers.Fetch("redis@1.0.0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer kubeware.Close()
+	if _, err := os.Stat(filepath.Join(kubeware.Path, "metadata.yaml")); err != nil {
+		t.Errorf("kubeware not fetched: %v", err)
+	}
+}
+
+func TestIndexPackagedKubewares(t *testing.T) {
+	dir, cleanup := tempRepository(t)
+	defer cleanup()
+	_, err := pack.Build(filepath.Join(dir, "redis"), dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	os.RemoveAll(filepath.Join(dir, "redis"))
+
+	index, err := BuildIndex(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	index.Write(filepath.Join(dir, IndexFile))
+	entry, _ := index.Lookup("redis", "1.0.0")
+	if entry == nil || !strings.HasPrefix(entry.Source, "redis-1.0.0.tgz#sha256=") {
+		t.Fatalf("expected the archive to be indexed with its checksum, got %v", entry)
+	}
+
+	Add("local", dir)
+	kubeware, err := fetchers.Fetch("redis@1.0.0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer kubeware.Close()
+	if _, err := os.Stat(filepath.Join(kubeware.Path, "MANIFEST")); err != nil {
+		t.Errorf("packaged kubeware not fetched: %v", err)
+	}
+}
+
+func TestResolveSource(t *testing.T) {
+	remote := Repository{Name: "remote", URL: "https://example.com/kubewares/"}
+	tests := map[string]string{
+		"redis-1.0.0.tgz":                    "https://example.com/kubewares/redis-1.0.0.tgz",
+		"redis-1.0.0.tgz#sha256=abc":         "https://example.com/kubewares/redis-1.0.0.tgz#sha256=abc",
+		"https://github.com/org/redis@1.0.0": "https://github.com/org/redis@1.0.0",
+		"git@github.com:org/redis.git":       "git@github.com:org/redis.git",
+	}
+	for source, expected := range tests {
+		if resolved := remote.ResolveSource(source); resolved != expected {
+			t.Errorf("expected %s to resolve to %s, got %s", source, expected, resolved)
+		}
+	}
+
+	local := Repository{Name: "local", URL: "/srv/kubewares/index.yaml"}
+	if resolved := local.ResolveSource("redis"); resolved != "/srv/kubewares/redis" {
+		t.Errorf("expected local source to resolve to /srv/kubewares/redis, got %s", resolved)
+	}
+}
@@ -0,0 +1,136 @@
+package scaffold
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+)
+
+// documentSeparator splits the documents of a YAML stream
+var documentSeparator = regexp.MustCompile(`(?m)^---[ \t]*$`)
+
+// sections of the metadata the kinds of manifests go to, along with the suffix of their files.
+// Manifests of any other kind go to the resources section
+var sections = map[string]struct{ key, suffix string }{
+	"ReplicationController": {"rc", "controller"},
+	"Service":               {"svc", "service"},
+	"Deployment":            {"deploy", "deployment"},
+	"ConfigMap":             {"cm", "configmap"},
+	"Secret":                {"secret", "secret"},
+}
+
+var sectionOrder = []string{"rc", "svc", "deploy", "cm", "secret", "resources"}
+
+// manifest is a document of a manifests file
+type manifest struct {
+	Kind     string
+	Metadata struct {
+		Name string
+	}
+	source  string
+	content string
+}
+
+// Convert writes to dir a kubeware made of the manifests in the YAML and JSON files of the source
+// directory. Each manifest is copied to its own template, which goes to the section of the
+// metadata matching its kind, under its name
+func Convert(dir, source string, options Options) ([]string, error) {
+	options, err := withDefaults(dir, options)
+	if err != nil {
+		return nil, err
+	}
+	manifests, err := readManifests(source)
+	if err != nil {
+		return nil, err
+	}
+	if len(manifests) == 0 {
+		return nil, fmt.Errorf("no manifests found in %s", source)
+	}
+
+	files := map[string][]byte{}
+	templates := map[string]map[string]string{}
+	for _, m := range manifests {
+		key, suffix := "resources", strings.ToLower(m.Kind)
+		if s, ok := sections[m.Kind]; ok {
+			key, suffix = s.key, s.suffix
+		}
+		if templates[key] == nil {
+			templates[key] = map[string]string{}
+		}
+		if file, ok := templates[key][m.Metadata.Name]; ok {
+			return nil, fmt.Errorf("%s and %s both define %s '%s'", file, m.source, key, m.Metadata.Name)
+		}
+		file := fmt.Sprintf("%s-%s%s", m.Metadata.Name, suffix, filepath.Ext(m.source))
+		templates[key][m.Metadata.Name] = file
+		files[file] = []byte(m.content)
+	}
+
+	metadata := fmt.Sprintf("name: %q\nmaintainer: %q\nemail: %q\ndescription: \"\"\nversion: %q\n", options.Name, options.Maintainer, options.Email, InitialVersion)
+	for _, key := range sectionOrder {
+		if len(templates[key]) == 0 {
+			continue
+		}
+		metadata += fmt.Sprintf("%s:\n", key)
+		names := []string{}
+		for name := range templates[key] {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			metadata += fmt.Sprintf("  %s: %q\n", name, templates[key][name])
+		}
+	}
+	files["metadata.yaml"] = []byte(metadata)
+	return write(dir, files)
+}
+
+// readManifests reads the manifests in the YAML and JSON files of a directory, sorted by file.
+// Documents without a kind or a name are skipped
+func readManifests(source string) ([]manifest, error) {
+	manifests := []manifest{}
+	err := filepath.Walk(source, func(file string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		switch strings.ToLower(filepath.Ext(file)) {
+		case ".yaml", ".yml", ".json":
+		default:
+			return nil
+		}
+		if info.Name() == "metadata.yaml" {
+			log.Warnf("Skipping %s, which looks like a kubeware already", file)
+			return nil
+		}
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		for i, doc := range documentSeparator.Split(string(data), -1) {
+			if strings.TrimSpace(doc) == "" {
+				continue
+			}
+			m := manifest{source: file, content: strings.TrimLeft(doc, "\n")}
+			err := yaml.Unmarshal([]byte(doc), &m)
+			if err != nil {
+				return fmt.Errorf("error parsing %s: %v", file, err)
+			}
+			if m.Kind == "" || m.Metadata.Name == "" {
+				log.Warnf("Skipping document %d of %s, which has no kind or name", i+1, file)
+				continue
+			}
+			if !strings.HasSuffix(m.content, "\n") {
+				m.content += "\n"
+			}
+			manifests = append(manifests, m)
+		}
+		return nil
+	})
+	return manifests, err
+}
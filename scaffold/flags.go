@@ -0,0 +1,25 @@
+package scaffold
+
+import "github.com/codegangsta/cli"
+
+// Flags builds a spec of the flags available for the init command
+func Flags() []cli.Flag {
+	return []cli.Flag{
+		cli.StringFlag{
+			Name:  "name, n",
+			Usage: "Kubeware name, the directory name by default",
+		},
+		cli.StringFlag{
+			Name:  "maintainer",
+			Usage: "Kubeware maintainer",
+		},
+		cli.StringFlag{
+			Name:  "email",
+			Usage: "Kubeware maintainer email",
+		},
+		cli.StringFlag{
+			Name:  "from",
+			Usage: "Directory of plain Kubernetes manifests to convert into the kubeware",
+		},
+	}
+}
@@ -0,0 +1,220 @@
+package scaffold
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/codegangsta/cli"
+	"github.com/flexiant/kdeploy/utils"
+)
+
+// InitialVersion is the version new kubewares start at
+const InitialVersion = "0.1.0"
+
+var notInDNSLabel = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// Options describe the kubeware being created
+type Options struct {
+	Name       string
+	Maintainer string
+	Email      string
+}
+
+// CmdInit implements 'init' command
+func CmdInit(c *cli.Context) {
+	dir := c.Args().First()
+	if dir == "" {
+		dir = "."
+	}
+	options := Options{
+		Name:       c.String("name"),
+		Maintainer: c.String("maintainer"),
+		Email:      c.String("email"),
+	}
+
+	var files []string
+	var err error
+	if c.String("from") != "" {
+		files, err = Convert(dir, c.String("from"), options)
+	} else {
+		files, err = Create(dir, options)
+	}
+	utils.CheckError(err)
+	for _, file := range files {
+		log.Infof("Created %s", filepath.Join(dir, file))
+	}
+	log.Infof("Kubeware initialized at %s. Run 'kdeploy lint %s' after editing it", dir, dir)
+}
+
+// Create writes a starter kubeware to dir: its metadata, a replication controller and a service
+// templated with a few attributes, and example attribute files
+func Create(dir string, options Options) ([]string, error) {
+	options, err := withDefaults(dir, options)
+	if err != nil {
+		return nil, err
+	}
+	resource, err := resourceName(options.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := fmt.Sprintf(starterMetadata, options.Name, options.Maintainer, options.Email, InitialVersion, resource)
+	development, err := attributesFile(map[string]interface{}{
+		"rc": map[string]interface{}{resource: map[string]interface{}{"number": 1}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	production, err := attributesFile(map[string]interface{}{
+		"rc":  map[string]interface{}{resource: map[string]interface{}{"number": 3}},
+		"svc": map[string]interface{}{resource: map[string]interface{}{"type": "LoadBalancer"}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return write(dir, map[string][]byte{
+		"metadata.yaml": []byte(metadata),
+		fmt.Sprintf("%s-controller.yaml", resource): []byte(fmt.Sprintf(starterController, resource)),
+		fmt.Sprintf("%s-service.yaml", resource):    []byte(fmt.Sprintf(starterService, resource)),
+		"attributes/development.json":               development,
+		"attributes/production.json":                production,
+	})
+}
+
+// withDefaults names the kubeware after its directory unless told otherwise
+func withDefaults(dir string, options Options) (Options, error) {
+	if options.Name == "" {
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return options, err
+		}
+		options.Name = filepath.Base(abs)
+	}
+	if _, err := utils.NormalizeName(options.Name); err != nil {
+		return options, err
+	}
+	return options, nil
+}
+
+// resourceName derives from the kubeware name a name its resources can have
+func resourceName(name string) (string, error) {
+	normalized, err := utils.NormalizeName(name)
+	if err != nil {
+		return "", err
+	}
+	resource := strings.Trim(notInDNSLabel.ReplaceAllString(normalized, "-"), "-")
+	if resource == "" {
+		return "", fmt.Errorf("could not derive a resource name from '%s', use --name", name)
+	}
+	return resource, nil
+}
+
+func attributesFile(attributes map[string]interface{}) ([]byte, error) {
+	data, err := json.MarshalIndent(attributes, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// write writes the files to dir, by path within it, refusing to overwrite any existing file.
+// It returns the paths written, sorted
+func write(dir string, files map[string][]byte) ([]string, error) {
+	paths := []string{}
+	for file := range files {
+		if utils.FileExists(filepath.Join(dir, file)) {
+			return nil, fmt.Errorf("%s already exists", filepath.Join(dir, file))
+		}
+		paths = append(paths, file)
+	}
+	sort.Strings(paths)
+	for _, file := range paths {
+		target := filepath.Join(dir, file)
+		err := os.MkdirAll(filepath.Dir(target), 0755)
+		if err != nil {
+			return nil, err
+		}
+		err = ioutil.WriteFile(target, files[file], 0644)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return paths, nil
+}
+
+const starterMetadata = `name: %[1]q
+maintainer: %[2]q
+email: %[3]q
+description: ""
+version: %[4]q
+attributes:
+  rc:
+    %[5]s:
+      number:
+        description: "Number of replicas"
+        type: int
+        min: 0
+        default: 1
+      image:
+        description: "Container image"
+        format: image
+        default: "nginx:1.9"
+  svc:
+    %[5]s:
+      port:
+        description: "Port the service is exposed at"
+        format: port
+        default: 80
+      type:
+        description: "How the service is exposed"
+        enum: [ClusterIP, NodePort, LoadBalancer]
+        default: ClusterIP
+rc:
+  %[5]s: "%[5]s-controller.yaml"
+svc:
+  %[5]s: "%[5]s-service.yaml"
+`
+
+const starterController = `apiVersion: v1
+kind: ReplicationController
+metadata:
+  name: %[1]s
+  labels:
+    name: %[1]s
+spec:
+  replicas: {{rc.%[1]s.number}}
+  selector:
+    name: %[1]s
+  template:
+    metadata:
+      labels:
+        name: %[1]s
+    spec:
+      containers:
+      - name: %[1]s
+        image: {{rc.%[1]s.image}}
+        ports:
+        - containerPort: 80
+`
+
+const starterService = `apiVersion: v1
+kind: Service
+metadata:
+  name: %[1]s
+  labels:
+    name: %[1]s
+spec:
+  type: {{svc.%[1]s.type}}
+  ports:
+  - port: {{svc.%[1]s.port}}
+    targetPort: 80
+  selector:
+    name: %[1]s
+`
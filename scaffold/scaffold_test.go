@@ -0,0 +1,130 @@
+package scaffold
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/flexiant/kdeploy/lint"
+	"github.com/flexiant/kdeploy/template"
+)
+
+func TestCreate(t *testing.T) {
+	parent, _ := ioutil.TempDir("", "kdeploy-init")
+	defer os.RemoveAll(parent)
+	dir := filepath.Join(parent, "My App")
+
+	files, err := Create(dir, Options{Maintainer: "Jane Doe", Email: "jane@example.com"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{
+		"attributes/development.json",
+		"attributes/production.json",
+		"metadata.yaml",
+		"my-app-controller.yaml",
+		"my-app-service.yaml",
+	}
+	if !reflect.DeepEqual(files, expected) {
+		t.Errorf("expected files %v, got %v", expected, files)
+	}
+
+	md, err := template.ReadMetadata(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if md.Name != "My App" || md.Maintainer != "Jane Doe" || md.Version != InitialVersion {
+		t.Errorf("unexpected metadata %+v", md)
+	}
+	if problems := lint.Lint(dir); len(problems) > 0 {
+		t.Errorf("starter kubeware has problems: %v", problems)
+	}
+
+	for _, file := range []string{"attributes/development.json", "attributes/production.json"} {
+		attributes, err := template.BuildAttributes([]string{filepath.Join(dir, file)}, nil, md)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := md.ParseControllers(attributes); err != nil {
+			t.Errorf("could not parse controllers with %s: %v", file, err)
+		}
+	}
+
+	_, err = Create(dir, Options{})
+	if err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("expected existing kubeware to be refused, got %v", err)
+	}
+}
+
+func TestConvert(t *testing.T) {
+	source, _ := ioutil.TempDir("", "kdeploy-manifests")
+	defer os.RemoveAll(source)
+	ioutil.WriteFile(filepath.Join(source, "redis.yaml"), []byte(`apiVersion: v1
+kind: ReplicationController
+metadata:
+  name: redis
+spec:
+  replicas: 1
+  template:
+    metadata:
+      labels:
+        name: redis
+---
+# the service
+apiVersion: v1
+kind: Service
+metadata:
+  name: redis
+spec:
+  ports:
+  - port: 6379
+---
+`), 0644)
+	ioutil.WriteFile(filepath.Join(source, "ingress.json"), []byte(`{"apiVersion": "extensions/v1beta1", "kind": "Ingress", "metadata": {"name": "web"}}`), 0644)
+	ioutil.WriteFile(filepath.Join(source, "list.yaml"), []byte("apiVersion: v1\nkind: List\nitems: []\n"), 0644)
+	ioutil.WriteFile(filepath.Join(source, "README.md"), []byte("# redis\n"), 0644)
+
+	dir, _ := ioutil.TempDir("", "kdeploy-init")
+	defer os.RemoveAll(dir)
+	files, err := Convert(dir, source, Options{Name: "redis"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{"metadata.yaml", "redis-controller.yaml", "redis-service.yaml", "web-ingress.json"}
+	if !reflect.DeepEqual(files, expected) {
+		t.Errorf("expected files %v, got %v", expected, files)
+	}
+
+	md, err := template.ReadMetadata(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if md.ReplicationControllers["redis"] != "redis-controller.yaml" || md.Services["redis"] != "redis-service.yaml" || md.Resources["web"] != "web-ingress.json" {
+		t.Errorf("unexpected metadata %+v", md)
+	}
+	service, _ := ioutil.ReadFile(filepath.Join(dir, "redis-service.yaml"))
+	if !strings.HasPrefix(string(service), "# the service\n") {
+		t.Errorf("expected manifest to be copied as is, got %s", service)
+	}
+	if problems := lint.Lint(dir); len(problems) > 0 {
+		t.Errorf("converted kubeware has problems: %v", problems)
+	}
+}
+
+func TestConvertDuplicates(t *testing.T) {
+	source, _ := ioutil.TempDir("", "kdeploy-manifests")
+	defer os.RemoveAll(source)
+	service := "apiVersion: v1\nkind: Service\nmetadata:\n  name: redis\n"
+	ioutil.WriteFile(filepath.Join(source, "a.yaml"), []byte(service), 0644)
+	ioutil.WriteFile(filepath.Join(source, "b.yaml"), []byte(service), 0644)
+
+	dir, _ := ioutil.TempDir("", "kdeploy-init")
+	defer os.RemoveAll(dir)
+	_, err := Convert(dir, source, Options{Name: "redis"})
+	if err == nil || !strings.Contains(err.Error(), "both define svc 'redis'") {
+		t.Errorf("expected duplicates to be refused, got %v", err)
+	}
+}
@@ -0,0 +1,30 @@
+package search
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/codegangsta/cli"
+	"github.com/flexiant/kdeploy/repository"
+	"github.com/flexiant/kdeploy/utils"
+)
+
+// CmdSearch implements 'search' command
+func CmdSearch(c *cli.Context) {
+	results, err := repository.Search(c.Args().First())
+	utils.CheckError(err)
+
+	if len(results) == 0 {
+		log.Infof("No Kubeware found")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 10, 1, 5, ' ', 0)
+	fmt.Fprintln(w, "NAME\tVERSION\tREPOSITORY\tDESCRIPTION\r")
+	for _, r := range results {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", r.Name, r.Version, r.Repository, r.Description)
+	}
+	w.Flush()
+}
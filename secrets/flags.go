@@ -0,0 +1,57 @@
+package secrets
+
+import (
+	"os"
+
+	"github.com/codegangsta/cli"
+)
+
+var keyFlag = cli.StringFlag{
+	Name:   "key",
+	Usage:  "File holding the secrets key, ~/.kdeploy/secrets.key by default",
+	EnvVar: "KDEPLOY_SECRETS_KEY",
+}
+
+var recipientFlag = cli.StringSliceFlag{
+	Name:  "recipient, r",
+	Usage: "Public key, besides the secrets key, that can decrypt the file: age, ssh-ed25519 or base64 ed25519",
+}
+
+// EncryptFlags builds a spec of the flags available for the 'secrets encrypt' command
+func EncryptFlags() []cli.Flag {
+	return []cli.Flag{
+		keyFlag,
+		recipientFlag,
+		cli.StringFlag{
+			Name:  "output, o",
+			Usage: "Encrypted file, the given one with an '.age' extension by default",
+		},
+	}
+}
+
+// DecryptFlags builds a spec of the flags available for the 'secrets decrypt' command
+func DecryptFlags() []cli.Flag {
+	return []cli.Flag{
+		keyFlag,
+		cli.StringFlag{
+			Name:  "output, o",
+			Usage: "Decrypted file, the standard output by default",
+		},
+	}
+}
+
+// EditFlags builds a spec of the flags available for the 'secrets edit' command
+func EditFlags() []cli.Flag {
+	return []cli.Flag{
+		keyFlag,
+		recipientFlag,
+	}
+}
+
+// PrepareFlags processes the flags
+func PrepareFlags(c *cli.Context) error {
+	if c.String("key") != "" {
+		os.Setenv("KDEPLOY_SECRETS_KEY", c.String("key"))
+	}
+	return nil
+}
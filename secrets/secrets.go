@@ -0,0 +1,134 @@
+package secrets
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/codegangsta/cli"
+	"github.com/flexiant/kdeploy/template"
+	"github.com/flexiant/kdeploy/utils"
+)
+
+// CmdEncrypt implements 'secrets encrypt' command
+func CmdEncrypt(c *cli.Context) {
+	if len(c.Args()) != 1 {
+		log.Fatal("Usage: kdeploy secrets encrypt <file>")
+	}
+	file := c.Args().First()
+	output := c.String("output")
+	if output == "" {
+		output = file + template.EncryptedExtension
+	}
+	err := EncryptFile(file, output, c.StringSlice("recipient"))
+	utils.CheckError(err)
+	log.Infof("Encrypted %s into %s. Remove %s once it is no longer needed", file, output, file)
+}
+
+// CmdDecrypt implements 'secrets decrypt' command
+func CmdDecrypt(c *cli.Context) {
+	if len(c.Args()) != 1 {
+		log.Fatal("Usage: kdeploy secrets decrypt <file>")
+	}
+	data, err := ioutil.ReadFile(c.Args().First())
+	utils.CheckError(err)
+	plain, err := utils.Decrypt(data)
+	utils.CheckError(err)
+	if c.String("output") == "" {
+		os.Stdout.Write(plain)
+		return
+	}
+	err = ioutil.WriteFile(c.String("output"), plain, 0600)
+	utils.CheckError(err)
+}
+
+// CmdEdit implements 'secrets edit' command
+func CmdEdit(c *cli.Context) {
+	if len(c.Args()) != 1 {
+		log.Fatal("Usage: kdeploy secrets edit <file>")
+	}
+	err := Edit(c.Args().First(), c.StringSlice("recipient"), editor)
+	utils.CheckError(err)
+}
+
+// EncryptFile encrypts an attributes file, which must parse, into output
+func EncryptFile(file, output string, recipients []string) error {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	if utils.IsEncrypted(data) {
+		return fmt.Errorf("%s is already encrypted", file)
+	}
+	if _, err := template.ReadAttributesFile(file); err != nil {
+		return err
+	}
+	encrypted, err := utils.Encrypt(data, recipients)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(output, encrypted, 0644)
+}
+
+// Edit decrypts an encrypted attributes file to a private temporary file, lets edit run on it,
+// and encrypts it back, for the secrets key and the recipients, if it was changed and still parses
+func Edit(file string, recipients []string, edit func(file string) error) error {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	plain, err := utils.Decrypt(data)
+	if err != nil {
+		return err
+	}
+
+	dir, err := ioutil.TempDir("", "kdeploy-secrets")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	// the temporary file keeps the extension telling its format
+	tmp := filepath.Join(dir, filepath.Base(strings.TrimSuffix(file, template.EncryptedExtension)))
+	err = ioutil.WriteFile(tmp, plain, 0600)
+	if err != nil {
+		return err
+	}
+
+	err = edit(tmp)
+	if err != nil {
+		return err
+	}
+	edited, err := ioutil.ReadFile(tmp)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(edited, plain) {
+		log.Infof("%s unchanged", file)
+		return nil
+	}
+	if _, err := template.ReadAttributesFile(tmp); err != nil {
+		return fmt.Errorf("%v, %s left unchanged", err, file)
+	}
+	encrypted, err := utils.Encrypt(edited, recipients)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, encrypted, 0644)
+}
+
+// editor runs the editor in EDITOR, vi by default, on a file
+func editor(file string) error {
+	name := os.Getenv("EDITOR")
+	if name == "" {
+		name = "vi"
+	}
+	args := strings.Fields(name)
+	cmd := exec.Command(args[0], append(args[1:], file)...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	return cmd.Run()
+}
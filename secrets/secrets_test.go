@@ -0,0 +1,95 @@
+package secrets
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/flexiant/kdeploy/template"
+)
+
+func withKey(t *testing.T) (string, func()) {
+	dir, _ := ioutil.TempDir("", "kdeploy-secrets")
+	identity, _ := age.GenerateX25519Identity()
+	key := filepath.Join(dir, "secrets.key")
+	ioutil.WriteFile(key, []byte(identity.String()), 0600)
+	os.Setenv("KDEPLOY_SECRETS_KEY", key)
+	return dir, func() {
+		os.Unsetenv("KDEPLOY_SECRETS_KEY")
+		os.RemoveAll(dir)
+	}
+}
+
+func TestEncryptFile(t *testing.T) {
+	dir, restore := withKey(t)
+	defer restore()
+	plain := filepath.Join(dir, "secrets.json")
+	ioutil.WriteFile(plain, []byte(`{"secret": {"db": {"password": "s3cr3t"}}}`), 0600)
+
+	err := EncryptFile(plain, plain+".age", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	values, err := template.ReadAttributesFile(plain + ".age")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if values["secret"].(map[string]interface{})["db"].(map[string]interface{})["password"] != "s3cr3t" {
+		t.Errorf("unexpected attributes %#v", values)
+	}
+
+	err = EncryptFile(plain+".age", plain+".age.age", nil)
+	if err == nil || !strings.Contains(err.Error(), "already encrypted") {
+		t.Errorf("expected encrypted file to be refused, got %v", err)
+	}
+	ioutil.WriteFile(plain, []byte(`{"secret": `), 0600)
+	if err := EncryptFile(plain, plain+".age", nil); err == nil {
+		t.Errorf("expected malformed attributes to be refused")
+	}
+}
+
+func TestEdit(t *testing.T) {
+	dir, restore := withKey(t)
+	defer restore()
+	plain := filepath.Join(dir, "secrets.yaml")
+	ioutil.WriteFile(plain, []byte("secret:\n  db:\n    password: old\n"), 0600)
+	file := plain + ".age"
+	EncryptFile(plain, file, nil)
+
+	var edited string
+	err := Edit(file, nil, func(tmp string) error {
+		edited = tmp
+		if filepath.Base(tmp) != "secrets.yaml" {
+			t.Errorf("expected temporary file to keep the format extension, got %s", tmp)
+		}
+		return ioutil.WriteFile(tmp, []byte("secret:\n  db:\n    password: new\n"), 0600)
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := os.Stat(edited); !os.IsNotExist(err) {
+		t.Errorf("expected temporary file to be removed")
+	}
+	values, err := template.ReadAttributesFile(file)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if values["secret"].(map[string]interface{})["db"].(map[string]interface{})["password"] != "new" {
+		t.Errorf("unexpected attributes %#v", values)
+	}
+
+	before, _ := ioutil.ReadFile(file)
+	err = Edit(file, nil, func(tmp string) error {
+		return ioutil.WriteFile(tmp, []byte("secret: [\n"), 0600)
+	})
+	if err == nil || !strings.Contains(err.Error(), "left unchanged") {
+		t.Errorf("expected malformed edit to be refused, got %v", err)
+	}
+	after, _ := ioutil.ReadFile(file)
+	if string(before) != string(after) {
+		t.Errorf("expected file to be left unchanged")
+	}
+}
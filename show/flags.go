@@ -2,32 +2,66 @@ package show
 
 import (
 	"os"
+	"strings"
 
 	"github.com/codegangsta/cli"
+	"github.com/flexiant/kdeploy/fetchers"
+	"github.com/flexiant/kdeploy/utils"
 )
 
 func Flags() []cli.Flag {
 	return []cli.Flag{
-		cli.StringFlag{
+		cli.StringSliceFlag{
 			Name:   "attribute, a",
-			Usage:  "Attribute List",
+			Usage:  "Attributes file, JSON or YAML. Repeat to merge several, left to right",
 			EnvVar: "KDEPLOY_ATTRIBUTE",
 		},
+		cli.StringSliceFlag{
+			Name:  "set",
+			Usage: "Attribute override applied last, as path=value (e.g. rc/frontend/number=3)",
+		},
+		cli.BoolFlag{
+			Name:   "strict",
+			Usage:  "Fail on template variables not resolved by the attributes",
+			EnvVar: "KDEPLOY_STRICT",
+		},
 		cli.StringFlag{
 			Name:   "kubeware, k",
 			Usage:  "Kubeware path",
 			EnvVar: "KDEPLOY_KUBEWARE",
 		},
+		cli.StringFlag{
+			Name:   "sha256",
+			Usage:  "Expected SHA-256 checksum of the kubeware archive",
+			EnvVar: "KDEPLOY_SHA256",
+		},
+		cli.StringFlag{
+			Name:   "instance, i",
+			Usage:  "Instance to show the Kubeware as, which prefixes the names of its resources",
+			EnvVar: "KDEPLOY_INSTANCE",
+		},
 	}
 }
 
 func PrepareFlags(c *cli.Context) error {
-	if c.String("attribute") != "" {
-		os.Setenv("KDEPLOY_ATTRIBUTE", c.String("attribute"))
+	if len(c.StringSlice("attribute")) > 0 {
+		os.Setenv("KDEPLOY_ATTRIBUTE", strings.Join(c.StringSlice("attribute"), ","))
 	}
 
 	if c.String("kubeware") != "" {
-		os.Setenv("KDEPLOY_KUBEWARE", c.String("kubeware"))
+		kubeware, err := fetchers.WithChecksum(c.String("kubeware"), c.String("sha256"))
+		if err != nil {
+			return err
+		}
+		os.Setenv("KDEPLOY_KUBEWARE", kubeware)
+	}
+
+	if c.String("instance") != "" {
+		err := utils.ValidateInstance(c.String("instance"))
+		if err != nil {
+			return err
+		}
+		os.Setenv("KDEPLOY_INSTANCE", c.String("instance"))
 	}
 
 	return nil
@@ -17,19 +17,25 @@ func CmdShow(c *cli.Context) {
 	utils.CheckRequiredFlags(c, []string{"kubeware"})
 
 	kubeware := os.Getenv("KDEPLOY_KUBEWARE")
-	localKubePath, err := fetchers.Fetch(kubeware)
+	fetched, err := fetchers.Fetch(kubeware)
 	if err != nil {
 		log.Fatal(fmt.Errorf("Could not fetch kubeware: '%s' (%v)", kubeware, err))
 	}
+	defer fetched.Close()
+	localKubePath := fetched.Path
 
 	log.Debugf("Going to parse kubeware in %s", localKubePath)
 
 	metadata := template.ParseMetadata(localKubePath)
-	defaults, err := metadata.AttributeDefaults()
-	utils.CheckError(err)
+	metadata.Instance = os.Getenv("KDEPLOY_INSTANCE")
 
 	log.Debugf("Building attributes")
-	attributes := template.BuildAttributes(os.Getenv("KDEPLOY_ATTRIBUTE"), defaults)
+	attributes, err := template.BuildAttributes(c.StringSlice("attribute"), c.StringSlice("set"), metadata)
+	utils.CheckError(err)
+	if c.Bool("strict") {
+		err = metadata.CheckTemplates(attributes)
+		utils.CheckError(err)
+	}
 
 	log.Debugf("Parsing services")
 	servicesSpecs, err := metadata.ParseServices(attributes)
@@ -39,15 +45,49 @@ func CmdShow(c *cli.Context) {
 	controllersSpecs, err := metadata.ParseControllers(attributes)
 	utils.CheckError(err)
 
+	log.Debugf("Parsing deployments")
+	deploymentsSpecs, err := metadata.ParseDeployments(attributes)
+	utils.CheckError(err)
+
+	log.Debugf("Parsing config maps and secrets")
+	configMapsSpecs, err := metadata.ParseConfigMaps(attributes)
+	utils.CheckError(err)
+	secretsSpecs, err := metadata.ParseSecrets(attributes)
+	utils.CheckError(err)
+
+	log.Debugf("Parsing resources")
+	resourcesSpecs, err := metadata.ParseResources(attributes)
+	utils.CheckError(err)
+
 	// print resolved resources
+	for _, cm := range configMapsSpecs {
+		y, err := gyml.JSONToYAML([]byte(cm))
+		utils.CheckError(err)
+		fmt.Println(utils.MaskSecrets(string(y)))
+	}
+	for _, s := range secretsSpecs {
+		y, err := gyml.JSONToYAML([]byte(s))
+		utils.CheckError(err)
+		fmt.Println(utils.MaskSecrets(string(y)))
+	}
+	for _, r := range resourcesSpecs {
+		y, err := gyml.JSONToYAML([]byte(r))
+		utils.CheckError(err)
+		fmt.Println(utils.MaskSecrets(string(y)))
+	}
 	for _, s := range servicesSpecs {
 		y, err := gyml.JSONToYAML([]byte(s))
 		utils.CheckError(err)
-		fmt.Println(string(y))
+		fmt.Println(utils.MaskSecrets(string(y)))
 	}
 	for _, c := range controllersSpecs {
 		y, err := gyml.JSONToYAML([]byte(c))
 		utils.CheckError(err)
-		fmt.Println(string(y))
+		fmt.Println(utils.MaskSecrets(string(y)))
+	}
+	for _, d := range deploymentsSpecs {
+		y, err := gyml.JSONToYAML([]byte(d))
+		utils.CheckError(err)
+		fmt.Println(utils.MaskSecrets(string(y)))
 	}
 }
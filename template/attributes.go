@@ -0,0 +1,295 @@
+package template
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/flexiant/kdeploy/utils"
+	"github.com/mafraba/deeply"
+	"gopkg.in/yaml.v2"
+)
+
+// Sources of attributes other than files
+const (
+	SourceDefault    = "default"
+	SourceSet        = "--set"
+	SourceDependents = "dependents"
+)
+
+// ResolvedAttributes are the attributes of a kubeware merged from every layer, along with the
+// layer that set each of them
+type ResolvedAttributes struct {
+	Values  map[string]interface{}
+	Layers  []string       // sources of the layers, in the order they were merged
+	sources map[string]int // layer that set each leaf attribute, by path
+	user    map[int]bool   // layers given by the user, the only ones whose values may reference secret sources
+}
+
+// Source returns where the attribute at path (e.g. 'rc/frontend/number') got its value from.
+// Attributes holding maps may have been set by several layers, which are all returned
+func (r *ResolvedAttributes) Source(path string) string {
+	if layer, ok := r.sources[path]; ok {
+		return r.Layers[layer]
+	}
+	layers := map[int]bool{}
+	for p, layer := range r.sources {
+		if strings.HasPrefix(p, path+"/") {
+			layers[layer] = true
+		}
+	}
+	sources := []string{}
+	for i, source := range r.Layers {
+		if layers[i] {
+			sources = append(sources, source)
+		}
+	}
+	return strings.Join(sources, ", ")
+}
+
+// Paths returns the paths of every leaf attribute, sorted
+func (r *ResolvedAttributes) Paths() []string {
+	paths := []string{}
+	for p := range r.sources {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// merge merges a layer over the attributes resolved so far
+func (r *ResolvedAttributes) merge(source string, values map[string]interface{}) {
+	layer := len(r.Layers)
+	r.Layers = append(r.Layers, source)
+	r.Values = deeply.Merge(r.Values, values)
+	walkLeaves("", values, func(path string, val interface{}) {
+		// a value replacing a map replaces whatever was set below it
+		for p := range r.sources {
+			if strings.HasPrefix(p, path+"/") {
+				delete(r.sources, p)
+			}
+		}
+		if val == nil {
+			delete(r.sources, path)
+			return
+		}
+		r.sources[path] = layer
+	})
+}
+
+// mergeUser merges a layer given by the user, whose values may reference secret sources
+func (r *ResolvedAttributes) mergeUser(source string, values map[string]interface{}) {
+	r.user[len(r.Layers)] = true
+	r.merge(source, values)
+}
+
+// fromUser tells if the leaf attribute at path was set by a layer given by the user
+func (r *ResolvedAttributes) fromUser(path string) bool {
+	layer, ok := r.sources[path]
+	return ok && r.user[layer]
+}
+
+func walkLeaves(prefix string, values map[string]interface{}, fn func(path string, val interface{})) {
+	for k, v := range values {
+		path := k
+		if prefix != "" {
+			path = prefix + "/" + k
+		}
+		if m, ok := v.(map[string]interface{}); ok && len(m) > 0 {
+			walkLeaves(path, m, fn)
+			continue
+		}
+		fn(path, v)
+	}
+}
+
+// ResolveAttributes merges the defaults of the kubeware, then each attributes file from left to
+// right, and finally the '--set' overrides given as 'path=value' (e.g. 'rc/frontend/number=3')
+func ResolveAttributes(files []string, sets []string, md Metadata) (*ResolvedAttributes, error) {
+	return ResolveAttributesWithOutputs(files, sets, nil, md)
+}
+
+// ResolveAttributesWithOutputs resolves the attributes as ResolveAttributes does, merging the
+// outputs of the kubewares it depends on, by kubeware name, over the defaults
+func ResolveAttributesWithOutputs(files []string, sets []string, outputs map[string]map[string]interface{}, md Metadata) (*ResolvedAttributes, error) {
+	r, err := resolveDefaults(md)
+	if err != nil {
+		return nil, err
+	}
+	if len(outputs) > 0 {
+		r.merge(SourceOutputs, outputsLayer(outputs))
+	}
+	for _, file := range files {
+		values, err := ReadAttributesFile(file)
+		if err != nil {
+			return nil, err
+		}
+		r.mergeUser(file, values)
+	}
+	if len(sets) > 0 {
+		values := map[string]interface{}{}
+		for _, set := range sets {
+			err := setPath(values, set)
+			if err != nil {
+				return nil, err
+			}
+		}
+		r.mergeUser(SourceSet, values)
+	}
+	return r, nil
+}
+
+func resolveDefaults(md Metadata) (*ResolvedAttributes, error) {
+	defaults, err := md.AttributeDefaults()
+	if err != nil {
+		return nil, err
+	}
+	r := &ResolvedAttributes{Values: map[string]interface{}{}, sources: map[string]int{}, user: map[int]bool{}}
+	r.merge(SourceDefault, defaults)
+	return r, nil
+}
+
+// BuildAttributes resolves the attributes of the kubeware and checks them against its attribute
+// metadata
+func BuildAttributes(files []string, sets []string, md Metadata) (map[string]interface{}, error) {
+	return BuildAttributesWithOutputs(files, sets, nil, md)
+}
+
+// BuildAttributesWithOutputs builds the attributes as BuildAttributes does, with the outputs of
+// the kubewares it depends on
+func BuildAttributesWithOutputs(files []string, sets []string, outputs map[string]map[string]interface{}, md Metadata) (map[string]interface{}, error) {
+	r, err := ResolveAttributesWithOutputs(files, sets, outputs, md)
+	if err != nil {
+		return nil, err
+	}
+	return r.build(md)
+}
+
+// BuildDependencyAttributes resolves the attributes of a dependency, its defaults overridden by
+// the outputs of the kubewares it depends on and then by the kubewares depending on it, and
+// checks them against its attribute metadata
+func BuildDependencyAttributes(overrides map[string]interface{}, outputs map[string]map[string]interface{}, md Metadata) (map[string]interface{}, error) {
+	r, err := resolveDefaults(md)
+	if err != nil {
+		return nil, err
+	}
+	if len(outputs) > 0 {
+		r.merge(SourceOutputs, outputsLayer(outputs))
+	}
+	if len(overrides) > 0 {
+		r.merge(SourceDependents, overrides)
+	}
+	return r.build(md)
+}
+
+// build resolves the secrets in the attributes and validates them. Only attributes given by the
+// user are resolved, so that kubewares, their dependents and the cluster can't make kdeploy read
+// local files or environment variables
+func (r *ResolvedAttributes) build(md Metadata) (map[string]interface{}, error) {
+	for _, path := range r.Paths() {
+		log.Debugf("Attribute %s set by %s", path, r.Source(path))
+	}
+	values, err := resolveSecrets("", r.Values, r.fromUser)
+	if err != nil {
+		return nil, err
+	}
+	err = md.ValidateAttributes(values.(map[string]interface{}))
+	if err != nil {
+		return nil, err
+	}
+	return values.(map[string]interface{}), nil
+}
+
+// ReadAttributesFile reads an attributes file, which is YAML if its extension tells so and JSON
+// otherwise. Files encrypted with age are decrypted with the secrets key, their extension told
+// without '.age', and every value in them is masked in the output
+func ReadAttributesFile(file string) (map[string]interface{}, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("could not read file '%s' (%v)", file, err)
+	}
+	encrypted := utils.IsEncrypted(data)
+	if encrypted {
+		data, err = utils.Decrypt(data)
+		if err != nil {
+			return nil, fmt.Errorf("could not read file '%s' (%v)", file, err)
+		}
+	}
+	attributes, err := parseAttributes(strings.TrimSuffix(file, EncryptedExtension), data)
+	if err != nil {
+		return nil, err
+	}
+	if encrypted {
+		walkLeaves("", attributes, func(path string, val interface{}) {
+			if s, ok := val.(string); ok {
+				utils.RegisterSecret(s)
+			}
+		})
+	}
+	return attributes, nil
+}
+
+func parseAttributes(file string, data []byte) (map[string]interface{}, error) {
+	var err error
+	var attributes map[string]interface{}
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".yaml", ".yml":
+		var values interface{}
+		err = yaml.Unmarshal(data, &values)
+		if err == nil {
+			var normalized interface{}
+			normalized, err = normalizeValue(values)
+			attributes, _ = normalized.(map[string]interface{})
+			if err == nil && values != nil && attributes == nil {
+				err = fmt.Errorf("attributes must be a map")
+			}
+		}
+	default:
+		err = json.Unmarshal(data, &attributes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not parse file '%s' (%v)", file, err)
+	}
+	if attributes == nil {
+		attributes = map[string]interface{}{}
+	}
+	return attributes, nil
+}
+
+// setPath sets the value in 'path=value' into the attributes. The value is read as YAML, so
+// that numbers and booleans keep their type
+func setPath(attributes map[string]interface{}, set string) error {
+	i := strings.Index(set, "=")
+	if i <= 0 {
+		return fmt.Errorf("invalid --set '%s', expected path=value", set)
+	}
+	path, raw := strings.Trim(set[:i], "/"), set[i+1:]
+	var value interface{}
+	if err := yaml.Unmarshal([]byte(raw), &value); err != nil {
+		return fmt.Errorf("invalid value in --set '%s': %v", set, err)
+	}
+	value, err := normalizeValue(value)
+	if err != nil {
+		// values such as a bare null are kept as they were given
+		value = raw
+	}
+	keys := strings.Split(path, "/")
+	node := attributes
+	for _, key := range keys[:len(keys)-1] {
+		if key == "" {
+			return fmt.Errorf("invalid path in --set '%s'", set)
+		}
+		next, ok := node[key].(map[string]interface{})
+		if !ok {
+			next = map[string]interface{}{}
+			node[key] = next
+		}
+		node = next
+	}
+	node[keys[len(keys)-1]] = value
+	return nil
+}
@@ -0,0 +1,147 @@
+package template
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/flexiant/digger"
+	"gopkg.in/yaml.v2"
+)
+
+func TestResolveAttributes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kdeploy-attributes")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	base := filepath.Join(dir, "base.json")
+	ioutil.WriteFile(base, []byte(`{"rc":{"frontend":{"number":2,"image":"kubernetes/example-guestbook-php-redis"}},"svc":{"frontend":{"port":8080}}}`), 0644)
+	cluster := filepath.Join(dir, "cluster.yaml")
+	ioutil.WriteFile(cluster, []byte("svc:\n  frontend:\n    balancer: NodePort\n    port: 80\n"), 0644)
+
+	var md Metadata
+	err = yaml.Unmarshal([]byte(metadataSample), &md)
+	if err != nil {
+		t.Fatalf("error parsing metadata: %v", err)
+	}
+	r, err := ResolveAttributes([]string{base, cluster}, []string{"rc/frontend/number=3", "rc/frontend/version=v3"}, md)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	d, err := digger.NewMapDigger(r.Values)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for path, expected := range map[string]struct {
+		value  interface{}
+		source string
+	}{
+		"rc/frontend/number":    {3, SourceSet},
+		"rc/frontend/version":   {"v3", SourceSet},
+		"rc/frontend/image":     {"kubernetes/example-guestbook-php-redis", base},
+		"rc/frontend/name":      {"php-redis", SourceDefault},
+		"svc/frontend/port":     {80, cluster},
+		"svc/frontend/balancer": {"NodePort", cluster},
+	} {
+		if source := r.Source(path); source != expected.source {
+			t.Errorf("expected %s to be set by %s, got %s", path, expected.source, source)
+		}
+		leaf, err := d.Get(path)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if leaf != expected.value {
+			t.Errorf("expected %s to be %#v, got %#v", path, expected.value, leaf)
+		}
+	}
+	if source := r.Source("rc/frontend"); source != SourceDefault+", "+base+", "+SourceSet {
+		t.Errorf("unexpected sources of rc/frontend: %s", source)
+	}
+
+	if _, err := BuildAttributes([]string{base, cluster}, nil, md); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := BuildAttributes(nil, []string{"svc/frontend/port=80"}, md); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := ResolveAttributes(nil, []string{"rc/frontend/number"}, md); err == nil {
+		t.Errorf("expected an error for a --set without value")
+	}
+	if _, err := ResolveAttributes([]string{filepath.Join(dir, "missing.json")}, nil, md); err == nil {
+		t.Errorf("expected an error for a missing attributes file")
+	}
+}
+
+func TestBuildDependencyAttributes(t *testing.T) {
+	var md Metadata
+	yaml.Unmarshal([]byte(`
+attributes:
+  rc:
+    redis-slave:
+      number:
+        type: int
+        default: 1
+      image:
+        default: "redis"
+`), &md)
+
+	attributes, err := BuildDependencyAttributes(map[string]interface{}{
+		"rc": map[string]interface{}{"redis-slave": map[string]interface{}{"number": 3}},
+	}, nil, md)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	slave := attributes["rc"].(map[string]interface{})["redis-slave"].(map[string]interface{})
+	if slave["number"] != 3 || slave["image"] != "redis" {
+		t.Errorf("unexpected attributes %v", slave)
+	}
+
+	_, err = BuildDependencyAttributes(map[string]interface{}{
+		"rc": map[string]interface{}{"redis-slave": map[string]interface{}{"number": "three"}},
+	}, nil, md)
+	if err == nil || !strings.Contains(err.Error(), "rc/redis-slave/number: must be of type int") {
+		t.Errorf("expected overrides to be validated, got %v", err)
+	}
+}
+
+func TestOutputAttributes(t *testing.T) {
+	var md Metadata
+	yaml.Unmarshal([]byte(`
+attributes:
+  rc:
+    frontend:
+      redis:
+        default: "localhost"
+`), &md)
+
+	r, err := ResolveAttributesWithOutputs(nil, []string{"outputs/redis/port=6380"}, map[string]map[string]interface{}{
+		"redis": {"host": "10.0.0.12", "port": 6379},
+	}, md)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	redis := r.Values[OutputsAttribute].(map[string]interface{})["redis"].(map[string]interface{})
+	if redis["host"] != "10.0.0.12" || redis["port"] != 6380 {
+		t.Errorf("unexpected outputs %v", redis)
+	}
+	if source := r.Source("outputs/redis/host"); source != SourceOutputs {
+		t.Errorf("expected outputs/redis/host to come from %s, got %s", SourceOutputs, source)
+	}
+	if source := r.Source("outputs/redis/port"); source != SourceSet {
+		t.Errorf("expected outputs to be overridden by %s, got %s", SourceSet, source)
+	}
+
+	attributes, err := BuildDependencyAttributes(nil, map[string]map[string]interface{}{
+		"redis": {"host": "10.0.0.12"},
+	}, md)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := attributes[OutputsAttribute].(map[string]interface{})["redis"]; !ok {
+		t.Errorf("expected the outputs of redis in %v", attributes)
+	}
+}
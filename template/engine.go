@@ -0,0 +1,338 @@
+package template
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	gotemplate "text/template"
+	"text/template/parse"
+
+	"github.com/cbroglie/mustache"
+	"gopkg.in/yaml.v2"
+)
+
+// Template engines a kubeware can select in its metadata
+const (
+	EngineMustache   = "mustache"
+	EngineGoTemplate = "gotemplate"
+)
+
+// Funcs are the helpers available to gotemplate kubewares
+var Funcs = gotemplate.FuncMap{
+	"default":  defaultValue,
+	"quote":    quote,
+	"toYaml":   toYaml,
+	"indent":   indent,
+	"b64enc":   b64enc,
+	"join":     join,
+	"required": required,
+	"add":      arithmetic(func(a, b float64) float64 { return a + b }),
+	"sub":      arithmetic(func(a, b float64) float64 { return a - b }),
+	"mul":      arithmetic(func(a, b float64) float64 { return a * b }),
+	"div":      arithmetic(func(a, b float64) float64 { return a / b }),
+}
+
+// engine returns the template engine selected by the kubeware, mustache by default
+func (m Metadata) engine() string {
+	if m.Engine == "" {
+		return EngineMustache
+	}
+	return m.Engine
+}
+
+// resolve resolves a template of the kubeware, given by its path within the kubeware, with its
+// partials included and its engine
+func (m Metadata) resolve(templateFile string, attributes map[string]interface{}) (string, error) {
+	expanded, err := m.expand(templateFile)
+	if err != nil {
+		return "", err
+	}
+	attributes = m.withInstance(attributes)
+	switch m.engine() {
+	case EngineMustache:
+		output, err := mustache.Render(expanded.content, attributes)
+		if err != nil {
+			return "", fmt.Errorf("error parsing template %s: %v", templateFile, err)
+		}
+		return output, nil
+	case EngineGoTemplate:
+		return renderGoTemplate(templateFile, expanded.content, attributes)
+	}
+	return "", fmt.Errorf("unknown template engine '%s'", m.Engine)
+}
+
+func renderGoTemplate(name, content string, attributes map[string]interface{}) (string, error) {
+	t, err := gotemplate.New(name).Funcs(Funcs).Funcs(gotemplate.FuncMap{blankFunc: blank}).Parse(content)
+	if err != nil {
+		return "", fmt.Errorf("error parsing template %s: %v", name, err)
+	}
+	for _, tmpl := range t.Templates() {
+		if tmpl.Tree != nil {
+			blankMissing(tmpl.Tree, tmpl.Tree.Root)
+		}
+	}
+	var out bytes.Buffer
+	err = t.Execute(&out, attributes)
+	if err != nil {
+		return "", fmt.Errorf("error parsing template %s: %v", name, err)
+	}
+	return out.String(), nil
+}
+
+// blankFunc is the helper, not available to kubewares, that every action is piped to so that
+// missing values are rendered blank as mustache does, rather than as '<no value>'
+const blankFunc = "kdeployBlank"
+
+func blank(value interface{}) interface{} {
+	if value == nil {
+		return ""
+	}
+	return value
+}
+
+// blankMissing pipes the output of every action under node to the blank helper
+func blankMissing(tree *parse.Tree, node parse.Node) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			blankMissing(tree, child)
+		}
+	case *parse.ActionNode:
+		// assignments render nothing
+		if len(n.Pipe.Decl) > 0 {
+			return
+		}
+		helper := parse.NewIdentifier(blankFunc).SetTree(tree).SetPos(n.Position())
+		n.Pipe.Cmds = append(n.Pipe.Cmds, &parse.CommandNode{NodeType: parse.NodeCommand, Pos: n.Position(), Args: []parse.Node{helper}})
+	case *parse.IfNode:
+		blankMissing(tree, n.List)
+		blankMissing(tree, n.ElseList)
+	case *parse.WithNode:
+		blankMissing(tree, n.List)
+		blankMissing(tree, n.ElseList)
+	case *parse.RangeNode:
+		blankMissing(tree, n.List)
+		blankMissing(tree, n.ElseList)
+	}
+}
+
+func defaultValue(def interface{}, value ...interface{}) interface{} {
+	if len(value) == 0 || isEmpty(value[0]) {
+		return def
+	}
+	return value[0]
+}
+
+func isEmpty(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	}
+	return false
+}
+
+func quote(value interface{}) string {
+	if value == nil {
+		return `""`
+	}
+	return strconv.Quote(fmt.Sprint(value))
+}
+
+func toYaml(value interface{}) (string, error) {
+	out, err := yaml.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(string(out), "\n"), nil
+}
+
+func indent(spaces int, s string) string {
+	pad := strings.Repeat(" ", spaces)
+	return pad + strings.Replace(s, "\n", "\n"+pad, -1)
+}
+
+func b64enc(value interface{}) string {
+	return base64.StdEncoding.EncodeToString([]byte(fmt.Sprint(value)))
+}
+
+func join(sep string, list interface{}) (string, error) {
+	v := reflect.ValueOf(list)
+	if v.Kind() != reflect.Slice {
+		return "", fmt.Errorf("join expects a list, got %T", list)
+	}
+	items := make([]string, v.Len())
+	for i := range items {
+		items[i] = fmt.Sprint(v.Index(i).Interface())
+	}
+	return strings.Join(items, sep), nil
+}
+
+func required(msg string, value interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, errors.New(msg)
+	}
+	if s, ok := value.(string); ok && s == "" {
+		return nil, errors.New(msg)
+	}
+	return value, nil
+}
+
+// arithmetic builds a helper operating on numbers, which may have been read as integers or
+// floats, and keeping integral results as integers
+func arithmetic(op func(a, b float64) float64) func(a, b interface{}) (interface{}, error) {
+	return func(a, b interface{}) (interface{}, error) {
+		x, ok := toFloat(a)
+		if !ok {
+			return nil, fmt.Errorf("expected a number, got %#v", a)
+		}
+		y, ok := toFloat(b)
+		if !ok {
+			return nil, fmt.Errorf("expected a number, got %#v", b)
+		}
+		result := op(x, y)
+		if result == math.Trunc(result) && !math.IsInf(result, 0) {
+			return int(result), nil
+		}
+		return result, nil
+	}
+}
+
+// analyzeGoTemplate walks the fields a text/template template refers to, resolving them against
+// the attributes. It returns those not resolved, and records in used the paths of those that are.
+// Fields given to 'default' may be missing on purpose, so they are not reported
+func analyzeGoTemplate(file, content string, attributes map[string]interface{}, used map[string]bool) ([]UndefinedVariable, error) {
+	t, err := gotemplate.New(file).Funcs(Funcs).Parse(content)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing template %s: %v", file, err)
+	}
+	a := &goTemplateAnalysis{file: file, content: content, root: frame{value: attributes}, used: used}
+	for _, tmpl := range t.Templates() {
+		if tmpl.Tree != nil && tmpl.Tree.Root != nil {
+			a.walk(tmpl.Tree.Root, a.root)
+		}
+	}
+	return a.undefined, nil
+}
+
+type goTemplateAnalysis struct {
+	file      string
+	content   string
+	root      frame
+	used      map[string]bool
+	undefined []UndefinedVariable
+}
+
+func (a *goTemplateAnalysis) walk(node parse.Node, dot frame) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			a.walk(child, dot)
+		}
+	case *parse.ActionNode:
+		a.pipe(n.Pipe, dot)
+	case *parse.IfNode:
+		a.pipe(n.Pipe, dot)
+		a.walk(n.List, dot)
+		a.walk(n.ElseList, dot)
+	case *parse.WithNode:
+		inner := a.pipe(n.Pipe, dot)
+		a.walk(n.List, inner)
+		a.walk(n.ElseList, dot)
+	case *parse.RangeNode:
+		inner := a.pipe(n.Pipe, dot)
+		// items of a list are checked against the first one, as with mustache sections
+		if items, ok := inner.value.([]interface{}); ok && !inner.unknown && len(items) > 0 {
+			inner = frame{value: items[0], path: inner.path}
+		} else {
+			inner = frame{unknown: true}
+		}
+		a.walk(n.List, inner)
+		a.walk(n.ElseList, dot)
+	case *parse.TemplateNode:
+		a.pipe(n.Pipe, dot)
+	}
+}
+
+// pipe checks the fields in a pipeline, returning the frame it evaluates to when it is a single
+// field, or an unknown frame otherwise
+func (a *goTemplateAnalysis) pipe(p *parse.PipeNode, dot frame) frame {
+	if p == nil {
+		return frame{unknown: true}
+	}
+	result := frame{unknown: true}
+	// values given to default, as arguments or piped into it, may be missing
+	defaulted := -1
+	for i, cmd := range p.Cmds {
+		if len(cmd.Args) > 0 {
+			if ident, ok := cmd.Args[0].(*parse.IdentifierNode); ok && ident.Ident == "default" {
+				defaulted = i
+			}
+		}
+	}
+	for i, cmd := range p.Cmds {
+		lenient := i <= defaulted
+		for _, arg := range cmd.Args {
+			f := a.arg(arg, dot, lenient)
+			if len(p.Cmds) == 1 && len(cmd.Args) == 1 {
+				result = f
+			}
+		}
+	}
+	return result
+}
+
+func (a *goTemplateAnalysis) arg(node parse.Node, dot frame, lenient bool) frame {
+	switch n := node.(type) {
+	case *parse.FieldNode:
+		return a.field(n.Position(), n.Ident, dot, lenient)
+	case *parse.VariableNode:
+		// only '$' is known, other variables hold what was assigned to them
+		if len(n.Ident) > 1 && n.Ident[0] == "$" {
+			return a.field(n.Position(), n.Ident[1:], a.root, lenient)
+		}
+	case *parse.DotNode:
+		return dot
+	case *parse.PipeNode:
+		return a.pipe(n, dot)
+	}
+	return frame{unknown: true}
+}
+
+func (a *goTemplateAnalysis) field(pos parse.Pos, idents []string, dot frame, lenient bool) frame {
+	name := strings.Join(idents, ".")
+	value, path, found := lookup([]frame{dot}, name)
+	if !found {
+		if !lenient {
+			line := strings.Count(a.content[:pos], "\n") + 1
+			a.undefined = append(a.undefined, UndefinedVariable{File: a.file, Line: line, Name: "." + name})
+		}
+		return frame{unknown: true}
+	}
+	if path != "" {
+		a.used[path] = true
+	}
+	if dot.unknown {
+		return frame{unknown: true}
+	}
+	if m, ok := asMap(value); ok {
+		return frame{value: m, path: path}
+	}
+	return frame{value: value, path: path}
+}
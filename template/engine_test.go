@@ -0,0 +1,132 @@
+package template
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGoTemplateEngine(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kdeploy-kubeware")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "metadata.yaml"), []byte(`
+name: "Guestbook"
+version: "0.0.1"
+engine: gotemplate
+rc:
+  frontend: "frontend-controller.yaml"
+cm:
+  frontend: "frontend-configmap.yaml"
+attributes:
+  rc:
+    frontend:
+      number:
+        default: 2
+      env:
+        default: prod
+      args:
+        default: ["-v", "--port=80"]
+      labels:
+        default:
+          tier: frontend
+      image:
+        description: "Optional, without default"
+`), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "frontend-controller.yaml"), []byte(`kind: ReplicationController
+metadata:
+  name: frontend
+  labels:
+{{ toYaml .rc.frontend.labels | indent 4 }}
+spec:
+  replicas: {{ if eq .rc.frontend.env "prod" }}{{ mul .rc.frontend.number 2 }}{{ else }}1{{ end }}
+  template:
+    spec:
+      containers:
+      - image: {{ .rc.frontend.image | default "gb-frontend:v4" | quote }}
+        command: ["sh", "-c", {{ join " " .rc.frontend.args | quote }}]
+        {{- with .rc.frontend }}
+        name: {{ .env }}-frontend
+        {{- end }}
+`), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "frontend-configmap.yaml"), []byte(`kind: ConfigMap
+metadata:
+  name: frontend
+data:
+  password: {{ b64enc "secret" }}
+  missing: "{{ .rc.frontend.unknown }}"
+  literal: "<no value>"
+`), 0644)
+
+	md, err := ReadMetadata(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	attributes, err := BuildAttributes(nil, nil, md)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	controllers, err := md.ParseControllers(attributes)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var rc struct {
+		Metadata struct{ Labels map[string]string }
+		Spec     struct {
+			Replicas int
+			Template struct {
+				Spec struct {
+					Containers []struct {
+						Name    string
+						Image   string
+						Command []string
+					}
+				}
+			}
+		}
+	}
+	err = json.Unmarshal([]byte(controllers["frontend"]), &rc)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	container := rc.Spec.Template.Spec.Containers[0]
+	if rc.Spec.Replicas != 4 || container.Image != "gb-frontend:v4" || container.Name != "prod-frontend" || container.Command[2] != "-v --port=80" {
+		t.Errorf("unexpected rendering: %s", controllers["frontend"])
+	}
+	if rc.Metadata.Labels["tier"] != "frontend" || rc.Metadata.Labels["kubeware"] != "guestbook" {
+		t.Errorf("unexpected labels: %v", rc.Metadata.Labels)
+	}
+
+	configMaps, err := md.ParseConfigMaps(attributes)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(configMaps["frontend"], `"password":"c2VjcmV0"`) || !strings.Contains(configMaps["frontend"], `"missing":""`) || !strings.Contains(configMaps["frontend"], `"literal":"\u003cno value\u003e"`) {
+		t.Errorf("unexpected rendering: %s", configMaps["frontend"])
+	}
+
+	err = md.CheckTemplates(attributes)
+	undefined, ok := err.(UndefinedVariables)
+	if !ok || len(undefined) != 1 || undefined[0] != (UndefinedVariable{"frontend-configmap.yaml", 6, ".rc.frontend.unknown"}) {
+		t.Errorf("expected only .rc.frontend.unknown to be undefined, got %v", err)
+	}
+
+	ioutil.WriteFile(filepath.Join(dir, "metadata.yaml"), []byte("name: guestbook\nengine: jinja\n"), 0644)
+	if _, err := ReadMetadata(dir); err == nil {
+		t.Errorf("expected an error for an unknown engine")
+	}
+}
+
+func TestRequired(t *testing.T) {
+	if _, err := required("image is required", ""); err == nil || err.Error() != "image is required" {
+		t.Errorf("expected required to fail on empty values, got %v", err)
+	}
+	if v, err := required("image is required", "redis"); err != nil || v != "redis" {
+		t.Errorf("unexpected result: %v %v", v, err)
+	}
+}
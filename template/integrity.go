@@ -0,0 +1,226 @@
+package template
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/flexiant/kdeploy/utils"
+)
+
+// ErrUnsigned indicates the kubeware carries no signature
+var ErrUnsigned = errors.New("kubeware is not signed")
+
+// TemplateFiles returns the paths of all the template files in the kubeware, partials included,
+// sorted
+func (m Metadata) TemplateFiles() []string {
+	partials, err := m.Partials()
+	if err != nil {
+		log.Warnf("Could not list partials: %v", err)
+	}
+	return uniqueFiles(m.ReplicationControllers, m.Services, m.Deployments, m.ConfigMaps, m.Secrets, m.Resources, partials)
+}
+
+// ResourceTemplateFiles returns the paths of the templates of the kubeware resources, sorted
+func (m Metadata) ResourceTemplateFiles() []string {
+	return uniqueFiles(m.ReplicationControllers, m.Services, m.Deployments, m.ConfigMaps, m.Secrets, m.Resources)
+}
+
+func uniqueFiles(templates ...map[string]string) []string {
+	seen := map[string]bool{}
+	files := []string{}
+	for _, t := range templates {
+		for _, file := range t {
+			file = path.Clean(filepath.ToSlash(file))
+			if !seen[file] {
+				seen[file] = true
+				files = append(files, file)
+			}
+		}
+	}
+	sort.Strings(files)
+	return files
+}
+
+// FileDigests returns the expected SHA-256 digests of the kubeware files, either inlined in the
+// metadata or read from the manifest it points to. It returns nil if the kubeware has none
+func (m Metadata) FileDigests() (map[string]string, error) {
+	if m.Manifest == "" {
+		if m.Digests == nil {
+			return nil, nil
+		}
+		digests := map[string]string{}
+		for file, digest := range m.Digests {
+			digests[path.Clean(filepath.ToSlash(file))] = strings.ToLower(digest)
+		}
+		return digests, nil
+	}
+	if len(m.Digests) > 0 {
+		return nil, fmt.Errorf("metadata can't both inline digests and point to a manifest")
+	}
+	data, err := ioutil.ReadFile(filepath.Join(m.path, m.Manifest))
+	if err != nil {
+		return nil, fmt.Errorf("could not read manifest: %v", err)
+	}
+	return ParseManifest(data)
+}
+
+// VerifyDigests checks every template file against its expected digest. Kubewares without
+// digests are not checked
+func (m Metadata) VerifyDigests() error {
+	digests, err := m.FileDigests()
+	if err != nil {
+		return err
+	}
+	if digests == nil {
+		return nil
+	}
+	for _, file := range m.TemplateFiles() {
+		if _, ok := digests[file]; !ok {
+			return fmt.Errorf("no digest for template %s", file)
+		}
+	}
+	for file, expected := range digests {
+		actual, err := FileDigest(filepath.Join(m.path, filepath.FromSlash(file)))
+		if err != nil {
+			return fmt.Errorf("could not verify %s: %v", file, err)
+		}
+		if actual != expected {
+			return fmt.Errorf("digest mismatch for %s: expected sha256 %s, got %s", file, expected, actual)
+		}
+	}
+	return nil
+}
+
+// SignedFile returns the file covered by the kubeware signature: the manifest when there is
+// one, or the metadata file otherwise
+func (m Metadata) SignedFile() string {
+	if m.Manifest != "" {
+		return filepath.Join(m.path, m.Manifest)
+	}
+	return filepath.Join(m.path, "metadata.yaml")
+}
+
+// VerifySignature checks the detached ed25519 signature kept next to the signed file (with a
+// '.sig' extension) against the trusted keys. It returns ErrUnsigned if there is no signature
+func (m Metadata) VerifySignature(keys []ed25519.PublicKey) error {
+	signed := m.SignedFile()
+	encoded, err := ioutil.ReadFile(fmt.Sprintf("%s.sig", signed))
+	if os.IsNotExist(err) {
+		return ErrUnsigned
+	}
+	if err != nil {
+		return err
+	}
+	signature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(encoded)))
+	if err != nil || len(signature) != ed25519.SignatureSize {
+		return fmt.Errorf("malformed signature for %s", filepath.Base(signed))
+	}
+	if m.Manifest != "" {
+		// the metadata is only covered by the signature through the manifest
+		digests, err := m.FileDigests()
+		if err != nil {
+			return err
+		}
+		if _, ok := digests["metadata.yaml"]; !ok {
+			return fmt.Errorf("signed manifest must include metadata.yaml")
+		}
+	}
+	data, err := ioutil.ReadFile(signed)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if ed25519.Verify(key, data, signature) {
+			return nil
+		}
+	}
+	return fmt.Errorf("signature for %s does not match any trusted key", filepath.Base(signed))
+}
+
+// TrustedKeys reads the ed25519 public keys trusted to sign kubewares, kept base64 encoded
+// in '.pub' files at ~/.kdeploy/trust
+func TrustedKeys() ([]ed25519.PublicKey, error) {
+	dir, err := utils.KdeployDir()
+	if err != nil {
+		return nil, err
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "trust", "*.pub"))
+	if err != nil {
+		return nil, err
+	}
+	keys := []ed25519.PublicKey{}
+	for _, file := range files {
+		encoded, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(encoded)))
+		if err != nil || len(key) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("malformed public key in %s", file)
+		}
+		keys = append(keys, ed25519.PublicKey(key))
+	}
+	return keys, nil
+}
+
+// ParseManifest parses a manifest holding one '<sha256>  <path>' line per file, as written by
+// sha256sum
+func ParseManifest(data []byte) (map[string]string, error) {
+	digests := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 || len(fields[0]) != hex.EncodedLen(sha256.Size) {
+			return nil, fmt.Errorf("malformed manifest line %d: %s", n, line)
+		}
+		digests[path.Clean(strings.TrimPrefix(fields[1], "*"))] = strings.ToLower(fields[0])
+	}
+	return digests, scanner.Err()
+}
+
+// FormatManifest writes the digests as a manifest, sorted by path
+func FormatManifest(digests map[string]string) []byte {
+	files := make([]string, 0, len(digests))
+	for file := range digests {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	var buf bytes.Buffer
+	for _, file := range files {
+		fmt.Fprintf(&buf, "%s  %s\n", digests[file], file)
+	}
+	return buf.Bytes()
+}
+
+// FileDigest calculates the SHA-256 digest of a file
+func FileDigest(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	hash := sha256.New()
+	_, err = io.Copy(hash, f)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
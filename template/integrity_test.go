@@ -0,0 +1,122 @@
+package template
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const integrityMetadata = `
+name: "Guestbook"
+version: "0.0.1"
+rc:
+  frontend: "frontend-controller.yaml"
+svc:
+  frontend: "frontend-service.yaml"
+`
+
+func TestVerifyInlineDigests(t *testing.T) {
+	dir := tempKubeware(t)
+	defer os.RemoveAll(dir)
+
+	md := readTestMetadata(t, dir, integrityMetadata+digestsSection(t, dir))
+	if err := md.VerifyDigests(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ioutil.WriteFile(filepath.Join(dir, "frontend-service.yaml"), []byte("kind: Service\nspec: {}\n"), 0644)
+	if err := md.VerifyDigests(); err == nil {
+		t.Errorf("tampered template should have been detected")
+	}
+}
+
+func TestVerifyDigestsMissingTemplate(t *testing.T) {
+	dir := tempKubeware(t)
+	defer os.RemoveAll(dir)
+
+	digest, _ := FileDigest(filepath.Join(dir, "frontend-service.yaml"))
+	md := readTestMetadata(t, dir, integrityMetadata+"digests:\n  frontend-service.yaml: "+digest+"\n")
+	if err := md.VerifyDigests(); err == nil {
+		t.Errorf("template without digest should have been detected")
+	}
+}
+
+func TestVerifySignedManifest(t *testing.T) {
+	dir := tempKubeware(t)
+	defer os.RemoveAll(dir)
+	public, private, _ := ed25519.GenerateKey(rand.Reader)
+	other, _, _ := ed25519.GenerateKey(rand.Reader)
+
+	md := readTestMetadata(t, dir, integrityMetadata+"manifest: \"MANIFEST\"\n")
+	if err := md.VerifySignature([]ed25519.PublicKey{public}); err != ErrUnsigned {
+		t.Fatalf("expected ErrUnsigned, got %v", err)
+	}
+
+	digests := map[string]string{}
+	for _, file := range append(md.TemplateFiles(), "metadata.yaml") {
+		digests[file], _ = FileDigest(filepath.Join(dir, file))
+	}
+	manifest := FormatManifest(digests)
+	ioutil.WriteFile(filepath.Join(dir, "MANIFEST"), manifest, 0644)
+	signature := base64.StdEncoding.EncodeToString(ed25519.Sign(private, manifest))
+	ioutil.WriteFile(filepath.Join(dir, "MANIFEST.sig"), []byte(signature+"\n"), 0644)
+
+	if err := md.VerifyDigests(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := md.VerifySignature([]ed25519.PublicKey{other, public}); err != nil {
+		t.Errorf("signature should match trusted key: %v", err)
+	}
+	if err := md.VerifySignature([]ed25519.PublicKey{other}); err == nil {
+		t.Errorf("signature should not match untrusted key")
+	}
+}
+
+func TestParseManifest(t *testing.T) {
+	digest := "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	digests, err := ParseManifest([]byte("# kubeware files\n" + digest + "  ./frontend-service.yaml\n" + digest + " *metadata.yaml\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if digests["frontend-service.yaml"] != digest || digests["metadata.yaml"] != digest {
+		t.Errorf("unexpected digests: %v", digests)
+	}
+	if _, err = ParseManifest([]byte("not a digest  metadata.yaml\n")); err == nil {
+		t.Errorf("malformed manifest should fail")
+	}
+}
+
+func tempKubeware(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "kdeploy-kubeware")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ioutil.WriteFile(filepath.Join(dir, "frontend-controller.yaml"), []byte("kind: ReplicationController\n"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "frontend-service.yaml"), []byte("kind: Service\n"), 0644)
+	return dir
+}
+
+func digestsSection(t *testing.T, dir string) string {
+	section := "digests:\n"
+	for _, file := range []string{"frontend-controller.yaml", "frontend-service.yaml"} {
+		digest, err := FileDigest(filepath.Join(dir, file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		section += "  " + file + ": " + digest + "\n"
+	}
+	return section
+}
+
+func readTestMetadata(t *testing.T, dir, metadata string) Metadata {
+	ioutil.WriteFile(filepath.Join(dir, "metadata.yaml"), []byte(metadata), 0644)
+	md, err := ReadMetadata(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return md
+}
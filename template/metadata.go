@@ -5,24 +5,61 @@ import (
 	"fmt"
 	"io/ioutil"
 	"path/filepath"
+	"strings"
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/flexiant/digger"
+	"github.com/flexiant/kdeploy/models"
 	"github.com/flexiant/kdeploy/utils"
 	"gopkg.in/yaml.v2"
 )
 
+// InstanceAttribute holds, in the attributes templates are rendered with, the name of the instance
+// deployed and the prefix of the names of its resources, e.g. '{{instance.prefix}}redis-master'
+const InstanceAttribute = "instance"
+
 // SingleAttributeMetadata holds metadata for a configuration attribute
 type SingleAttributeMetadata struct {
-	Description string      // Description of the attribute
-	Default     interface{} // Default value for the attribute: could be string, number, or bool
-	Required    bool        // Required or not
+	Description string        // Description of the attribute
+	Default     interface{}   // Default value for the attribute: could be string, number, or bool
+	Required    bool          // Required or not
+	Type        string        // One of string, int, bool, list or map
+	Enum        []interface{} // Allowed values
+	Pattern     string        // Regular expression string values must match
+	Min         *float64      // Lower bound of numbers, or of the length of strings and lists
+	Max         *float64      // Upper bound of numbers, or of the length of strings and lists
+	Format      string        // One of port, dns-label or image
+	nullDefault bool          // Default is explicitly null, which makes the attribute optional
+}
+
+// UnmarshalYAML tells a null default apart from a missing one, since only the former makes an
+// attribute optional in templates
+func (s *SingleAttributeMetadata) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain SingleAttributeMetadata
+	if err := unmarshal((*plain)(s)); err != nil {
+		return err
+	}
+	var keys map[string]interface{}
+	if err := unmarshal(&keys); err != nil {
+		return err
+	}
+	value, ok := keys["default"]
+	s.nullDefault = ok && value == nil
+	return nil
 }
 
 // AttributesMetadata holds a whole collection of MetadataAttribute organized into three levels:
 // <resource-type>/<resource-name>/<attribute-name> (e.g. "svc/frontend/balancer")
 type AttributesMetadata map[string]map[string]map[string]SingleAttributeMetadata
 
+// Dependency is another kubeware that must be deployed before the one depending on it
+type Dependency struct {
+	Name       string                 // Name of the kubeware, as in its metadata
+	Source     string                 // Kubeware path, as given to --kubeware
+	Version    string                 // Version constraint (e.g. '~> 1.2'), any version if empty
+	Attributes map[string]interface{} // Overrides of its attribute defaults
+}
+
 // Metadata holds generic info about the deployment's resources and attributes
 type Metadata struct {
 	Name                   string
@@ -30,29 +67,73 @@ type Metadata struct {
 	Email                  string
 	Description            string
 	Version                string
+	Engine                 string // Template engine, mustache (default) or gotemplate
 	Attributes             AttributesMetadata
-	ReplicationControllers map[string]string `yaml:"rc"`
-	Services               map[string]string `yaml:"svc"`
+	ReplicationControllers map[string]string   `yaml:"rc"`
+	Services               map[string]string   `yaml:"svc"`
+	Deployments            map[string]string   `yaml:"deploy"`
+	ConfigMaps             map[string]string   `yaml:"cm"`
+	Secrets                map[string]string   `yaml:"secret"`
+	Resources              map[string]string   // Manifests of any other kind, by name
+	PartialFiles           map[string]string   `yaml:"partials"` // Files templates can include, by name
+	Digests                map[string]string   // SHA-256 digests of the kubeware files, by path
+	Manifest               string              // File listing the digests, as an alternative to Digests
+	Dependencies           []Dependency        // Kubewares to deploy first
+	Outputs                map[string]Output   // Values read from the deployed resources, by name
+	Order                  map[string]int      // Creation order of resources, by <type>/<name> (e.g. 'rc/redis-master')
+	DependsOn              map[string][]string `yaml:"dependsOn"` // Resources to create, and wait for, before a resource
+	Instance               string              `yaml:"-"`         // Instance deployed, prefixing resource names, if not the default one
 	path                   string
 }
 
 // ParseMetadata parses the metadata file in the kube dir
 func ParseMetadata(path string) Metadata {
-	absPath, err := filepath.Abs(path)
+	metadata, err := ReadMetadata(path)
 	utils.CheckError(err)
+	return metadata
+}
 
-	metadataFile := fmt.Sprintf("%s/metadata.yaml", filepath.Clean(absPath))
-	utils.CheckError(err)
+// ReadMetadata reads the metadata file in the kube dir, returning an error if it can't be parsed
+func ReadMetadata(path string) (Metadata, error) {
+	var metadata Metadata
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return metadata, err
+	}
 
+	metadataFile := fmt.Sprintf("%s/metadata.yaml", filepath.Clean(absPath))
 	metadataContent, err := ioutil.ReadFile(metadataFile)
-	utils.CheckError(err)
+	if err != nil {
+		return metadata, err
+	}
 
-	var metadata Metadata
 	err = yaml.Unmarshal(metadataContent, &metadata)
-	utils.CheckError(err)
+	if err != nil {
+		return metadata, fmt.Errorf("error parsing %s: %v", metadataFile, err)
+	}
 
 	metadata.path = filepath.Dir(metadataFile)
-	return metadata
+	for i, dep := range metadata.Dependencies {
+		attributes, err := normalizeValue(dep.Attributes)
+		if err != nil {
+			return metadata, fmt.Errorf("error parsing %s: attributes of dependency %s: %v", metadataFile, dep.Name, err)
+		}
+		metadata.Dependencies[i].Attributes = attributes.(map[string]interface{})
+	}
+	switch metadata.engine() {
+	case EngineMustache, EngineGoTemplate:
+	default:
+		return metadata, fmt.Errorf("error parsing %s: unknown template engine '%s'", metadataFile, metadata.Engine)
+	}
+	err = metadata.checkOutputs()
+	if err != nil {
+		return metadata, fmt.Errorf("error parsing %s: %v", metadataFile, err)
+	}
+	err = metadata.checkOrdering()
+	if err != nil {
+		return metadata, fmt.Errorf("error parsing %s: %v", metadataFile, err)
+	}
+	return metadata, nil
 }
 
 // RequiredAttributes returns default values for attributes
@@ -70,23 +151,10 @@ func (m Metadata) RequiredAttributes() ([]string, error) {
 	return reqs, nil
 }
 
-// CheckRequiredAttributes returns an error if some required attribute is missing
+// CheckRequiredAttributes returns an error if some required attribute is missing or some
+// attribute doesn't match its metadata, listing every violation found
 func (m Metadata) CheckRequiredAttributes(attributes map[string]interface{}) error {
-	reqs, err := m.RequiredAttributes()
-	if err != nil {
-		return fmt.Errorf("could not calculate required attributes: %v", err)
-	}
-	digger, err := digger.NewMapDigger(attributes)
-	if err != nil {
-		return fmt.Errorf("could not build digger: %v", err)
-	}
-	for _, att := range reqs {
-		_, err := digger.Get(att)
-		if err != nil {
-			return fmt.Errorf("required attribute not present: '%s'", att)
-		}
-	}
-	return nil
+	return m.ValidateAttributes(attributes)
 }
 
 // AttributeDefaults returns default values for attributes
@@ -132,6 +200,10 @@ func (m Metadata) ParseServices(attributes map[string]interface{}) (map[string]s
 	if err != nil {
 		return nil, err
 	}
+	err = setInstanceOnServices(specMap)
+	if err != nil {
+		return nil, err
+	}
 	return marshalMapValues(specMap)
 }
 
@@ -164,11 +236,54 @@ func (m Metadata) ParseControllers(attributes map[string]interface{}) (map[strin
 	return marshalMapValues(specMap)
 }
 
+// ParseDeployments parses the deployments in the kube and returns their JSON representations
+func (m Metadata) ParseDeployments(attributes map[string]interface{}) (map[string]string, error) {
+	err := m.CheckRequiredAttributes(attributes)
+	if err != nil {
+		return nil, err
+	}
+	specMap, err := m.parseTemplates(m.Deployments, attributes)
+	if err != nil {
+		return nil, err
+	}
+	err = setKubeLabelsOnDeployments(specMap)
+	if err != nil {
+		return nil, err
+	}
+	return marshalMapValues(specMap)
+}
+
+// ParseConfigMaps parses the config maps in the kube and returns their JSON representations
+func (m Metadata) ParseConfigMaps(attributes map[string]interface{}) (map[string]string, error) {
+	err := m.CheckRequiredAttributes(attributes)
+	if err != nil {
+		return nil, err
+	}
+	specMap, err := m.parseTemplates(m.ConfigMaps, attributes)
+	if err != nil {
+		return nil, err
+	}
+	return marshalMapValues(specMap)
+}
+
+// ParseSecrets parses the secrets in the kube and returns their JSON representations
+func (m Metadata) ParseSecrets(attributes map[string]interface{}) (map[string]string, error) {
+	err := m.CheckRequiredAttributes(attributes)
+	if err != nil {
+		return nil, err
+	}
+	specMap, err := m.parseTemplates(m.Secrets, attributes)
+	if err != nil {
+		return nil, err
+	}
+	return marshalMapValues(specMap)
+}
+
 func (m Metadata) parseTemplates(templates map[string]string, attributes map[string]interface{}) (map[string]interface{}, error) {
 	var specs = map[string]interface{}{}
 	for specName, templateFile := range templates {
 		log.Debugf("Going to parse %s/%s", m.path, templateFile)
-		specMap, err := parseTemplate(fmt.Sprintf("%s/%s", m.path, templateFile), attributes)
+		specMap, err := m.ParseTemplate(templateFile, attributes)
 		if err != nil {
 			return nil, fmt.Errorf("error parsing template %s: %v", templateFile, err)
 		}
@@ -183,17 +298,26 @@ func (m Metadata) parseTemplates(templates map[string]string, attributes map[str
 		if name != specName {
 			return nil, fmt.Errorf("non matching resource name in %s", templateFile)
 		}
-		err = addKubewareLabel(m.Name, m.Version, specMap)
+		err = addKubewareLabel(m.Name, m.Version, m.Instance, specMap)
 		if err != nil {
 			return nil, fmt.Errorf("error adding kubeware labels to %s: %v", templateFile, err)
 		}
-		specs[specName] = specMap
+		err = m.addDependenciesAnnotation(specMap)
+		if err != nil {
+			return nil, fmt.Errorf("error adding kubeware annotations to %s: %v", templateFile, err)
+		}
+		// resources of an instance are told apart from those of others by their prefix
+		name = m.ResourceName(specName)
+		specMap["metadata"].(map[string]interface{})["name"] = name
+		specs[name] = specMap
 	}
 	return specs, nil
 }
 
-func parseTemplate(templateFile string, attributes map[string]interface{}) (map[string]interface{}, error) {
-	specYaml, err := ResolveTemplate(templateFile, attributes)
+// ParseTemplate resolves a template of the kubeware, given by its path within the kubeware, and
+// parses the resulting YAML
+func (m Metadata) ParseTemplate(templateFile string, attributes map[string]interface{}) (map[string]interface{}, error) {
+	specYaml, err := m.resolve(templateFile, attributes)
 	if err != nil {
 		return nil, fmt.Errorf("error resolving template %s: %v", templateFile, err)
 	}
@@ -209,7 +333,7 @@ func parseTemplate(templateFile string, attributes map[string]interface{}) (map[
 	return normalizedMap.(map[string]interface{}), nil
 }
 
-func addKubewareLabel(name, version string, specmap map[string]interface{}) error {
+func addKubewareLabel(name, version, instance string, specmap map[string]interface{}) error {
 	fixedName, err := utils.NormalizeName(name)
 	if err != nil {
 		return err
@@ -225,7 +349,59 @@ func addKubewareLabel(name, version string, specmap map[string]interface{}) erro
 			"kubeware-version": version,
 		}
 	}
+	if instance != "" {
+		metadata["labels"].(map[string]interface{})[models.InstanceLabel] = instance
+	}
+
+	return nil
+}
+
+// ResourceName returns the name a resource of the kubeware is deployed with, which is prefixed
+// by the instance if not the default one
+func (m Metadata) ResourceName(name string) string {
+	return m.instancePrefix() + name
+}
+
+func (m Metadata) instancePrefix() string {
+	if m.Instance == "" {
+		return ""
+	}
+	return m.Instance + "-"
+}
+
+// withInstance adds the instance the kubeware is deployed as to the attributes templates are
+// rendered with, so that they can refer to the other resources of the kubeware by name
+func (m Metadata) withInstance(attributes map[string]interface{}) map[string]interface{} {
+	values := make(map[string]interface{}, len(attributes)+1)
+	for k, v := range attributes {
+		values[k] = v
+	}
+	values[InstanceAttribute] = map[string]interface{}{
+		"name":   m.Instance,
+		"prefix": m.instancePrefix(),
+	}
+	return values
+}
 
+// addDependenciesAnnotation tells in the resource which kubewares the kubeware depends on, so
+// that they are not deleted while it needs them
+func (m Metadata) addDependenciesAnnotation(specmap map[string]interface{}) error {
+	if len(m.Dependencies) == 0 {
+		return nil
+	}
+	names := []string{}
+	for _, dep := range m.Dependencies {
+		name, err := utils.NormalizeName(dep.Name)
+		if err != nil {
+			return err
+		}
+		names = append(names, name)
+	}
+	metadata := specmap["metadata"].(map[string]interface{})
+	if metadata["annotations"] == nil {
+		metadata["annotations"] = map[string]interface{}{}
+	}
+	metadata["annotations"].(map[string]interface{})[models.DependenciesAnnotation] = strings.Join(names, ",")
 	return nil
 }
 
@@ -297,6 +473,7 @@ func setKubeVersionOnRC(rc map[string]interface{}) error {
 		m = m[s].(map[string]interface{})
 	}
 	m["kubeware"] = kv
+	setInstanceLabel(rc, m)
 	// set at label selector
 	path = []string{"spec", "selector"}
 	m = rc
@@ -307,7 +484,90 @@ func setKubeVersionOnRC(rc map[string]interface{}) error {
 		m = m[s].(map[string]interface{})
 	}
 	m["kubeware"] = kv
+	setInstanceLabel(rc, m)
+
+	return nil
+}
+
+// setInstanceLabel copies the instance label of a resource, if any, to labels of its own such as
+// those of its pods or its selector, so that it doesn't select the pods of another instance
+func setInstanceLabel(resource map[string]interface{}, labels map[string]interface{}) {
+	resourceLabels, _ := resource["metadata"].(map[string]interface{})["labels"].(map[string]interface{})
+	if instance, ok := resourceLabels[models.InstanceLabel]; ok {
+		labels[models.InstanceLabel] = instance
+	}
+}
+
+// setInstanceOnWorkloads labels the pods of the resources that have a pod template with the
+// instance, and adds it to their selector, so that services of the instance select them
+func setInstanceOnWorkloads(resources map[string]interface{}) error {
+	for _, r := range resources {
+		resource := r.(map[string]interface{})
+		labels, _ := resource["metadata"].(map[string]interface{})["labels"].(map[string]interface{})
+		if _, ok := labels[models.InstanceLabel]; !ok {
+			continue
+		}
+		spec, _ := resource["spec"].(map[string]interface{})
+		podTemplate, ok := spec["template"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if podTemplate["metadata"] == nil {
+			podTemplate["metadata"] = map[string]interface{}{}
+		}
+		podMetadata := podTemplate["metadata"].(map[string]interface{})
+		if podMetadata["labels"] == nil {
+			podMetadata["labels"] = map[string]interface{}{}
+		}
+		setInstanceLabel(resource, podMetadata["labels"].(map[string]interface{}))
+		selector, _ := spec["selector"].(map[string]interface{})
+		if matchLabels, ok := selector["matchLabels"].(map[string]interface{}); ok {
+			setInstanceLabel(resource, matchLabels)
+		}
+	}
+	return nil
+}
+
+// setInstanceOnServices adds the instance to the selector of the services, if any
+func setInstanceOnServices(services map[string]interface{}) error {
+	for _, s := range services {
+		svc := s.(map[string]interface{})
+		spec, _ := svc["spec"].(map[string]interface{})
+		if selector, ok := spec["selector"].(map[string]interface{}); ok && len(selector) > 0 {
+			setInstanceLabel(svc, selector)
+		}
+	}
+	return nil
+}
 
+// setKubeLabelsOnDeployments labels the pods of the deployments with the kubeware and its version.
+// Unlike replication controllers, the selector only gets the instance, which never changes, since
+// deployments roll their pods themselves and don't allow changing it
+func setKubeLabelsOnDeployments(deployments map[string]interface{}) error {
+	for _, d := range deployments {
+		deployment := d.(map[string]interface{})
+		labels := deployment["metadata"].(map[string]interface{})["labels"].(map[string]interface{})
+		m := deployment
+		for _, s := range []string{"spec", "template", "metadata", "labels"} {
+			if m[s] == nil {
+				m[s] = map[string]interface{}{}
+			}
+			m = m[s].(map[string]interface{})
+		}
+		m["kubeware"] = labels["kubeware"]
+		m["kubeware-version"] = labels["kubeware-version"]
+		setInstanceLabel(deployment, m)
+		if _, ok := labels[models.InstanceLabel]; ok {
+			m = deployment
+			for _, s := range []string{"spec", "selector", "matchLabels"} {
+				if m[s] == nil {
+					m[s] = map[string]interface{}{}
+				}
+				m = m[s].(map[string]interface{})
+			}
+			setInstanceLabel(deployment, m)
+		}
+	}
 	return nil
 }
 
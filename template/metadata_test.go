@@ -1,8 +1,14 @@
 package template
 
 import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/flexiant/digger"
 	"gopkg.in/yaml.v2"
 )
 
@@ -68,7 +74,11 @@ func TestAttributeDefaults(t *testing.T) {
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-	val, err := defaults.GetString("rc/frontend/name")
+	d, err := digger.NewMapDigger(defaults)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	val, err := d.GetString("rc/frontend/name")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	} else if val != "php-redis" {
@@ -91,3 +101,242 @@ func TestCheckRequiredAttributes(t *testing.T) {
 		t.Fatalf("should have detected missing required attribute: %s", "svc/frontend/port")
 	}
 }
+
+func TestParseDeploymentsAndConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kdeploy-kubeware")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "metadata.yaml"), []byte(`
+name: "Redis Server"
+version: "1.0.0"
+deploy:
+  redis: "redis-deployment.yaml"
+cm:
+  redis-config: "redis-configmap.yaml"
+secret:
+  redis-auth: "redis-secret.yaml"
+attributes:
+  deploy:
+    redis:
+      replicas:
+        default: 3
+`), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "redis-deployment.yaml"), []byte(`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: redis
+spec:
+  replicas: {{deploy.redis.replicas}}
+  selector:
+    matchLabels:
+      app: redis
+  template:
+    metadata:
+      labels:
+        app: redis
+`), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "redis-configmap.yaml"), []byte("kind: ConfigMap\nmetadata:\n  name: redis-config\ndata:\n  maxmemory: 2mb\n"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "redis-secret.yaml"), []byte("kind: Secret\nmetadata:\n  name: redis-auth\ndata:\n  password: c2VjcmV0\n"), 0644)
+
+	md, err := ReadMetadata(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if files := md.TemplateFiles(); len(files) != 3 {
+		t.Errorf("expected 3 template files, got %v", files)
+	}
+	attributes, _ := md.AttributeDefaults()
+
+	deployments, err := md.ParseDeployments(attributes)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var deployment struct {
+		Metadata struct{ Labels map[string]string }
+		Spec     struct {
+			Replicas int
+			Selector struct{ MatchLabels map[string]string }
+			Template struct {
+				Metadata struct{ Labels map[string]string }
+			}
+		}
+	}
+	err = json.Unmarshal([]byte(deployments["redis"]), &deployment)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if deployment.Spec.Replicas != 3 {
+		t.Errorf("expected 3 replicas, got %d", deployment.Spec.Replicas)
+	}
+	if deployment.Metadata.Labels["kubeware"] != "redis-server" || deployment.Spec.Template.Metadata.Labels["kubeware-version"] != "1.0.0" {
+		t.Errorf("deployment and its pods should be labelled with the kubeware, got %v and %v", deployment.Metadata.Labels, deployment.Spec.Template.Metadata.Labels)
+	}
+	if len(deployment.Spec.Selector.MatchLabels) != 1 {
+		t.Errorf("deployment selector should be left untouched, got %v", deployment.Spec.Selector.MatchLabels)
+	}
+
+	for kind, parse := range map[string]func(map[string]interface{}) (map[string]string, error){
+		"cm":     md.ParseConfigMaps,
+		"secret": md.ParseSecrets,
+	} {
+		specs, err := parse(attributes)
+		if err != nil {
+			t.Fatalf("unexpected error parsing %s: %v", kind, err)
+		}
+		if len(specs) != 1 {
+			t.Errorf("expected a single %s, got %d", kind, len(specs))
+		}
+		for _, spec := range specs {
+			if !strings.Contains(spec, `"kubeware":"redis-server"`) {
+				t.Errorf("%s should be labelled with the kubeware: %s", kind, spec)
+			}
+		}
+	}
+}
+
+func TestDependencies(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kdeploy-kubeware")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "metadata.yaml"), []byte(`
+name: "Guestbook"
+version: "1.0.0"
+svc:
+  frontend: "frontend-service.yaml"
+dependencies:
+- name: "Redis Server"
+  source: "https://github.com/flexiant/kubeware-redis"
+  version: "~> 1.0"
+  attributes:
+    rc:
+      redis-slave:
+        number: 2
+- name: volumes
+  source: "stable/volumes"
+`), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "frontend-service.yaml"), []byte("kind: Service\nmetadata:\n  name: frontend\n"), 0644)
+
+	md, err := ReadMetadata(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(md.Dependencies) != 2 || md.Dependencies[0].Version != "~> 1.0" || md.Dependencies[1].Source != "stable/volumes" {
+		t.Fatalf("unexpected dependencies %+v", md.Dependencies)
+	}
+	// overrides are read as attribute files are
+	rcs := md.Dependencies[0].Attributes["rc"].(map[string]interface{})
+	if rcs["redis-slave"].(map[string]interface{})["number"] != 2 {
+		t.Errorf("unexpected overrides %#v", md.Dependencies[0].Attributes)
+	}
+
+	services, err := md.ParseServices(map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(services["frontend"], `"annotations":{"kubeware-dependencies":"redis-server,volumes"}`) {
+		t.Errorf("service should be annotated with the dependencies: %s", services["frontend"])
+	}
+}
+
+func TestInstance(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kdeploy-kubeware")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "metadata.yaml"), []byte(`
+name: "Guestbook"
+version: "1.0.0"
+rc:
+  frontend: "frontend-controller.yaml"
+svc:
+  frontend: "frontend-service.yaml"
+deploy:
+  redis: "redis-deployment.yaml"
+`), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "frontend-controller.yaml"), []byte(`
+kind: ReplicationController
+metadata:
+  name: frontend
+spec:
+  selector:
+    app: frontend
+  template:
+    metadata:
+      labels:
+        app: frontend
+    spec:
+      containers:
+      - name: php-redis
+        env:
+        - name: REDIS_HOST
+          value: "{{instance.prefix}}redis"
+`), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "frontend-service.yaml"), []byte("kind: Service\nmetadata:\n  name: frontend\nspec:\n  selector:\n    app: frontend\n"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "redis-deployment.yaml"), []byte("apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: redis\nspec:\n  selector:\n    matchLabels:\n      app: redis\n"), 0644)
+
+	md, err := ReadMetadata(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := md.CheckTemplates(map[string]interface{}{}); err != nil {
+		t.Errorf("the instance should be known to templates, got %v", err)
+	}
+	md.Instance = "blue"
+
+	controllers, err := md.ParseControllers(map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rc, ok := controllers["blue-frontend"]
+	if !ok {
+		t.Fatalf("expected the controller to be named after the instance, got %v", controllers)
+	}
+	d, _ := digger.NewJSONDigger([]byte(rc))
+	for _, path := range []string{"metadata/labels/kubeware-instance", "spec/selector/kubeware-instance", "spec/template/metadata/labels/kubeware-instance"} {
+		if instance, _ := d.GetString(path); instance != "blue" {
+			t.Errorf("expected %s to be blue in %s", path, rc)
+		}
+	}
+	if name, _ := d.GetString("metadata/name"); name != "blue-frontend" {
+		t.Errorf("expected the controller to be named blue-frontend, got %s", name)
+	}
+	if !strings.Contains(rc, `"value":"blue-redis"`) {
+		t.Errorf("expected the instance prefix to be rendered: %s", rc)
+	}
+
+	services, err := md.ParseServices(map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	d, _ = digger.NewJSONDigger([]byte(services["blue-frontend"]))
+	if instance, _ := d.GetString("spec/selector/kubeware-instance"); instance != "blue" {
+		t.Errorf("expected the service to select the pods of the instance: %s", services["blue-frontend"])
+	}
+
+	deployments, err := md.ParseDeployments(map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	d, _ = digger.NewJSONDigger([]byte(deployments["blue-redis"]))
+	for _, path := range []string{"spec/selector/matchLabels/kubeware-instance", "spec/template/metadata/labels/kubeware-instance"} {
+		if instance, _ := d.GetString(path); instance != "blue" {
+			t.Errorf("expected %s to be blue in %s", path, deployments["blue-redis"])
+		}
+	}
+
+	// the default instance keeps the names and has no instance label
+	md.Instance = ""
+	controllers, err = md.ParseControllers(map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rc, ok := controllers["frontend"]; !ok || strings.Contains(rc, "kubeware-instance") || !strings.Contains(rc, `"value":"redis"`) {
+		t.Errorf("unexpected controllers for the default instance %v", controllers)
+	}
+}
@@ -0,0 +1,178 @@
+package template
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// sectionRanks tells which resources are created first when their order is the same: config
+// maps and secrets, which pods may refer to, then resources of other kinds, such as volume
+// claims pods may mount, services, controllers and deployments
+var sectionRanks = map[string]int{
+	"cm":        0,
+	"secret":    1,
+	"resources": 2,
+	"svc":       3,
+	"rc":        4,
+	"deploy":    5,
+}
+
+// ResourceID identifies a resource of the kubeware within the metadata, as <section>/<name>
+// (e.g. 'rc/redis-master')
+func ResourceID(section, name string) string {
+	return fmt.Sprintf("%s/%s", section, name)
+}
+
+// SplitResourceID returns the section and the name of a resource identified by id
+func SplitResourceID(id string) (string, string) {
+	parts := strings.SplitN(id, "/", 2)
+	if len(parts) != 2 {
+		return id, ""
+	}
+	return parts[0], parts[1]
+}
+
+// sectionTemplates returns the templates of a section of the metadata, by resource name
+func (m Metadata) sectionTemplates(section string) (map[string]string, bool) {
+	switch section {
+	case "rc":
+		return m.ReplicationControllers, true
+	case "svc":
+		return m.Services, true
+	case "deploy":
+		return m.Deployments, true
+	case "cm":
+		return m.ConfigMaps, true
+	case "secret":
+		return m.Secrets, true
+	case "resources":
+		return m.Resources, true
+	}
+	return nil, false
+}
+
+// resourceIDs returns the ids of every resource of the kubeware, sorted
+func (m Metadata) resourceIDs() []string {
+	ids := []string{}
+	for section := range sectionRanks {
+		templates, _ := m.sectionTemplates(section)
+		for name := range templates {
+			ids = append(ids, ResourceID(section, name))
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// checkResourceID checks id refers to a resource of the kubeware
+func (m Metadata) checkResourceID(id string) error {
+	section, name := SplitResourceID(id)
+	templates, ok := m.sectionTemplates(section)
+	if !ok || name == "" {
+		return fmt.Errorf("'%s' must be <type>/<name>, with type one of rc, svc, deploy, cm, secret or resources", id)
+	}
+	if _, ok := templates[name]; !ok {
+		return fmt.Errorf("%s '%s' is not a resource of the kubeware", section, name)
+	}
+	return nil
+}
+
+// creationLess tells if the resource identified by a is created before b when nothing else
+// decides it: lower order first, then by section and by name
+func (m Metadata) creationLess(a, b string) bool {
+	if m.Order[a] != m.Order[b] {
+		return m.Order[a] < m.Order[b]
+	}
+	sa, na := SplitResourceID(a)
+	sb, nb := SplitResourceID(b)
+	if sectionRanks[sa] != sectionRanks[sb] {
+		return sectionRanks[sa] < sectionRanks[sb]
+	}
+	return na < nb
+}
+
+// CreationOrder returns the ids of the resources of the kubeware in the order they have to be
+// created, which is that of their order, if any, with every resource after those it depends on
+func (m Metadata) CreationOrder() ([]string, error) {
+	ids := m.resourceIDs()
+	pending := map[string]int{}
+	dependents := map[string][]string{}
+	for _, id := range ids {
+		pending[id] = 0
+	}
+	for _, id := range ids {
+		for _, dep := range m.DependsOn[id] {
+			if m.Order[dep] > m.Order[id] {
+				return nil, fmt.Errorf("%s depends on %s, which has a later order", id, dep)
+			}
+			pending[id]++
+			dependents[dep] = append(dependents[dep], id)
+		}
+	}
+
+	ordered := []string{}
+	ready := []string{}
+	for _, id := range ids {
+		if pending[id] == 0 {
+			ready = append(ready, id)
+		}
+	}
+	for len(ready) > 0 {
+		sort.Slice(ready, func(i, j int) bool { return m.creationLess(ready[i], ready[j]) })
+		id := ready[0]
+		ready = ready[1:]
+		ordered = append(ordered, id)
+		for _, dependent := range dependents[id] {
+			pending[dependent]--
+			if pending[dependent] == 0 {
+				ready = append(ready, dependent)
+			}
+		}
+	}
+	if len(ordered) < len(ids) {
+		cycle := []string{}
+		for _, id := range ids {
+			if pending[id] > 0 {
+				cycle = append(cycle, id)
+			}
+		}
+		return nil, fmt.Errorf("resources depend on each other: %s", strings.Join(cycle, ", "))
+	}
+	return ordered, nil
+}
+
+// checkOrdering checks order and dependsOn refer to resources of the kubeware and that the
+// resources can be created in an order satisfying both
+func (m Metadata) checkOrdering() error {
+	ids := []string{}
+	for id := range m.Order {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		if err := m.checkResourceID(id); err != nil {
+			return fmt.Errorf("order: %v", err)
+		}
+	}
+	ids = []string{}
+	for id := range m.DependsOn {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		if err := m.checkResourceID(id); err != nil {
+			return fmt.Errorf("dependsOn: %v", err)
+		}
+		for _, dep := range m.DependsOn[id] {
+			if err := m.checkResourceID(dep); err != nil {
+				return fmt.Errorf("dependsOn %s: %v", id, err)
+			}
+			if dep == id {
+				return fmt.Errorf("dependsOn: %s depends on itself", id)
+			}
+		}
+	}
+	_, err := m.CreationOrder()
+	return err
+}
@@ -0,0 +1,76 @@
+package template
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCreationOrder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kdeploy-kubeware")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	metadata := `
+name: "Guestbook"
+version: "1.0.0"
+rc:
+  frontend: "frontend-controller.yaml"
+  redis-master: "redis-master-controller.yaml"
+  redis-slave: "redis-slave-controller.yaml"
+svc:
+  frontend: "frontend-service.yaml"
+  redis-master: "redis-master-service.yaml"
+cm:
+  settings: "settings-configmap.yaml"
+order:
+  rc/redis-master: -1
+dependsOn:
+  rc/redis-slave: [rc/redis-master, svc/redis-master]
+`
+	ioutil.WriteFile(filepath.Join(dir, "metadata.yaml"), []byte(metadata), 0644)
+
+	md, err := ReadMetadata(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	order, err := md.CreationOrder()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "rc/redis-master,cm/settings,svc/frontend,svc/redis-master,rc/frontend,rc/redis-slave"
+	if strings.Join(order, ",") != expected {
+		t.Errorf("expected order %s, got %s", expected, strings.Join(order, ","))
+	}
+
+	// without order nor dependencies, resources are created by type
+	md.Order = nil
+	md.DependsOn = map[string][]string{"svc/frontend": {"rc/frontend"}}
+	order, err = md.CreationOrder()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected = "cm/settings,svc/redis-master,rc/frontend,svc/frontend,rc/redis-master,rc/redis-slave"
+	if strings.Join(order, ",") != expected {
+		t.Errorf("expected order %s, got %s", expected, strings.Join(order, ","))
+	}
+
+	for ordering, msg := range map[string]string{
+		"order:\n  rc/backend: 1\n":                                                  "order: rc 'backend' is not a resource of the kubeware",
+		"order:\n  redis-master: 1\n":                                                "order: 'redis-master' must be <type>/<name>",
+		"dependsOn:\n  rc/frontend: [svc/backend]\n":                                 "dependsOn rc/frontend: svc 'backend' is not a resource of the kubeware",
+		"dependsOn:\n  rc/frontend: [rc/frontend]\n":                                 "dependsOn: rc/frontend depends on itself",
+		"order:\n  rc/frontend: 1\ndependsOn:\n  cm/settings: [rc/frontend]\n":       "cm/settings depends on rc/frontend, which has a later order",
+		"dependsOn:\n  rc/frontend: [svc/frontend]\n  svc/frontend: [rc/frontend]\n": "resources depend on each other: rc/frontend, svc/frontend",
+	} {
+		content := metadata[:strings.Index(metadata, "order:")] + ordering
+		ioutil.WriteFile(filepath.Join(dir, "metadata.yaml"), []byte(content), 0644)
+		_, err := ReadMetadata(dir)
+		if err == nil || !strings.Contains(err.Error(), msg) {
+			t.Errorf("expected an error about %s, got %v", msg, err)
+		}
+	}
+}
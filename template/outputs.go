@@ -0,0 +1,115 @@
+package template
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/flexiant/kdeploy/models"
+)
+
+// OutputsAttribute is the resource type under which a kubeware gets the outputs of the kubewares
+// it depends on as attributes, e.g. 'outputs/redis/host'
+const OutputsAttribute = "outputs"
+
+// SourceOutputs is the source of the attributes holding the outputs of dependencies
+const SourceOutputs = "outputs"
+
+// Output is a value read from a deployed resource of the kubeware
+type Output struct {
+	Description string
+	From        string // <type>/<name>/<field>, e.g. 'svc/frontend/clusterIP' or 'rc/frontend/spec/replicas'
+}
+
+// OutputSource is the deployed resource an output is read from
+type OutputSource struct {
+	Section string      // Section of the metadata holding the resource: rc, svc, deploy, cm or resources
+	Kind    models.Kind // Kind of the resource
+	Name    string      // Name of the resource, as deployed
+	Field   string      // Path of the value within the resource, or a shortcut such as 'clusterIP'
+}
+
+// OutputNames returns the names of the outputs of the kubeware, sorted
+func (m Metadata) OutputNames() []string {
+	names := []string{}
+	for name := range m.Outputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// OutputSource tells where the value of an output is read from
+func (m Metadata) OutputSource(name string) (OutputSource, error) {
+	output, ok := m.Outputs[name]
+	if !ok {
+		return OutputSource{}, fmt.Errorf("unknown output '%s'", name)
+	}
+	source, err := m.parseOutputFrom(output.From)
+	if err != nil {
+		return OutputSource{}, fmt.Errorf("output %s: %v", name, err)
+	}
+	// deployments and other resources are read as the kind their templates tell
+	if source.Section == "deploy" || source.Section == "resources" {
+		templates, _ := m.sectionTemplates(source.Section)
+		source.Kind, err = m.resourceKind(source.Name, templates[source.Name])
+		if err != nil {
+			return OutputSource{}, fmt.Errorf("output %s: %v", name, err)
+		}
+	}
+	source.Name = m.ResourceName(source.Name)
+	return source, nil
+}
+
+// parseOutputFrom splits the from of an output, checking it refers to a resource of the kubeware
+func (m Metadata) parseOutputFrom(from string) (OutputSource, error) {
+	parts := strings.SplitN(from, "/", 3)
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return OutputSource{}, fmt.Errorf("from must be <type>/<name>/<field>, got '%s'", from)
+	}
+	source := OutputSource{Section: parts[0], Name: parts[1], Field: parts[2]}
+	var templates map[string]string
+	switch source.Section {
+	case "rc":
+		templates = m.ReplicationControllers
+	case "svc":
+		templates = m.Services
+	case "deploy":
+		templates = m.Deployments
+	case "cm":
+		templates = m.ConfigMaps
+	case "resources":
+		templates = m.Resources
+	default:
+		return OutputSource{}, fmt.Errorf("outputs can be read from rc, svc, deploy, cm or resources, got '%s'", source.Section)
+	}
+	if _, ok := templates[source.Name]; !ok {
+		return OutputSource{}, fmt.Errorf("%s '%s' is not a resource of the kubeware", source.Section, source.Name)
+	}
+	source.Kind = sectionKinds[source.Section]
+	return source, nil
+}
+
+// checkOutputs checks the from of every output
+func (m Metadata) checkOutputs() error {
+	for _, name := range m.OutputNames() {
+		if _, err := m.parseOutputFrom(m.Outputs[name].From); err != nil {
+			return fmt.Errorf("output %s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// outputsLayer nests the outputs of the dependencies of a kubeware, by kubeware name, under
+// their attribute type
+func outputsLayer(outputs map[string]map[string]interface{}) map[string]interface{} {
+	layer := map[string]interface{}{}
+	for kubeware, values := range outputs {
+		m := map[string]interface{}{}
+		for name, value := range values {
+			m[name] = value
+		}
+		layer[kubeware] = m
+	}
+	return map[string]interface{}{OutputsAttribute: layer}
+}
@@ -0,0 +1,90 @@
+package template
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/flexiant/kdeploy/models"
+)
+
+func TestOutputs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kdeploy-kubeware")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	metadata := `
+name: "Guestbook"
+version: "1.0.0"
+svc:
+  frontend: "frontend-service.yaml"
+deploy:
+  frontend: "frontend-deployment.yaml"
+resources:
+  web: "web-ingress.yaml"
+outputs:
+  host:
+    description: "Address of the frontend within the cluster"
+    from: svc/frontend/clusterIP
+  address:
+    from: resources/web/status/loadBalancer
+  replicas:
+    from: deploy/frontend/spec/replicas
+`
+	ioutil.WriteFile(filepath.Join(dir, "metadata.yaml"), []byte(metadata), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "frontend-deployment.yaml"), []byte("apiVersion: extensions/v1beta1\nkind: Deployment\n"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "web-ingress.yaml"), []byte("apiVersion: networking.k8s.io/v1\nkind: Ingress\n"), 0644)
+
+	md, err := ReadMetadata(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if names := md.OutputNames(); strings.Join(names, ",") != "address,host,replicas" {
+		t.Errorf("unexpected outputs %v", names)
+	}
+	source, err := md.OutputSource("host")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := OutputSource{"svc", models.Kind{APIVersion: "v1", Kind: "Service"}, "frontend", "clusterIP"}
+	if source != expected {
+		t.Errorf("expected source %+v, got %+v", expected, source)
+	}
+	source, err = md.OutputSource("address")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if source.Kind.Kind != "Ingress" || source.Field != "status/loadBalancer" {
+		t.Errorf("unexpected source %+v", source)
+	}
+	// deployments are read as the kind their template tells
+	source, err = md.OutputSource("replicas")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if source.Kind != (models.Kind{APIVersion: "extensions/v1beta1", Kind: "Deployment"}) {
+		t.Errorf("expected the output to be read from an extensions/v1beta1 deployment, got %s", source.Kind)
+	}
+
+	// instances read the outputs from their own resources
+	md.Instance = "blue"
+	if source, _ = md.OutputSource("host"); source.Name != "blue-frontend" {
+		t.Errorf("expected the output to be read from blue-frontend, got %s", source.Name)
+	}
+
+	for from, msg := range map[string]string{
+		"svc/frontend":           "from must be <type>/<name>/<field>",
+		"secret/db/data":         "outputs can be read from rc, svc, deploy, cm or resources",
+		"svc/backend/clusterIP":  "svc 'backend' is not a resource of the kubeware",
+		"rc/frontend/spec/image": "rc 'frontend' is not a resource of the kubeware",
+	} {
+		ioutil.WriteFile(filepath.Join(dir, "metadata.yaml"), []byte(strings.Replace(metadata, "svc/frontend/clusterIP", from, 1)), 0644)
+		_, err := ReadMetadata(dir)
+		if err == nil || !strings.Contains(err.Error(), "output host: "+msg) {
+			t.Errorf("expected an error about %s for %s, got %v", msg, from, err)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package template
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// PartialsDir is the directory of the kubeware whose files can be included from any template, by
+// their path within it without extension (e.g. 'probes/http' for 'partials/probes/http.yaml')
+const PartialsDir = "partials"
+
+// partialTag matches '{{> name}}' tags, which include a partial in any engine
+var partialTag = regexp.MustCompile(`\{\{>\s*([^{}\s]+)\s*\}\}`)
+
+// sourceLine tells where a line of an expanded template comes from
+type sourceLine struct {
+	File string
+	Line int
+}
+
+// expandedTemplate is a template with its partials included
+type expandedTemplate struct {
+	content string
+	lines   []sourceLine
+}
+
+// source returns where a line of the expanded template comes from
+func (e *expandedTemplate) source(line int) sourceLine {
+	if line < 1 || line > len(e.lines) {
+		return sourceLine{Line: line}
+	}
+	return e.lines[line-1]
+}
+
+// Partials returns the files of the partials the kubeware templates can include, by name. Those
+// in the partials section of the metadata take precedence over those in the partials directory
+func (m Metadata) Partials() (map[string]string, error) {
+	partials := map[string]string{}
+	dir := filepath.Join(m.path, PartialsDir)
+	if info, err := os.Stat(dir); err == nil && info.IsDir() {
+		err := filepath.Walk(dir, func(file string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() {
+				return err
+			}
+			rel, err := filepath.Rel(m.path, file)
+			if err != nil {
+				return err
+			}
+			rel = filepath.ToSlash(rel)
+			name := strings.TrimPrefix(rel, PartialsDir+"/")
+			partials[strings.TrimSuffix(name, path.Ext(name))] = rel
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	for name, file := range m.PartialFiles {
+		partials[name] = file
+	}
+	return partials, nil
+}
+
+// expand includes the partials referenced by a template, recursively. Standalone partial tags
+// have every included line indented as the tag is, so that YAML blocks can be shared
+func (m Metadata) expand(file string) (*expandedTemplate, error) {
+	partials, err := m.Partials()
+	if err != nil {
+		return nil, err
+	}
+	e := &expandedTemplate{}
+	lines, trailingNewline, err := m.expandFile(file, partials, []string{})
+	if err != nil {
+		return nil, err
+	}
+	texts := make([]string, len(lines))
+	for i, l := range lines {
+		texts[i] = l.text
+		e.lines = append(e.lines, l.source)
+	}
+	e.content = strings.Join(texts, "\n")
+	if trailingNewline {
+		e.content += "\n"
+	}
+	return e, nil
+}
+
+type expandedLine struct {
+	text   string
+	source sourceLine
+}
+
+func (m Metadata) expandFile(file string, partials map[string]string, including []string) ([]expandedLine, bool, error) {
+	content, err := ioutil.ReadFile(filepath.Join(m.path, filepath.FromSlash(file)))
+	if err != nil {
+		return nil, false, err
+	}
+	text := string(content)
+	trailingNewline := strings.HasSuffix(text, "\n")
+	text = strings.TrimSuffix(text, "\n")
+
+	lines := []expandedLine{}
+	for i, line := range strings.Split(text, "\n") {
+		source := sourceLine{File: file, Line: i + 1}
+		tags := partialTag.FindAllStringSubmatchIndex(line, -1)
+		if len(tags) == 0 {
+			lines = append(lines, expandedLine{line, source})
+			continue
+		}
+
+		// a tag alone in its line indents the partial as the tag is
+		if len(tags) == 1 && strings.TrimSpace(line[:tags[0][0]]) == "" && strings.TrimSpace(line[tags[0][1]:]) == "" {
+			included, err := m.include(line[tags[0][2]:tags[0][3]], source, partials, including)
+			if err != nil {
+				return nil, false, err
+			}
+			indentation := line[:tags[0][0]]
+			for _, l := range included {
+				if l.text != "" {
+					l.text = indentation + l.text
+				}
+				lines = append(lines, l)
+			}
+			continue
+		}
+
+		// otherwise the partial is inserted right where the tag is
+		current := expandedLine{source: source}
+		last := 0
+		for _, tag := range tags {
+			current.text += line[last:tag[0]]
+			included, err := m.include(line[tag[2]:tag[3]], source, partials, including)
+			if err != nil {
+				return nil, false, err
+			}
+			for j, l := range included {
+				if j == 0 {
+					current.text += l.text
+					continue
+				}
+				lines = append(lines, current)
+				current = l
+			}
+			last = tag[1]
+		}
+		current.text += line[last:]
+		lines = append(lines, current)
+	}
+	return lines, trailingNewline, nil
+}
+
+// include expands a partial, refusing partials that end up including themselves
+func (m Metadata) include(name string, from sourceLine, partials map[string]string, including []string) ([]expandedLine, error) {
+	file, ok := partials[name]
+	if !ok {
+		file, ok = m.siblingPartial(name, from.File)
+	}
+	if !ok {
+		return nil, fmt.Errorf("%s:%d: unknown partial '%s'", from.File, from.Line, name)
+	}
+	for i, n := range including {
+		if n == name {
+			cycle := append(append([]string{}, including[i:]...), name)
+			return nil, fmt.Errorf("%s:%d: partials include each other: %s", from.File, from.Line, strings.Join(cycle, " -> "))
+		}
+	}
+	lines, _, err := m.expandFile(file, partials, append(including, name))
+	return lines, err
+}
+
+// siblingExtensions are tried, in order, for partials found next to the template including them
+var siblingExtensions = []string{"", ".mustache", ".stache"}
+
+// siblingPartial finds a partial not declared by the kubeware next to the file including it, as
+// mustache did when templates were rendered from their files
+func (m Metadata) siblingPartial(name, including string) (string, bool) {
+	for _, ext := range siblingExtensions {
+		file := path.Join(path.Dir(including), name+ext)
+		if file == ".." || strings.HasPrefix(file, "../") {
+			return "", false
+		}
+		info, err := os.Stat(filepath.Join(m.path, filepath.FromSlash(file)))
+		if err == nil && !info.IsDir() {
+			return file, true
+		}
+	}
+	return "", false
+}
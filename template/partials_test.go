@@ -0,0 +1,127 @@
+package template
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPartials(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kdeploy-kubeware")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	os.MkdirAll(filepath.Join(dir, "partials", "probes"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "metadata.yaml"), []byte(`
+name: "Guestbook"
+version: "0.0.1"
+rc:
+  frontend: "frontend-controller.yaml"
+partials:
+  limits: "shared/limits.yaml"
+attributes:
+  rc:
+    frontend:
+      port:
+        default: 80
+      memory:
+        default: 128Mi
+`), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "frontend-controller.yaml"), []byte(`kind: ReplicationController
+metadata:
+  name: frontend
+spec:
+  template:
+    spec:
+      containers:
+      - name: {{> name}}
+        {{> probes/http}}
+        {{> limits}}
+`), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "partials", "name.mustache"), []byte("php-redis\n"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "partials", "probes", "http.yaml"), []byte(`livenessProbe:
+  httpGet:
+    path: /healthz
+    port: {{rc.frontend.port}}
+`), 0644)
+	os.MkdirAll(filepath.Join(dir, "shared"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "shared", "limits.yaml"), []byte(`resources:
+  limits:
+    memory: "{{rc.frontend.memroy}}"
+`), 0644)
+
+	md, err := ReadMetadata(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	files := md.TemplateFiles()
+	if strings.Join(files, ",") != "frontend-controller.yaml,partials/name.mustache,partials/probes/http.yaml,shared/limits.yaml" {
+		t.Errorf("expected partials among the template files, got %v", files)
+	}
+	if resources := md.ResourceTemplateFiles(); len(resources) != 1 {
+		t.Errorf("expected partials not to be resource templates, got %v", resources)
+	}
+
+	attributes, _ := BuildAttributes(nil, nil, md)
+	controllers, err := md.ParseControllers(attributes)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var rc struct {
+		Spec struct {
+			Template struct {
+				Spec struct {
+					Containers []struct {
+						Name          string
+						LivenessProbe struct {
+							HTTPGet struct {
+								Path string
+								Port int
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	err = json.Unmarshal([]byte(controllers["frontend"]), &rc)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	container := rc.Spec.Template.Spec.Containers[0]
+	if container.Name != "php-redis" || container.LivenessProbe.HTTPGet.Port != 80 || container.LivenessProbe.HTTPGet.Path != "/healthz" {
+		t.Errorf("unexpected rendering: %s", controllers["frontend"])
+	}
+
+	// undefined variables are told in the partial they are in
+	err = md.CheckTemplates(attributes)
+	undefined, ok := err.(UndefinedVariables)
+	if !ok || len(undefined) != 1 || undefined[0] != (UndefinedVariable{"shared/limits.yaml", 3, "rc.frontend.memroy"}) {
+		t.Errorf("expected undefined variable in shared/limits.yaml:3, got %v", err)
+	}
+
+	// partials including each other are refused
+	ioutil.WriteFile(filepath.Join(dir, "shared", "limits.yaml"), []byte("{{> probes/http}}\n"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "partials", "probes", "http.yaml"), []byte("{{> limits}}\n"), 0644)
+	_, err = md.ParseControllers(attributes)
+	if err == nil || !strings.Contains(err.Error(), "probes/http -> limits -> probes/http") {
+		t.Errorf("expected a cycle to be detected, got %v", err)
+	}
+
+	// partials not declared are looked for next to the template including them
+	ioutil.WriteFile(filepath.Join(dir, "frontend-controller.yaml"), []byte("kind: {{> kind}}\nmetadata:\n  name: frontend\n"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "kind.mustache"), []byte("ReplicationController\n"), 0644)
+	controllers, err = md.ParseControllers(attributes)
+	if err != nil || !strings.Contains(controllers["frontend"], `"kind":"ReplicationController"`) {
+		t.Errorf("expected the sibling partial to be included, got %v %v", controllers["frontend"], err)
+	}
+
+	ioutil.WriteFile(filepath.Join(dir, "frontend-controller.yaml"), []byte("{{> missing}}\n"), 0644)
+	if _, err = md.ParseControllers(attributes); err == nil || !strings.Contains(err.Error(), "unknown partial 'missing'") {
+		t.Errorf("expected an unknown partial to be refused, got %v", err)
+	}
+}
@@ -0,0 +1,128 @@
+package template
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+
+	"github.com/flexiant/kdeploy/models"
+)
+
+// kindLine matches the top level apiVersion and kind of a manifest, which are expected to be
+// literal rather than templated
+var kindLine = regexp.MustCompile(`(?m)^(apiVersion|kind):[ \t]*["']?([^"'\s#]+)`)
+
+// ParseResources parses the manifests in the resources section of the kube and returns their
+// JSON representations. Each of them must tell its apiVersion and kind
+func (m Metadata) ParseResources(attributes map[string]interface{}) (map[string]string, error) {
+	err := m.CheckRequiredAttributes(attributes)
+	if err != nil {
+		return nil, err
+	}
+	specMap, err := m.parseTemplates(m.Resources, attributes)
+	if err != nil {
+		return nil, err
+	}
+	for name, spec := range specMap {
+		if _, err := KindOf(spec.(map[string]interface{})); err != nil {
+			return nil, fmt.Errorf("resource %s: %v", name, err)
+		}
+	}
+	err = setInstanceOnWorkloads(specMap)
+	if err != nil {
+		return nil, err
+	}
+	return marshalMapValues(specMap)
+}
+
+// KindOf returns the kind of a parsed manifest
+func KindOf(spec map[string]interface{}) (models.Kind, error) {
+	apiVersion, _ := spec["apiVersion"].(string)
+	kind, _ := spec["kind"].(string)
+	if apiVersion == "" || kind == "" {
+		return models.Kind{}, fmt.Errorf("manifest must have an apiVersion and a kind")
+	}
+	return models.Kind{APIVersion: apiVersion, Kind: kind}, nil
+}
+
+// ResourceKinds returns the kinds of the manifests in the resources section, read from the
+// templates without rendering them so that no attributes are needed
+func (m Metadata) ResourceKinds() ([]models.Kind, error) {
+	seen := map[models.Kind]bool{}
+	kinds := []models.Kind{}
+	for name, file := range m.Resources {
+		kind, err := m.resourceKind(name, file)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[kind] {
+			seen[kind] = true
+			kinds = append(kinds, kind)
+		}
+	}
+	sort.Sort(byKind(kinds))
+	return kinds, nil
+}
+
+// sectionKinds are the kinds of the sections holding resources of a single, fixed kind
+var sectionKinds = map[string]models.Kind{
+	"rc":     {APIVersion: "v1", Kind: "ReplicationController"},
+	"svc":    {APIVersion: "v1", Kind: "Service"},
+	"cm":     {APIVersion: "v1", Kind: "ConfigMap"},
+	"secret": {APIVersion: "v1", Kind: "Secret"},
+}
+
+// Kinds returns the kinds of every resource the kubeware declares. Deployments and the manifests
+// in the resources section are of the kind their templates tell
+func (m Metadata) Kinds() ([]models.Kind, error) {
+	seen := map[models.Kind]bool{}
+	kinds := []models.Kind{}
+	for section := range sectionRanks {
+		templates, _ := m.sectionTemplates(section)
+		for name, file := range templates {
+			kind, fixed := sectionKinds[section]
+			if !fixed {
+				var err error
+				kind, err = m.resourceKind(name, file)
+				if err != nil {
+					return nil, err
+				}
+			}
+			if !seen[kind] {
+				seen[kind] = true
+				kinds = append(kinds, kind)
+			}
+		}
+	}
+	sort.Sort(byKind(kinds))
+	return kinds, nil
+}
+
+// resourceKind reads the kind of a manifest in the resources or deploy section from its template
+func (m Metadata) resourceKind(name, file string) (models.Kind, error) {
+	data, err := ioutil.ReadFile(filepath.Join(m.path, file))
+	if err != nil {
+		return models.Kind{}, err
+	}
+	var kind models.Kind
+	for _, match := range kindLine.FindAllStringSubmatch(string(data), -1) {
+		if match[1] == "apiVersion" && kind.APIVersion == "" {
+			kind.APIVersion = match[2]
+		}
+		if match[1] == "kind" && kind.Kind == "" {
+			kind.Kind = match[2]
+		}
+	}
+	if kind.APIVersion == "" || kind.Kind == "" {
+		return models.Kind{}, fmt.Errorf("resource %s: manifest must have an apiVersion and a kind", name)
+	}
+	return kind, nil
+}
+
+type byKind []models.Kind
+
+func (k byKind) Len() int           { return len(k) }
+func (k byKind) Swap(i, j int)      { k[i], k[j] = k[j], k[i] }
+func (k byKind) Less(i, j int) bool { return k[i].String() < k[j].String() }
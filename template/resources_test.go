@@ -0,0 +1,124 @@
+package template
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/flexiant/kdeploy/models"
+)
+
+func TestParseResources(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kdeploy-kubeware")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "metadata.yaml"), []byte(`
+name: "Redis Server"
+version: "1.0.0"
+resources:
+  redis-data: "redis-pvc.yaml"
+  redis-ingress: "redis-ingress.yaml"
+  redis-claim: "redis-claim.yaml"
+attributes:
+  resources:
+    redis-data:
+      size:
+        default: 1Gi
+`), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "redis-pvc.yaml"), []byte(`
+apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  name: redis-data
+spec:
+  resources:
+    requests:
+      storage: {{resources.redis-data.size}}
+`), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "redis-ingress.yaml"), []byte("apiVersion: \"networking.k8s.io/v1\"\nkind: Ingress # exposes redis\nmetadata:\n  name: redis-ingress\n"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "redis-claim.yaml"), []byte("kind: PersistentVolumeClaim\napiVersion: v1\nmetadata:\n  name: redis-claim\n"), 0644)
+
+	md, err := ReadMetadata(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	kinds, err := md.ResourceKinds()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []models.Kind{{APIVersion: "networking.k8s.io/v1", Kind: "Ingress"}, {APIVersion: "v1", Kind: "PersistentVolumeClaim"}}
+	if len(kinds) != len(expected) || kinds[0] != expected[0] || kinds[1] != expected[1] {
+		t.Errorf("expected kinds %v, got %v", expected, kinds)
+	}
+
+	attributes, _ := md.AttributeDefaults()
+	specs, err := md.ParseResources(attributes)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var pvc struct {
+		Metadata struct{ Labels map[string]string }
+		Spec     struct {
+			Resources struct{ Requests map[string]string }
+		}
+	}
+	err = json.Unmarshal([]byte(specs["redis-data"]), &pvc)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pvc.Spec.Resources.Requests["storage"] != "1Gi" {
+		t.Errorf("expected attributes to be rendered, got %s", specs["redis-data"])
+	}
+	if pvc.Metadata.Labels["kubeware"] != "redis-server" {
+		t.Errorf("resource should be labelled with the kubeware, got %v", pvc.Metadata.Labels)
+	}
+
+	// manifests must tell their kind
+	ioutil.WriteFile(filepath.Join(dir, "redis-claim.yaml"), []byte("metadata:\n  name: redis-claim\n"), 0644)
+	if _, err := md.ResourceKinds(); err == nil {
+		t.Errorf("expected an error for a manifest without kind")
+	}
+	if _, err := md.ParseResources(attributes); err == nil {
+		t.Errorf("expected an error for a manifest without kind")
+	}
+}
+
+func TestKinds(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kdeploy-kubeware")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "metadata.yaml"), []byte(`
+name: "Redis Server"
+version: "1.0.0"
+svc:
+  redis-master: "redis-master-service.yaml"
+deploy:
+  redis-master: "redis-master-deployment.yaml"
+  redis-slave: "redis-slave-deployment.yaml"
+resources:
+  redis-data: "redis-pvc.yaml"
+`), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "redis-master-deployment.yaml"), []byte("apiVersion: extensions/v1beta1\nkind: Deployment\n"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "redis-slave-deployment.yaml"), []byte("apiVersion: extensions/v1beta1\nkind: Deployment\n"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "redis-pvc.yaml"), []byte("apiVersion: v1\nkind: PersistentVolumeClaim\n"), 0644)
+
+	md, err := ReadMetadata(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	kinds, err := md.Kinds()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "[extensions/v1beta1/Deployment v1/PersistentVolumeClaim v1/Service]"
+	if fmt.Sprint(kinds) != expected {
+		t.Errorf("expected kinds %s, got %v", expected, kinds)
+	}
+}
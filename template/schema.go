@@ -0,0 +1,211 @@
+package template
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/flexiant/digger"
+)
+
+// Attribute types that can be declared in the metadata
+const (
+	TypeString = "string"
+	TypeInt    = "int"
+	TypeBool   = "bool"
+	TypeList   = "list"
+	TypeMap    = "map"
+)
+
+// Attribute formats that can be declared in the metadata
+const (
+	FormatPort     = "port"
+	FormatDNSLabel = "dns-label"
+	FormatImage    = "image"
+)
+
+var (
+	dnsLabel = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]{0,61}[a-z0-9])?$`)
+	// image matches references such as 'redis', 'gcr.io/google_samples/gb-frontend:v4' or
+	// 'localhost:5000/redis@sha256:<digest>'
+	image = regexp.MustCompile(`^([a-zA-Z0-9.-]+(:[0-9]+)?/)?[a-z0-9]+([._-][a-z0-9]+)*(/[a-z0-9]+([._-][a-z0-9]+)*)*(:[a-zA-Z0-9_][a-zA-Z0-9_.-]{0,127})?(@sha256:[a-f0-9]{64})?$`)
+)
+
+// AttributeError is a violation of the attribute metadata by a given attribute
+type AttributeError struct {
+	Path    string // e.g. 'svc/frontend/port'
+	Message string
+}
+
+func (e AttributeError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+// AttributeErrors holds every violation found in a set of attributes, sorted by path
+type AttributeErrors []AttributeError
+
+func (e AttributeErrors) Error() string {
+	lines := []string{fmt.Sprintf("%d invalid attribute(s):", len(e))}
+	for _, err := range e {
+		lines = append(lines, fmt.Sprintf("  %s", err.Error()))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (e AttributeErrors) Len() int           { return len(e) }
+func (e AttributeErrors) Swap(i, j int)      { e[i], e[j] = e[j], e[i] }
+func (e AttributeErrors) Less(i, j int) bool { return e[i].Path < e[j].Path }
+
+// ValidateAttributes checks the attributes against the metadata, returning AttributeErrors with
+// every missing required attribute and every value not matching its type, enum, pattern,
+// bounds or format
+func (m Metadata) ValidateAttributes(attributes map[string]interface{}) error {
+	d, err := digger.NewMapDigger(attributes)
+	if err != nil {
+		return fmt.Errorf("could not build digger: %v", err)
+	}
+	errs := AttributeErrors{}
+	for resourceType, resources := range m.Attributes {
+		for resourceName, attrs := range resources {
+			for attributeName, attrMetadata := range attrs {
+				path := fmt.Sprintf("%s/%s/%s", resourceType, resourceName, attributeName)
+				val, err := d.Get(path)
+				if err != nil || val == nil {
+					if attrMetadata.Required {
+						errs = append(errs, AttributeError{path, "required attribute not present"})
+					}
+					continue
+				}
+				for _, msg := range attrMetadata.Validate(val) {
+					errs = append(errs, AttributeError{path, msg})
+				}
+			}
+		}
+	}
+	if len(errs) > 0 {
+		sort.Sort(errs)
+		return errs
+	}
+	return nil
+}
+
+// Validate returns the violations of the attribute metadata by a value
+func (am SingleAttributeMetadata) Validate(val interface{}) []string {
+	msgs := []string{}
+	if am.Type != "" {
+		if ok, known := hasType(val, am.Type); !known {
+			return append(msgs, fmt.Sprintf("unknown type '%s' in metadata", am.Type))
+		} else if !ok {
+			return append(msgs, fmt.Sprintf("must be of type %s, got %#v", am.Type, val))
+		}
+	}
+	if len(am.Enum) > 0 && !inEnum(val, am.Enum) {
+		msgs = append(msgs, fmt.Sprintf("must be one of %v, got %#v", am.Enum, val))
+	}
+	if am.Pattern != "" {
+		re, err := regexp.Compile(am.Pattern)
+		if err != nil {
+			msgs = append(msgs, fmt.Sprintf("invalid pattern '%s' in metadata: %v", am.Pattern, err))
+		} else if s, ok := val.(string); !ok || !re.MatchString(s) {
+			msgs = append(msgs, fmt.Sprintf("must match '%s', got %#v", am.Pattern, val))
+		}
+	}
+	if am.Min != nil || am.Max != nil {
+		// numbers are bounded by their value, strings and lists by their length
+		size, what := 0.0, "be"
+		if n, ok := toFloat(val); ok {
+			size = n
+		} else if v := reflect.ValueOf(val); v.Kind() == reflect.String || v.Kind() == reflect.Slice {
+			size, what = float64(v.Len()), "have a length"
+		}
+		if am.Min != nil && size < *am.Min {
+			msgs = append(msgs, fmt.Sprintf("must %s at least %v, got %#v", what, *am.Min, val))
+		}
+		if am.Max != nil && size > *am.Max {
+			msgs = append(msgs, fmt.Sprintf("must %s at most %v, got %#v", what, *am.Max, val))
+		}
+	}
+	if am.Format != "" {
+		if msg := checkFormat(val, am.Format); msg != "" {
+			msgs = append(msgs, msg)
+		}
+	}
+	return msgs
+}
+
+// hasType tells if a value is of the given type, and if the type is known at all. Attributes
+// may come from YAML or JSON so integers can be decoded as floats
+func hasType(val interface{}, typ string) (bool, bool) {
+	switch typ {
+	case TypeString:
+		_, ok := val.(string)
+		return ok, true
+	case TypeInt:
+		n, ok := toFloat(val)
+		return ok && n == math.Trunc(n), true
+	case TypeBool:
+		_, ok := val.(bool)
+		return ok, true
+	case TypeList:
+		_, ok := val.([]interface{})
+		return ok, true
+	case TypeMap:
+		switch val.(type) {
+		case map[string]interface{}, map[interface{}]interface{}:
+			return true, true
+		}
+		return false, true
+	}
+	return false, false
+}
+
+func toFloat(val interface{}) (float64, bool) {
+	switch n := val.(type) {
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+func inEnum(val interface{}, enum []interface{}) bool {
+	n, isNumber := toFloat(val)
+	for _, e := range enum {
+		if en, ok := toFloat(e); ok && isNumber && en == n {
+			return true
+		}
+		if reflect.DeepEqual(e, val) {
+			return true
+		}
+	}
+	return false
+}
+
+func checkFormat(val interface{}, format string) string {
+	switch format {
+	case FormatPort:
+		n, ok := toFloat(val)
+		if !ok || n != math.Trunc(n) || n < 1 || n > 65535 {
+			return fmt.Sprintf("must be a port number, got %#v", val)
+		}
+	case FormatDNSLabel:
+		s, ok := val.(string)
+		if !ok || !dnsLabel.MatchString(s) {
+			return fmt.Sprintf("must be a DNS label of up to 63 lowercase alphanumeric characters or '-', got %#v", val)
+		}
+	case FormatImage:
+		s, ok := val.(string)
+		if !ok || !image.MatchString(s) {
+			return fmt.Sprintf("must be an image reference, got %#v", val)
+		}
+	default:
+		return fmt.Sprintf("unknown format '%s' in metadata", format)
+	}
+	return ""
+}
@@ -0,0 +1,132 @@
+package template
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+const schemaSample = `
+name: "Guestbook"
+version: "0.0.1"
+attributes:
+  svc:
+    frontend:
+      port:
+        type: int
+        format: port
+        required: true
+      balancer:
+        enum: [ClusterIP, NodePort, LoadBalancer]
+        default: LoadBalancer
+  rc:
+    frontend:
+      number:
+        type: int
+        min: 1
+        max: 10
+        default: 3
+      name:
+        format: dns-label
+        default: frontend
+      image:
+        format: image
+        default: "gcr.io/google_samples/gb-frontend:v4"
+      env:
+        pattern: "^(dev|prod)$"
+      debug:
+        type: bool
+      args:
+        type: list
+        max: 2
+`
+
+func TestValidateAttributes(t *testing.T) {
+	var md Metadata
+	err := yaml.Unmarshal([]byte(schemaSample), &md)
+	if err != nil {
+		t.Fatalf("error parsing metadata: %v", err)
+	}
+	defaults, err := md.AttributeDefaults()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// attributes read from JSON files hold numbers as floats
+	var valid map[string]interface{}
+	json.Unmarshal([]byte(`{"svc":{"frontend":{"port":80,"balancer":"NodePort"}},"rc":{"frontend":{"number":10,"env":"prod","debug":true,"args":["-v"]}}}`), &valid)
+	for resourceType, resources := range valid {
+		for name, attrs := range resources.(map[string]interface{}) {
+			for attr, val := range attrs.(map[string]interface{}) {
+				defaults[resourceType].(map[string]interface{})[name].(map[string]interface{})[attr] = val
+			}
+		}
+	}
+	if err := md.ValidateAttributes(defaults); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	var invalid map[string]interface{}
+	json.Unmarshal([]byte(`{"svc":{"frontend":{"balancer":"Ingress"}},"rc":{"frontend":{"number":"ten","name":"Frontend_1","image":"gb frontend","env":"staging","debug":"yes","args":["-v","-d","-x"]}}}`), &invalid)
+	err = md.ValidateAttributes(invalid)
+	if err == nil {
+		t.Fatalf("expected attributes to be invalid")
+	}
+	errs, ok := err.(AttributeErrors)
+	if !ok {
+		t.Fatalf("expected AttributeErrors, got %T", err)
+	}
+	expected := []string{
+		"rc/frontend/args",
+		"rc/frontend/debug",
+		"rc/frontend/env",
+		"rc/frontend/image",
+		"rc/frontend/name",
+		"rc/frontend/number",
+		"svc/frontend/balancer",
+		"svc/frontend/port",
+	}
+	if len(errs) != len(expected) {
+		t.Fatalf("expected %d violations, got %v", len(expected), err)
+	}
+	for i, path := range expected {
+		if errs[i].Path != path {
+			t.Errorf("expected violation of %s, got %v", path, errs[i])
+		}
+	}
+	if !strings.Contains(err.Error(), "svc/frontend/port: required attribute not present") {
+		t.Errorf("unexpected error message: %v", err)
+	}
+}
+
+func TestValidateFormats(t *testing.T) {
+	for _, c := range []struct {
+		format string
+		val    interface{}
+		valid  bool
+	}{
+		{FormatPort, 8080, true},
+		{FormatPort, 80.0, true},
+		{FormatPort, 0, false},
+		{FormatPort, 70000, false},
+		{FormatPort, "80", false},
+		{FormatDNSLabel, "redis-master", true},
+		{FormatDNSLabel, "-redis", false},
+		{FormatDNSLabel, strings.Repeat("a", 64), false},
+		{FormatImage, "redis", true},
+		{FormatImage, "localhost:5000/ops/redis:3.0-alpine", true},
+		{FormatImage, "redis@sha256:" + strings.Repeat("a", 64), true},
+		{FormatImage, "Redis", false},
+		{FormatImage, "redis:", false},
+	} {
+		msg := checkFormat(c.val, c.format)
+		if c.valid != (msg == "") {
+			t.Errorf("unexpected validation of %#v as %s: %q", c.val, c.format, msg)
+		}
+	}
+	if checkFormat("x", "uuid") == "" {
+		t.Errorf("expected unknown formats to be reported")
+	}
+}
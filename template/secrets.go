@@ -0,0 +1,98 @@
+package template
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/flexiant/kdeploy/utils"
+)
+
+// EncryptedExtension is appended to the name of attribute files encrypted with age
+const EncryptedExtension = ".age"
+
+// Prefixes of attribute values that are resolved from a secret source when rendering, rather than
+// kept in plain text in attribute files
+const (
+	SecretFile       = "file:"       // content of a file, without its trailing newline
+	SecretEnv        = "env:"        // value of an environment variable
+	SecretBase64File = "base64file:" // content of a file, base64 encoded as in secrets data
+)
+
+// resolveSecrets returns the attributes with every value referencing a secret source replaced by
+// the secret, which is registered to be masked in the output. Values at paths not resolvable are
+// kept as they are
+func resolveSecrets(path string, value interface{}, resolvable func(path string) bool) (interface{}, error) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		resolved := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			r, err := resolveSecrets(joinPath(path, key), item, resolvable)
+			if err != nil {
+				return nil, err
+			}
+			resolved[key] = r
+		}
+		return resolved, nil
+	case []interface{}:
+		if !resolvable(path) {
+			return value, nil
+		}
+		resolved := make([]interface{}, len(v))
+		for i, item := range v {
+			// lists are set as a whole by a single layer
+			r, err := resolveSecrets(fmt.Sprintf("%s/%d", path, i), item, anyPath)
+			if err != nil {
+				return nil, err
+			}
+			resolved[i] = r
+		}
+		return resolved, nil
+	case string:
+		if !resolvable(path) {
+			return value, nil
+		}
+		secret, ok, err := resolveSecret(v)
+		if err != nil {
+			return nil, fmt.Errorf("could not resolve attribute %s: %v", path, err)
+		}
+		if ok {
+			utils.RegisterSecret(secret)
+			return secret, nil
+		}
+	}
+	return value, nil
+}
+
+func anyPath(string) bool {
+	return true
+}
+
+// resolveSecret resolves a value referencing a secret source, telling whether it does
+func resolveSecret(value string) (string, bool, error) {
+	switch {
+	case strings.HasPrefix(value, SecretEnv):
+		name := strings.TrimPrefix(value, SecretEnv)
+		secret, ok := os.LookupEnv(name)
+		if !ok {
+			return "", true, fmt.Errorf("environment variable %s is not set", name)
+		}
+		return secret, true, nil
+	case strings.HasPrefix(value, SecretFile):
+		data, err := ioutil.ReadFile(strings.TrimPrefix(value, SecretFile))
+		if err != nil {
+			return "", true, err
+		}
+		return strings.TrimSuffix(string(data), "\n"), true, nil
+	case strings.HasPrefix(value, SecretBase64File):
+		data, err := ioutil.ReadFile(strings.TrimPrefix(value, SecretBase64File))
+		if err != nil {
+			return "", true, err
+		}
+		utils.RegisterSecret(strings.TrimSuffix(string(data), "\n"))
+		return base64.StdEncoding.EncodeToString(data), true, nil
+	}
+	return value, false, nil
+}
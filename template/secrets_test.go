@@ -0,0 +1,143 @@
+package template
+
+import (
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/flexiant/kdeploy/utils"
+)
+
+func TestSecretSources(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "kdeploy-secrets")
+	defer os.RemoveAll(dir)
+	password := filepath.Join(dir, "password")
+	ioutil.WriteFile(password, []byte("file-password\n"), 0600)
+	os.Setenv("KDEPLOY_TEST_TOKEN", "env-token")
+	defer os.Unsetenv("KDEPLOY_TEST_TOKEN")
+
+	attributes := filepath.Join(dir, "attributes.yaml")
+	ioutil.WriteFile(attributes, []byte(`secret:
+  db:
+    password: "file:`+password+`"
+    data: "base64file:`+password+`"
+    tokens: ["env:KDEPLOY_TEST_TOKEN", "plain"]
+`), 0644)
+
+	values, err := BuildAttributes([]string{attributes}, nil, Metadata{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	db := values["secret"].(map[string]interface{})["db"].(map[string]interface{})
+	if db["password"] != "file-password" {
+		t.Errorf("unexpected password %#v", db["password"])
+	}
+	if db["data"] != base64.StdEncoding.EncodeToString([]byte("file-password\n")) {
+		t.Errorf("unexpected data %#v", db["data"])
+	}
+	tokens := db["tokens"].([]interface{})
+	if tokens[0] != "env-token" || tokens[1] != "plain" {
+		t.Errorf("unexpected tokens %#v", tokens)
+	}
+	if masked := utils.MaskSecrets("file-password env-token plain"); masked != "****** ****** plain" {
+		t.Errorf("expected secrets to be masked, got %s", masked)
+	}
+
+	// references are kept as they are until attributes are built
+	resolved, err := ResolveAttributes([]string{attributes}, nil, Metadata{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p := resolved.Values["secret"].(map[string]interface{})["db"].(map[string]interface{})["password"]; p != "file:"+password {
+		t.Errorf("unexpected unresolved password %#v", p)
+	}
+
+	_, err = BuildAttributes(nil, []string{"secret/db/password=env:KDEPLOY_TEST_MISSING"}, Metadata{})
+	if err == nil || !strings.Contains(err.Error(), "secret/db/password: environment variable KDEPLOY_TEST_MISSING is not set") {
+		t.Errorf("expected missing variable error, got %v", err)
+	}
+}
+
+func TestSecretSourcesOnlyFromUser(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "kdeploy-secrets")
+	defer os.RemoveAll(dir)
+	password := filepath.Join(dir, "password")
+	ioutil.WriteFile(password, []byte("local-password\n"), 0600)
+	ioutil.WriteFile(filepath.Join(dir, "metadata.yaml"), []byte(`name: "Redis"
+version: "1.0.0"
+attributes:
+  secret:
+    db:
+      password:
+        default: "file:`+password+`"
+`), 0644)
+	md, err := ReadMetadata(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// defaults of the kubeware are literal
+	values, err := BuildAttributes(nil, nil, md)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p := values["secret"].(map[string]interface{})["db"].(map[string]interface{})["password"]; p != "file:"+password {
+		t.Errorf("expected the default to be kept literal, got %#v", p)
+	}
+
+	// and so are overrides of dependents and outputs of dependencies
+	values, err = BuildDependencyAttributes(map[string]interface{}{
+		"secret": map[string]interface{}{"db": map[string]interface{}{"user": "env:HOME"}},
+	}, map[string]map[string]interface{}{"other": {"host": "file:" + password}}, md)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if u := values["secret"].(map[string]interface{})["db"].(map[string]interface{})["user"]; u != "env:HOME" {
+		t.Errorf("expected the override to be kept literal, got %#v", u)
+	}
+	if h := values[OutputsAttribute].(map[string]interface{})["other"].(map[string]interface{})["host"]; h != "file:"+password {
+		t.Errorf("expected the output to be kept literal, got %#v", h)
+	}
+
+	// unless the user sets them
+	values, err = BuildAttributes(nil, []string{"secret/db/password=file:" + password}, md)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p := values["secret"].(map[string]interface{})["db"].(map[string]interface{})["password"]; p != "local-password" {
+		t.Errorf("expected the password set to be read, got %#v", p)
+	}
+}
+
+func TestEncryptedAttributesFile(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "kdeploy-secrets")
+	defer os.RemoveAll(dir)
+	identity, _ := age.GenerateX25519Identity()
+	key := filepath.Join(dir, "secrets.key")
+	ioutil.WriteFile(key, []byte(identity.String()), 0600)
+	os.Setenv("KDEPLOY_SECRETS_KEY", key)
+	defer os.Unsetenv("KDEPLOY_SECRETS_KEY")
+
+	encrypted, err := utils.Encrypt([]byte("secret:\n  db:\n    password: encrypted-password\n    port: 5432\n"), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	file := filepath.Join(dir, "secrets.yaml.age")
+	ioutil.WriteFile(file, encrypted, 0644)
+
+	values, err := ReadAttributesFile(file)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	db := values["secret"].(map[string]interface{})["db"].(map[string]interface{})
+	if db["password"] != "encrypted-password" || db["port"] != 5432 {
+		t.Errorf("unexpected attributes %#v", db)
+	}
+	if masked := utils.MaskSecrets("password: encrypted-password"); masked != "password: ******" {
+		t.Errorf("expected values of encrypted files to be masked, got %s", masked)
+	}
+}
@@ -0,0 +1,282 @@
+package template
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// mustacheTag matches mustache tags, capturing their type and name
+var mustacheTag = regexp.MustCompile(`\{\{(\{?)\s*([#^/!>&=]?)\s*(.*?)\s*\}?\}\}`)
+
+// UndefinedVariable is a tag of a template not resolved by the attributes
+type UndefinedVariable struct {
+	File string
+	Line int
+	Name string
+}
+
+func (u UndefinedVariable) Error() string {
+	return fmt.Sprintf("%s:%d: undefined variable '%s'", u.File, u.Line, u.Name)
+}
+
+// UndefinedVariables holds every tag not resolved in the templates of a kubeware, sorted by
+// file and line
+type UndefinedVariables []UndefinedVariable
+
+func (u UndefinedVariables) Error() string {
+	lines := []string{fmt.Sprintf("%d undefined variable(s) in templates:", len(u))}
+	for _, v := range u {
+		lines = append(lines, fmt.Sprintf("  %s", v.Error()))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (u UndefinedVariables) Len() int      { return len(u) }
+func (u UndefinedVariables) Swap(i, j int) { u[i], u[j] = u[j], u[i] }
+func (u UndefinedVariables) Less(i, j int) bool {
+	if u[i].File != u[j].File {
+		return u[i].File < u[j].File
+	}
+	return u[i].Line < u[j].Line
+}
+
+// CheckTemplates looks for the tags of every template that the attributes don't resolve, which
+// would silently be rendered as blanks, and returns them all as UndefinedVariables. It
+// also warns about attributes declared in the metadata that no template references
+func (m Metadata) CheckTemplates(attributes map[string]interface{}) error {
+	undefined, unused, err := m.AnalyzeTemplates(attributes)
+	if err != nil {
+		return err
+	}
+	for _, path := range unused {
+		log.Warnf("Attribute %s is declared but no template references it", path)
+	}
+	if len(undefined) > 0 {
+		return undefined
+	}
+	return nil
+}
+
+// AnalyzeTemplates returns the tags of every template that the attributes don't resolve, sorted
+// by file and line, and the attributes declared in the metadata that no template references
+func (m Metadata) AnalyzeTemplates(attributes map[string]interface{}) (UndefinedVariables, []string, error) {
+	undefined := UndefinedVariables{}
+	used := map[string]bool{}
+	attributes = m.withoutUnset(m.withInstance(attributes), "")
+	for _, file := range m.ResourceTemplateFiles() {
+		expanded, err := m.expand(file)
+		if err != nil {
+			return nil, nil, err
+		}
+		analyze := analyzeTemplate
+		if m.engine() == EngineGoTemplate {
+			analyze = analyzeGoTemplate
+		}
+		u, err := analyze(file, expanded.content, attributes, used)
+		if err != nil {
+			return nil, nil, err
+		}
+		// tell the lines in the partials rather than in the expanded template
+		for _, v := range u {
+			source := expanded.source(v.Line)
+			if source.File != "" {
+				v.File, v.Line = source.File, source.Line
+			}
+			undefined = append(undefined, v)
+		}
+	}
+	sort.Sort(undefined)
+	return undefined, m.UnusedAttributes(used), nil
+}
+
+// withoutUnset copies the attributes leaving out those without a value, such as attributes
+// declared with no default and not set, which templates would render blank. Those the metadata
+// makes optional with a null default are kept
+func (m Metadata) withoutUnset(attributes map[string]interface{}, path string) map[string]interface{} {
+	values := make(map[string]interface{}, len(attributes))
+	for name, value := range attributes {
+		p := joinPath(path, name)
+		if value == nil && !m.optional(p) {
+			continue
+		}
+		if nested, ok := asMap(value); ok {
+			value = m.withoutUnset(nested, p)
+		}
+		values[name] = value
+	}
+	return values
+}
+
+// optional tells if the attribute at path is declared with a null default
+func (m Metadata) optional(path string) bool {
+	parts := strings.Split(path, "/")
+	if len(parts) != 3 {
+		return false
+	}
+	return m.Attributes[parts[0]][parts[1]][parts[2]].nullDefault
+}
+
+// UnusedAttributes returns the attributes declared in the metadata that are not among the used
+// paths, sorted. Attributes holding maps are used if any of their keys is
+func (m Metadata) UnusedAttributes(used map[string]bool) []string {
+	unused := []string{}
+	for resourceType, resources := range m.Attributes {
+		for resourceName, attrs := range resources {
+			for attributeName := range attrs {
+				path := fmt.Sprintf("%s/%s/%s", resourceType, resourceName, attributeName)
+				if !isUsed(path, used) {
+					unused = append(unused, path)
+				}
+			}
+		}
+	}
+	sort.Strings(unused)
+	return unused
+}
+
+func isUsed(path string, used map[string]bool) bool {
+	for u := range used {
+		if u == path || strings.HasPrefix(path, u+"/") || strings.HasPrefix(u, path+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// frame is a context in which mustache looks names up, along with the attribute path it was
+// found at. Unknown frames resolve any name, since what they hold can't be told before rendering
+type frame struct {
+	value   interface{}
+	path    string
+	unknown bool
+}
+
+// analyzeTemplate walks the tags of a mustache template, resolving them as mustache would against
+// the attributes. It returns those not resolved, and records in used the paths of those that are
+func analyzeTemplate(file, content string, attributes map[string]interface{}, used map[string]bool) ([]UndefinedVariable, error) {
+	undefined := []UndefinedVariable{}
+	stack := []frame{{value: attributes}}
+	sections := []string{}
+	for _, match := range mustacheTag.FindAllStringSubmatchIndex(content, -1) {
+		kind, name := content[match[4]:match[5]], content[match[6]:match[7]]
+		line := strings.Count(content[:match[0]], "\n") + 1
+		switch kind {
+		case "!", ">":
+			continue
+		case "=":
+			// custom delimiters would need a full mustache parser
+			log.Debugf("Not checking %s past line %d since it changes delimiters", file, line)
+			return undefined, nil
+		case "/":
+			if len(sections) == 0 || sections[len(sections)-1] != name {
+				return nil, fmt.Errorf("%s:%d: unexpected closing tag '%s'", file, line, name)
+			}
+			sections = sections[:len(sections)-1]
+			stack = stack[:len(stack)-1]
+			continue
+		}
+
+		value, path, found := lookup(stack, name)
+		if !found {
+			undefined = append(undefined, UndefinedVariable{File: file, Line: line, Name: name})
+		} else if path != "" {
+			used[path] = true
+		}
+
+		switch kind {
+		case "#":
+			sections = append(sections, name)
+			stack = append(stack, sectionFrame(value, path, found))
+		case "^":
+			// inverted sections render in the enclosing context
+			sections = append(sections, name)
+			stack = append(stack, frame{unknown: !found})
+		}
+	}
+	if len(sections) > 0 {
+		return nil, fmt.Errorf("%s: unclosed section '%s'", file, sections[len(sections)-1])
+	}
+	return undefined, nil
+}
+
+// sectionFrame returns the context a section renders its content in
+func sectionFrame(value interface{}, path string, found bool) frame {
+	if !found {
+		return frame{unknown: true}
+	}
+	if m, ok := asMap(value); ok {
+		return frame{value: m, path: path}
+	}
+	switch v := value.(type) {
+	case []interface{}:
+		// items of a list are told apart by index, so they are checked against the first one
+		if len(v) == 0 {
+			return frame{unknown: true}
+		}
+		return frame{value: v[0], path: path}
+	}
+	// other values just tell whether the section renders, in the enclosing context
+	return frame{}
+}
+
+// lookup resolves a dotted name as mustache does: its first part in the innermost context that
+// holds it, and the rest within what was found. It returns the value and its attribute path
+func lookup(stack []frame, name string) (interface{}, string, bool) {
+	if name == "." {
+		return nil, "", true
+	}
+	parts := strings.Split(name, ".")
+	for i := len(stack) - 1; i >= 0; i-- {
+		f := stack[i]
+		if f.unknown {
+			return nil, "", true
+		}
+		m, ok := asMap(f.value)
+		if !ok {
+			continue
+		}
+		value, found := m[parts[0]]
+		if !found {
+			continue
+		}
+		path := joinPath(f.path, parts[0])
+		for _, part := range parts[1:] {
+			m, ok := asMap(value)
+			if !ok {
+				return nil, "", false
+			}
+			if value, found = m[part]; !found {
+				return nil, "", false
+			}
+			path = joinPath(path, part)
+		}
+		return value, path, true
+	}
+	return nil, "", false
+}
+
+// asMap returns maps as read from either JSON or YAML, the latter having keys of any type
+func asMap(value interface{}) (map[string]interface{}, bool) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return v, true
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			m[fmt.Sprint(k)] = item
+		}
+		return m, true
+	}
+	return nil, false
+}
+
+func joinPath(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "/" + name
+}
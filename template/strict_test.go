@@ -0,0 +1,126 @@
+package template
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckTemplates(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kdeploy-kubeware")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "metadata.yaml"), []byte(`
+name: "Guestbook"
+version: "0.0.1"
+rc:
+  frontend: "frontend-controller.yaml"
+svc:
+  frontend: "frontend-service.yaml"
+attributes:
+  rc:
+    frontend:
+      image:
+        default: "gb-frontend"
+      number:
+        default: 2
+      env:
+        default:
+          - name: TIER
+            value: frontend
+      debug:
+        description: "Optional, with a null default"
+        default: null
+      version:
+        description: "Without default, nor set"
+      unused:
+        default: "never referenced"
+  svc:
+    frontend:
+      port:
+        default: 80
+`), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "frontend-controller.yaml"), []byte(`kind: ReplicationController
+metadata:
+  name: frontend{{rc.frontend.version}}
+spec:
+  replicas: {{ rc.frontend.number }}
+  template:
+    spec:
+      containers:
+      - image: {{{rc.frontend.imgae}}}
+        {{! comments are not checked {{ nor.this }} }}
+        {{#rc.frontend.debug}}args: ["-v"]{{/rc.frontend.debug}}
+        env:
+        {{#rc.frontend.env}}
+        - name: {{name}}
+          value: {{valeu}}
+        {{/rc.frontend.env}}
+        {{^rc.frontend.probe}}
+        {{svc.frontend.port}}
+        {{/rc.frontend.probe}}
+`), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "frontend-service.yaml"), []byte(`kind: Service
+metadata:
+  name: frontend
+spec:
+  {{#svc.frontend}}
+  ports:
+  - port: {{port}}
+    nodePort: {{nodeport}}
+  {{/svc.frontend}}
+`), 0644)
+
+	md, err := ReadMetadata(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	attributes, err := BuildAttributes(nil, nil, md)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err = md.CheckTemplates(attributes)
+	undefined, ok := err.(UndefinedVariables)
+	if !ok {
+		t.Fatalf("expected UndefinedVariables, got %v", err)
+	}
+	expected := []UndefinedVariable{
+		{"frontend-controller.yaml", 3, "rc.frontend.version"},
+		{"frontend-controller.yaml", 9, "rc.frontend.imgae"},
+		{"frontend-controller.yaml", 15, "valeu"},
+		{"frontend-controller.yaml", 17, "rc.frontend.probe"},
+		{"frontend-service.yaml", 8, "nodeport"},
+	}
+	if len(undefined) != len(expected) {
+		t.Fatalf("expected %d undefined variables, got %v", len(expected), err)
+	}
+	for i, e := range expected {
+		if undefined[i] != e {
+			t.Errorf("expected %v, got %v", e, undefined[i])
+		}
+	}
+	if !strings.Contains(err.Error(), "frontend-controller.yaml:9: undefined variable 'rc.frontend.imgae'") {
+		t.Errorf("unexpected error message: %v", err)
+	}
+
+	used := map[string]bool{}
+	_, err = analyzeTemplate("frontend-service.yaml", "{{#svc.frontend}}{{port}}{{/svc.frontend}}{{rc.frontend.env}}", attributes, used)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !used["svc/frontend/port"] || !used["rc/frontend/env"] {
+		t.Errorf("expected names in sections to be resolved to their path, got %v", used)
+	}
+	unused := md.UnusedAttributes(used)
+	if len(unused) != 5 || unused[3] != "rc/frontend/unused" {
+		t.Errorf("unexpected unused attributes: %v", unused)
+	}
+
+	if _, err := analyzeTemplate("broken.yaml", "{{#a}}{{/b}}", attributes, used); err == nil {
+		t.Errorf("expected an error for mismatched sections")
+	}
+}
@@ -2,29 +2,56 @@ package upgrade
 
 import (
 	"os"
+	"strings"
 
 	"github.com/codegangsta/cli"
+	"github.com/flexiant/kdeploy/fetchers"
+	"github.com/flexiant/kdeploy/utils"
 )
 
 // Flags builds a spec of the flags available for the command
 func Flags() []cli.Flag {
 	return []cli.Flag{
-		cli.StringFlag{
+		cli.StringSliceFlag{
 			Name:   "attribute, a",
-			Usage:  "Attribute List",
+			Usage:  "Attributes file, JSON or YAML. Repeat to merge several, left to right",
 			EnvVar: "KDEPLOY_ATTRIBUTE",
 		},
+		cli.StringSliceFlag{
+			Name:  "set",
+			Usage: "Attribute override applied last, as path=value (e.g. rc/frontend/number=3)",
+		},
+		cli.BoolTFlag{
+			Name:   "strict",
+			Usage:  "Fail on template variables not resolved by the attributes, use --strict=false to render them blank",
+			EnvVar: "KDEPLOY_STRICT",
+		},
 		cli.StringFlag{
 			Name:   "kubeware, k",
 			Usage:  "Kubeware path",
 			EnvVar: "KDEPLOY_KUBEWARE",
 		},
+		cli.StringFlag{
+			Name:   "sha256",
+			Usage:  "Expected SHA-256 checksum of the kubeware archive",
+			EnvVar: "KDEPLOY_SHA256",
+		},
+		cli.BoolFlag{
+			Name:   "require-signed",
+			Usage:  "Refuse Kubewares not signed with a trusted key",
+			EnvVar: "KDEPLOY_REQUIRE_SIGNED",
+		},
 		cli.StringFlag{
 			Name:   "namespace, n",
 			Usage:  "Namespace which to deploy Kubeware",
 			Value:  "default",
 			EnvVar: "KDEPLOY_NAMESPACE",
 		},
+		cli.StringFlag{
+			Name:   "instance, i",
+			Usage:  "Instance of the Kubeware to upgrade, the default one if not given",
+			EnvVar: "KDEPLOY_INSTANCE",
+		},
 		cli.StringFlag{
 			Name:   "strategy, s",
 			Usage:  "Upgrade strategy to use",
@@ -41,12 +68,20 @@ func Flags() []cli.Flag {
 
 // PrepareFlags processes the flags
 func PrepareFlags(c *cli.Context) error {
-	if c.String("attribute") != "" {
-		os.Setenv("KDEPLOY_ATTRIBUTE", c.String("attribute"))
+	if len(c.StringSlice("attribute")) > 0 {
+		os.Setenv("KDEPLOY_ATTRIBUTE", strings.Join(c.StringSlice("attribute"), ","))
 	}
 
 	if c.String("kubeware") != "" {
-		os.Setenv("KDEPLOY_KUBEWARE", c.String("kubeware"))
+		kubeware, err := fetchers.WithChecksum(c.String("kubeware"), c.String("sha256"))
+		if err != nil {
+			return err
+		}
+		os.Setenv("KDEPLOY_KUBEWARE", kubeware)
+	}
+
+	if c.Bool("require-signed") {
+		os.Setenv("KDEPLOY_REQUIRE_SIGNED", "1")
 	}
 
 	if c.String("strategy") != "" {
@@ -57,6 +92,14 @@ func PrepareFlags(c *cli.Context) error {
 		os.Setenv("KDEPLOY_DRYRUN", "1")
 	}
 
+	if c.String("instance") != "" {
+		err := utils.ValidateInstance(c.String("instance"))
+		if err != nil {
+			return err
+		}
+		os.Setenv("KDEPLOY_INSTANCE", c.String("instance"))
+	}
+
 	os.Setenv("KDEPLOY_NAMESPACE", c.String("namespace"))
 
 	return nil
@@ -11,7 +11,7 @@ type replicationController struct {
 }
 
 func buildRCObject(kube webservice.KubeClient, ns, rcname string) (*replicationController, error) {
-	readyReplicas, err := countReadyReplicas(kube, ns, rcname)
+	readyReplicas, err := kube.GetReadyReplicas(ns, rcname)
 	if err != nil {
 		return nil, err
 	}
@@ -56,20 +56,3 @@ func setZeroReplicasOnRC(rc map[string]interface{}) error {
 	rcspec["replicas"] = 0
 	return nil
 }
-
-func countReadyReplicas(kube webservice.KubeClient, ns, rcname string) (uint, error) {
-	pods, err := kube.GetPodsForController(ns, rcname)
-	if err != nil {
-		return 0, err
-	}
-	var count uint
-	for _, p := range *pods {
-		for _, c := range p.Status.Conditions {
-			if c.Type == "Ready" && c.Status == "True" {
-				count++
-				break
-			}
-		}
-	}
-	return count, nil
-}
@@ -7,6 +7,7 @@ import (
 	log "github.com/Sirupsen/logrus"
 	"github.com/codegangsta/cli"
 	"github.com/flexiant/kdeploy/fetchers"
+	"github.com/flexiant/kdeploy/outputs"
 	"github.com/flexiant/kdeploy/template"
 	"github.com/flexiant/kdeploy/upgrade/strategies"
 	"github.com/flexiant/kdeploy/utils"
@@ -21,15 +22,17 @@ func CmdUpgrade(c *cli.Context) {
 	var err error
 
 	kubeware = os.Getenv("KDEPLOY_KUBEWARE")
-	localKubePath, err = fetchers.Fetch(kubeware)
+	fetched, err := fetchers.Fetch(kubeware)
 	if err != nil {
 		log.Fatal(fmt.Errorf("Could not fetch kubeware: '%s' (%v)", kubeware, err))
 	}
+	defer fetched.Close()
+	localKubePath = fetched.Path
 
 	log.Debugf("Going to parse kubeware in %s", localKubePath)
 
 	md := template.ParseMetadata(localKubePath)
-	utils.CheckError(err)
+	md.Instance = os.Getenv("KDEPLOY_INSTANCE")
 
 	kubernetes, err := webservice.NewKubeClient()
 	utils.CheckError(err)
@@ -38,7 +41,9 @@ func CmdUpgrade(c *cli.Context) {
 	// labelSelector := fmt.Sprintf("kubeware=%s,kubeware-version=%s", md.Name, md.Version)
 
 	// Check if kubeware already installed, error if it's not
-	v, err := kubernetes.FindDeployedKubewareVersion(namespace, md.Name)
+	kinds, err := md.Kinds()
+	utils.CheckError(err)
+	v, err := kubernetes.FindDeployedKubewareVersion(namespace, md.Name, md.Instance, kinds...)
 	utils.CheckError(err)
 	if v == "" {
 		log.Fatalf("Kubeware '%s.%s' is not deployed and thus it can't be upgraded", namespace, md.Name)
@@ -56,9 +61,15 @@ func CmdUpgrade(c *cli.Context) {
 
 	// build attributes merging "role list" to defaults
 	log.Debugf("Building attributes")
-	defaults, err := md.AttributeDefaults()
+	collected, err := outputs.DependencyOutputs(kubernetes, namespace, md)
+	utils.CheckError(err)
+	attributes, err := template.BuildAttributesWithOutputs(c.StringSlice("attribute"), c.StringSlice("set"), collected, md)
 	utils.CheckError(err)
-	attributes := template.BuildAttributes(c.String("attribute"), defaults)
+	// check templates don't refer to undefined attributes, which would be rendered blank
+	if c.BoolT("strict") {
+		err = md.CheckTemplates(attributes)
+		utils.CheckError(err)
+	}
 
 	// get services and parse each one
 	log.Debugf("Parsing services")
@@ -70,10 +81,51 @@ func CmdUpgrade(c *cli.Context) {
 	controllersSpecs, err := md.ParseControllers(attributes)
 	utils.CheckError(err)
 
+	// get deployments, config maps and secrets and parse each one
+	log.Debugf("Parsing deployments, config maps and secrets")
+	deploymentsSpecs, err := md.ParseDeployments(attributes)
+	utils.CheckError(err)
+	configMapsSpecs, err := md.ParseConfigMaps(attributes)
+	utils.CheckError(err)
+	secretsSpecs, err := md.ParseSecrets(attributes)
+	utils.CheckError(err)
+
+	// get resources of any other kind and parse each one
+	log.Debugf("Parsing resources")
+	resourcesSpecs, err := md.ParseResources(attributes)
+	utils.CheckError(err)
+
+	// config maps and secrets are in place before pods of the new version start
+	err = apply(namespace, configMapsSpecs, kubernetes.ReplaceConfigMap, kubernetes.CreateConfigMap)
+	utils.CheckError(err)
+	err = apply(namespace, secretsSpecs, kubernetes.ReplaceSecret, kubernetes.CreateSecret)
+	utils.CheckError(err)
+	err = apply(namespace, resourcesSpecs, kubernetes.ReplaceResource, kubernetes.CreateResource)
+	utils.CheckError(err)
+
 	// upgStrategy := upgradeStrategies.RecreateAllStrategy(kubernetes)
 	// upgStrategy := upgradeStrategies.RollRcPatchSvcStrategy(kubernetes, 1)
 	upgStrategy := upgradeStrategies.BuildUpgradeStrategy(os.Getenv("KDEPLOY_UPGRADE_STRATEGY"), kubernetes)
 	upgStrategy.Upgrade(namespace, servicesSpecs, controllersSpecs)
 
+	// deployments roll their pods themselves once replaced
+	err = apply(namespace, deploymentsSpecs, kubernetes.ReplaceDeployment, kubernetes.CreateDeployment)
+	utils.CheckError(err)
+
 	log.Infof("Kubeware '%s.%s' has been upgraded from version '%s' to '%s'", namespace, md.Name, v, md.Version)
 }
+
+// apply replaces each resource, creating those which were not deployed by the previous version
+func apply(namespace string, specs map[string]string, replace func(namespace, name, spec string) error, create func(namespace string, spec []byte) (string, error)) error {
+	for name, spec := range specs {
+		err := replace(namespace, name, spec)
+		if err == webservice.ErrNotFound {
+			log.Debugf("Creating '%s' since it wasn't deployed previously", name)
+			_, err = create(namespace, []byte(spec))
+		}
+		if err != nil {
+			return fmt.Errorf("error upgrading %s: %v", name, err)
+		}
+	}
+	return nil
+}
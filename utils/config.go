@@ -234,6 +234,19 @@ func ReadConfigFrom(path string) (*Config, error) {
 	return nil, errors.New(fmt.Sprintf("Could not locate file %s", path))
 }
 
+// KdeployDir returns the directory where kdeploy keeps its local state (e.g. fetched kubewares),
+// which is ~/.kdeploy unless overridden by KDEPLOY_HOME
+func KdeployDir() (string, error) {
+	if dir := os.Getenv("KDEPLOY_HOME"); dir != "" {
+		return filepath.Abs(dir)
+	}
+	home, err := homedir.Dir()
+	if err != nil {
+		return "", fmt.Errorf("Couldn't get home dir for current user: %s", err.Error())
+	}
+	return filepath.Join(home, ".kdeploy"), nil
+}
+
 func InitializeConfig(c *cli.Context) error {
 	log.Debug("InitializeConfig")
 	// overwrite with environment/arguments vars
@@ -0,0 +1,151 @@
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/mitchellh/go-homedir"
+	"gopkg.in/yaml.v2"
+)
+
+// HostCredentials holds the credentials used to fetch kubewares from a host
+type HostCredentials struct {
+	Host     string `yaml:"host"`
+	Username string `yaml:"username,omitempty"`
+	Token    string `yaml:"token,omitempty"`
+	SSHKey   string `yaml:"sshKey,omitempty"`
+}
+
+// kdeployConfig is the kdeploy configuration file kept in the kdeploy directory
+type kdeployConfig struct {
+	Credentials []HostCredentials `yaml:"credentials"`
+}
+
+// CredentialsFor looks up the credentials for a host, trying in order:
+// - the KDEPLOY_TOKEN_<HOST> env var (e.g. KDEPLOY_TOKEN_GITHUB_COM)
+// - the netrc file ($NETRC or ~/.netrc)
+// - the credentials section in ~/.kdeploy/config.yaml
+// It returns nil if no credentials are found
+func CredentialsFor(host string) (*HostCredentials, error) {
+	if creds := credentialsFromEnv(host); creds != nil {
+		log.Debugf("Using credentials for %s from environment", host)
+		return creds, nil
+	}
+	creds, err := credentialsFromNetrc(host)
+	if err != nil {
+		return nil, err
+	}
+	if creds != nil {
+		log.Debugf("Using credentials for %s from netrc", host)
+		return creds, nil
+	}
+	creds, err = credentialsFromConfig(host)
+	if err != nil {
+		return nil, err
+	}
+	if creds != nil {
+		log.Debugf("Using credentials for %s from kdeploy config", host)
+	}
+	return creds, nil
+}
+
+func credentialsFromEnv(host string) *HostCredentials {
+	envHost := strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(host))
+	token := os.Getenv(fmt.Sprintf("KDEPLOY_TOKEN_%s", envHost))
+	if token == "" {
+		return nil
+	}
+	return &HostCredentials{Host: host, Token: token}
+}
+
+func credentialsFromNetrc(host string) (*HostCredentials, error) {
+	path := os.Getenv("NETRC")
+	if path == "" {
+		home, err := homedir.Dir()
+		if err != nil {
+			return nil, nil
+		}
+		path = filepath.Join(home, ".netrc")
+	}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	// netrc tokens can be spread across lines, so we just walk through the words
+	scanner := bufio.NewScanner(f)
+	scanner.Split(bufio.ScanWords)
+	var creds *HostCredentials
+	for scanner.Scan() {
+		switch scanner.Text() {
+		case "machine":
+			if creds != nil {
+				return creds, nil
+			}
+			if scanner.Scan() && scanner.Text() == host {
+				creds = &HostCredentials{Host: host}
+			}
+		case "default":
+			if creds != nil {
+				return creds, nil
+			}
+		case "login":
+			if scanner.Scan() && creds != nil {
+				creds.Username = scanner.Text()
+			}
+		case "password":
+			if scanner.Scan() && creds != nil {
+				creds.Token = scanner.Text()
+			}
+		}
+	}
+	return creds, scanner.Err()
+}
+
+func credentialsFromConfig(host string) (*HostCredentials, error) {
+	dir, err := KdeployDir()
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dir, "config.yaml"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var cfg kdeployConfig
+	err = yaml.Unmarshal(data, &cfg)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse kdeploy config: %v", err)
+	}
+	for _, creds := range cfg.Credentials {
+		if creds.Host == host {
+			c := creds
+			return &c, nil
+		}
+	}
+	return nil, nil
+}
+
+// RedactURL hides the password or token in the user info of a URL, so that it can be logged
+func RedactURL(rawurl string) string {
+	u, err := url.Parse(rawurl)
+	if err != nil || u.User == nil {
+		return rawurl
+	}
+	if _, hasPassword := u.User.Password(); hasPassword {
+		u.User = url.UserPassword(u.User.Username(), "xxxxx")
+	}
+	return u.String()
+}
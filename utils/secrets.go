@@ -0,0 +1,217 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"filippo.io/age"
+	"filippo.io/age/agessh"
+	"filippo.io/age/armor"
+	"golang.org/x/crypto/ssh"
+)
+
+// SecretMask replaces secret values in the output
+const SecretMask = "******"
+
+// minSecretLength is the length below which values are not masked, since masking them would
+// garble the output rather than protect anything
+const minSecretLength = 4
+
+var secrets = struct {
+	sync.Mutex
+	values map[string]bool
+}{values: map[string]bool{}}
+
+// RegisterSecret marks a value, as well as its base64 and JSON encodings, to be masked
+// by MaskSecrets
+func RegisterSecret(value string) {
+	if len(value) < minSecretLength {
+		return
+	}
+	secrets.Lock()
+	defer secrets.Unlock()
+	secrets.values[value] = true
+	secrets.values[base64.StdEncoding.EncodeToString([]byte(value))] = true
+	if encoded, err := json.Marshal(value); err == nil {
+		secrets.values[string(encoded[1:len(encoded)-1])] = true
+	}
+}
+
+// MaskSecrets replaces every registered secret value found in s
+func MaskSecrets(s string) string {
+	secrets.Lock()
+	defer secrets.Unlock()
+	values := make([]string, 0, len(secrets.values))
+	for value := range secrets.values {
+		values = append(values, value)
+	}
+	// longer values first, so that values containing others are masked whole
+	sort.Slice(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+	for _, value := range values {
+		s = strings.Replace(s, value, SecretMask, -1)
+	}
+	return s
+}
+
+// SecretsKeyFile returns the file holding the key encrypted attribute files are decrypted with,
+// which is ~/.kdeploy/secrets.key unless overridden by KDEPLOY_SECRETS_KEY. It holds either an
+// age identity, an OpenSSH ed25519 private key or a base64 encoded ed25519 private key
+func SecretsKeyFile() (string, error) {
+	if file := os.Getenv("KDEPLOY_SECRETS_KEY"); file != "" {
+		return file, nil
+	}
+	dir, err := KdeployDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "secrets.key"), nil
+}
+
+// IsEncrypted tells if data is an age encrypted file, armored or not
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(armor.Header)) || bytes.HasPrefix(data, []byte("age-encryption.org/"))
+}
+
+// Decrypt decrypts an age encrypted file, armored or not, with the secrets key
+func Decrypt(data []byte) ([]byte, error) {
+	identity, _, err := readSecretsKey()
+	if err != nil {
+		return nil, err
+	}
+	var in io.Reader = bytes.NewReader(data)
+	if bytes.HasPrefix(data, []byte(armor.Header)) {
+		in = armor.NewReader(in)
+	}
+	r, err := age.Decrypt(in, identity)
+	if err != nil {
+		return nil, fmt.Errorf("could not decrypt: %v", err)
+	}
+	return ioutil.ReadAll(r)
+}
+
+// Encrypt encrypts data as an armored age file, which can be decrypted with the secrets key and
+// with the keys of the given recipients. Recipients are age public keys, 'ssh-ed25519' public
+// keys, or base64 encoded ed25519 public keys as the trusted ones
+func Encrypt(data []byte, recipients []string) ([]byte, error) {
+	_, own, err := readSecretsKey()
+	if err != nil {
+		return nil, err
+	}
+	all := []age.Recipient{own}
+	for _, r := range recipients {
+		recipient, err := parseRecipient(r)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, recipient)
+	}
+	var out bytes.Buffer
+	a := armor.NewWriter(&out)
+	w, err := age.Encrypt(a, all...)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	if err := a.Close(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// readSecretsKey reads the secrets key, returning it along with the recipient it decrypts for
+func readSecretsKey() (age.Identity, age.Recipient, error) {
+	file, err := SecretsKeyFile()
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not read secrets key: %v", err)
+	}
+	text := strings.TrimSpace(string(data))
+
+	if strings.Contains(text, "AGE-SECRET-KEY-") {
+		identities, err := age.ParseIdentities(strings.NewReader(text))
+		if err != nil {
+			return nil, nil, fmt.Errorf("malformed secrets key in %s: %v", file, err)
+		}
+		identity, ok := identities[0].(*age.X25519Identity)
+		if len(identities) != 1 || !ok {
+			return nil, nil, fmt.Errorf("secrets key in %s must hold a single age identity", file)
+		}
+		return identity, identity.Recipient(), nil
+	}
+
+	var key ed25519.PrivateKey
+	if strings.HasPrefix(text, "-----BEGIN") {
+		raw, err := ssh.ParseRawPrivateKey([]byte(text))
+		if err != nil {
+			return nil, nil, fmt.Errorf("malformed secrets key in %s: %v", file, err)
+		}
+		switch k := raw.(type) {
+		case *ed25519.PrivateKey:
+			key = *k
+		case ed25519.PrivateKey:
+			key = k
+		default:
+			return nil, nil, fmt.Errorf("secrets key in %s is not an ed25519 key", file)
+		}
+	} else {
+		raw, err := base64.StdEncoding.DecodeString(text)
+		switch {
+		case err == nil && len(raw) == ed25519.SeedSize:
+			key = ed25519.NewKeyFromSeed(raw)
+		case err == nil && len(raw) == ed25519.PrivateKeySize:
+			key = ed25519.PrivateKey(raw)
+		default:
+			return nil, nil, fmt.Errorf("malformed secrets key in %s", file)
+		}
+	}
+	identity, err := agessh.NewEd25519Identity(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	recipient, err := ed25519Recipient(key.Public().(ed25519.PublicKey))
+	if err != nil {
+		return nil, nil, err
+	}
+	return identity, recipient, nil
+}
+
+func parseRecipient(s string) (age.Recipient, error) {
+	s = strings.TrimSpace(s)
+	switch {
+	case strings.HasPrefix(s, "age1"):
+		return age.ParseX25519Recipient(s)
+	case strings.HasPrefix(s, "ssh-"):
+		return agessh.ParseRecipient(s)
+	}
+	key, err := base64.StdEncoding.DecodeString(s)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("malformed recipient '%s'", s)
+	}
+	return ed25519Recipient(ed25519.PublicKey(key))
+}
+
+func ed25519Recipient(key ed25519.PublicKey) (age.Recipient, error) {
+	pk, err := ssh.NewPublicKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return agessh.NewEd25519Recipient(pk)
+}
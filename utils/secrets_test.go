@@ -0,0 +1,98 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+)
+
+func TestMaskSecrets(t *testing.T) {
+	RegisterSecret("s3cr3t-password")
+	RegisterSecret("line one\nline \"two\"")
+	RegisterSecret("abc")
+
+	masked := MaskSecrets(`{"password": "s3cr3t-password", "encoded": "czNjcjN0LXBhc3N3b3Jk", "cert": "line one\nline \"two\"", "short": "abc"}`)
+	expected := `{"password": "******", "encoded": "******", "cert": "******", "short": "abc"}`
+	if masked != expected {
+		t.Errorf("expected '%s', got '%s'", expected, masked)
+	}
+}
+
+// withSecretsKey points the secrets key to a file holding key for the duration of a test
+func withSecretsKey(t *testing.T, key string) func() {
+	dir, _ := ioutil.TempDir("", "kdeploy-keys")
+	file := filepath.Join(dir, "secrets.key")
+	ioutil.WriteFile(file, []byte(key), 0600)
+	os.Setenv("KDEPLOY_SECRETS_KEY", file)
+	return func() {
+		os.Unsetenv("KDEPLOY_SECRETS_KEY")
+		os.RemoveAll(dir)
+	}
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	identity, _ := age.GenerateX25519Identity()
+	_, private, _ := ed25519.GenerateKey(rand.Reader)
+	other, _ := age.GenerateX25519Identity()
+	keys := map[string]string{
+		"age":            identity.String() + "\n",
+		"ed25519":        base64.StdEncoding.EncodeToString(private),
+		"ed25519 seed":   base64.StdEncoding.EncodeToString(private.Seed()),
+		"with recipient": identity.String(),
+	}
+
+	for name, key := range keys {
+		restore := withSecretsKey(t, key)
+		recipients := []string{}
+		if name == "with recipient" {
+			recipients = append(recipients, other.Recipient().String())
+		}
+		encrypted, err := Encrypt([]byte(`{"db": {"password": "secret"}}`), recipients)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		if !IsEncrypted(encrypted) || strings.Contains(string(encrypted), "secret") {
+			t.Errorf("%s: expected armored ciphertext, got %s", name, encrypted)
+		}
+		plain, err := Decrypt(encrypted)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		if string(plain) != `{"db": {"password": "secret"}}` {
+			t.Errorf("%s: unexpected plain text %s", name, plain)
+		}
+		restore()
+
+		if name == "with recipient" {
+			restore = withSecretsKey(t, other.String())
+			if _, err := Decrypt(encrypted); err != nil {
+				t.Errorf("expected recipient to decrypt, got %v", err)
+			}
+			restore()
+		}
+	}
+
+	restore := withSecretsKey(t, other.String())
+	defer restore()
+	encrypted, _ := Encrypt([]byte("{}"), nil)
+	ioutil.WriteFile(os.Getenv("KDEPLOY_SECRETS_KEY"), []byte(identity.String()), 0600)
+	if _, err := Decrypt(encrypted); err == nil {
+		t.Errorf("expected decrypting with another key to fail")
+	}
+}
+
+func TestMalformedSecretsKey(t *testing.T) {
+	restore := withSecretsKey(t, "not a key")
+	defer restore()
+	_, err := Encrypt([]byte("{}"), nil)
+	if err == nil || !strings.Contains(err.Error(), "malformed secrets key") {
+		t.Errorf("expected malformed key error, got %v", err)
+	}
+}
@@ -1,19 +1,26 @@
 package utils
 
 import (
+	"archive/tar"
 	"archive/zip"
+	"compress/gzip"
 	"crypto/md5"
+	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/codegangsta/cli"
 )
 
+// instanceName matches DNS labels, which instance names must be since they prefix resource names
+var instanceName = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]{0,61}[a-z0-9])?$`)
+
 func GetMD5Hash(text string) string {
 	hash := md5.Sum([]byte(text))
 	return hex.EncodeToString(hash[:])
@@ -46,36 +53,208 @@ func CheckError(err error) {
 	}
 }
 
+// Unzip extracts a zip archive into dest. Entries that would be extracted outside of dest
+// are rejected
 func Unzip(src, dest string) error {
 	r, err := zip.OpenReader(src)
-	CheckError(err)
+	if err != nil {
+		return fmt.Errorf("could not open zip archive %s: %v", src, err)
+	}
 	defer r.Close()
 
 	for _, f := range r.File {
-		rc, err := f.Open()
-		CheckError(err)
-		defer rc.Close()
-
-		fpath := filepath.Join(dest, f.Name)
+		fpath, err := extractPath(dest, f.Name)
+		if err != nil {
+			return err
+		}
 		if f.FileInfo().IsDir() {
-			os.MkdirAll(fpath, f.Mode())
-		} else {
-			var fdir string
-			if lastIndex := strings.LastIndex(fpath, string(os.PathSeparator)); lastIndex > -1 {
-				fdir = fpath[:lastIndex]
+			err = os.MkdirAll(fpath, 0755)
+			if err != nil {
+				return err
 			}
+			continue
+		}
+		if !f.Mode().IsRegular() {
+			log.Debugf("Skipping zip entry %s since it is not a regular file", f.Name)
+			continue
+		}
+		err = os.MkdirAll(filepath.Dir(fpath), 0755)
+		if err != nil {
+			return err
+		}
+		err = extractZipFile(f, fpath)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-			err = os.MkdirAll(fdir, f.Mode())
-			CheckError(err)
-			f, err := os.OpenFile(fpath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, f.Mode())
-			CheckError(err)
-			defer f.Close()
+// extractZipFile writes a single zip entry, closing both ends before returning
+func extractZipFile(f *zip.File, fpath string) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	out, err := os.OpenFile(fpath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, f.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	_, err = io.Copy(out, rc)
+	return err
+}
+
+// extractPath returns where an archive entry should be extracted, rejecting absolute paths
+// and paths escaping dest (zip-slip)
+func extractPath(dest, name string) (string, error) {
+	if filepath.IsAbs(name) || strings.HasPrefix(name, "/") || strings.HasPrefix(name, `\`) {
+		return "", fmt.Errorf("refusing to extract absolute path %s", name)
+	}
+	fpath := filepath.Join(dest, name)
+	rel, err := filepath.Rel(dest, fpath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("refusing to extract %s outside of the destination directory", name)
+	}
+	return fpath, nil
+}
+
+// Untar extracts a gzipped tarball into dest. Entries that would be extracted outside of dest
+// are rejected
+func Untar(src, dest string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
 
-			_, err = io.Copy(f, rc)
-			CheckError(err)
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("could not read gzip stream from %s: %v", src, err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("could not read tar entry from %s: %v", src, err)
+		}
+
+		fpath, err := extractPath(dest, hdr.Name)
+		if err != nil {
+			return err
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(fpath, 0755)
+			if err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			err = os.MkdirAll(filepath.Dir(fpath), 0755)
+			if err != nil {
+				return err
+			}
+			out, err := os.OpenFile(fpath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, os.FileMode(hdr.Mode).Perm())
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(out, tr)
+			out.Close()
+			if err != nil {
+				return err
+			}
+		default:
+			log.Debugf("Skipping tar entry %s of type %c", hdr.Name, hdr.Typeflag)
 		}
 	}
-	return nil
+}
+
+// CopyDir recursively copies the src directory tree into dest, leaving version control metadata out
+func CopyDir(src, dest string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dest, rel)
+		if info.IsDir() && path != src && vcsDirs[info.Name()] {
+			return filepath.SkipDir
+		}
+		if info.IsDir() {
+			return os.MkdirAll(target, info.Mode().Perm()|0700)
+		}
+		if !info.Mode().IsRegular() {
+			log.Debugf("Skipping %s since it is not a regular file", path)
+			return nil
+		}
+		in, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+		if err != nil {
+			return err
+		}
+		defer out.Close()
+		_, err = io.Copy(out, in)
+		return err
+	})
+}
+
+// vcsDirs are the directories version control systems keep their metadata in, which differs
+// between checkouts of the same contents
+var vcsDirs = map[string]bool{
+	".git": true,
+	".hg":  true,
+	".svn": true,
+	".bzr": true,
+}
+
+// DirDigest calculates a SHA-256 digest of the contents of a directory tree, based on the
+// relative path and contents of every regular file in it. Version control metadata is left out
+func DirDigest(dir string) (string, error) {
+	tree := sha256.New()
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() && path != dir && vcsDirs[info.Name()] {
+			return filepath.SkipDir
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		file := sha256.New()
+		_, err = io.Copy(file, f)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(tree, "%x  %s\n", file.Sum(nil), filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(tree.Sum(nil)), nil
 }
 
 // Keys returns the keys in a map[string]string
@@ -110,3 +289,11 @@ func NormalizeName(name string) (string, error) {
 	}
 	return s, nil
 }
+
+// ValidateInstance checks the name of an instance of a kubeware, which is a DNS label
+func ValidateInstance(instance string) error {
+	if !instanceName.MatchString(instance) {
+		return fmt.Errorf("instance '%s' must be a DNS label of up to 63 lowercase alphanumeric characters or '-'", instance)
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package utils
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeZip(t *testing.T, path string, names ...string) {
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	for _, name := range names {
+		w, _ := zw.Create(name)
+		w.Write([]byte("kind: Service\n"))
+	}
+	zw.Close()
+}
+
+func writeTarball(t *testing.T, path string, names ...string) {
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	for _, name := range names {
+		content := []byte("kind: Service\n")
+		tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg})
+		tw.Write(content)
+	}
+	tw.Close()
+	gz.Close()
+}
+
+func TestExtract(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "kdeploy-extract")
+	defer os.RemoveAll(dir)
+
+	for ext, extract := range map[string]func(string, string) error{".zip": Unzip, ".tgz": Untar} {
+		archive := filepath.Join(dir, "kubeware"+ext)
+		if ext == ".zip" {
+			writeZip(t, archive, "redis/metadata.yaml", "redis/svc/redis-service.yaml")
+		} else {
+			writeTarball(t, archive, "redis/metadata.yaml", "redis/svc/redis-service.yaml")
+		}
+		dest := filepath.Join(dir, "dest"+ext)
+		err := extract(archive, dest)
+		if err != nil {
+			t.Fatalf("could not extract %s: %v", ext, err)
+		}
+		if _, err := os.Stat(filepath.Join(dest, "redis", "svc", "redis-service.yaml")); err != nil {
+			t.Errorf("%s entry not extracted: %v", ext, err)
+		}
+	}
+}
+
+func TestExtractRejectsEscapingPaths(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "kdeploy-extract")
+	defer os.RemoveAll(dir)
+
+	for _, name := range []string{"../evil.yaml", "redis/../../evil.yaml", "/tmp/evil.yaml"} {
+		zipArchive := filepath.Join(dir, "evil.zip")
+		writeZip(t, zipArchive, name)
+		if err := Unzip(zipArchive, filepath.Join(dir, "dest")); err == nil {
+			t.Errorf("Unzip should reject entry %s", name)
+		}
+		tarball := filepath.Join(dir, "evil.tgz")
+		writeTarball(t, tarball, name)
+		if err := Untar(tarball, filepath.Join(dir, "dest")); err == nil {
+			t.Errorf("Untar should reject entry %s", name)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "evil.yaml")); !os.IsNotExist(err) {
+		t.Errorf("entry extracted outside of the destination")
+	}
+}
+
+func TestUnzipMissingArchive(t *testing.T) {
+	if err := Unzip("/nonexistent/kubeware.zip", os.TempDir()); err == nil {
+		t.Errorf("Unzip should fail for missing archives")
+	}
+}
+
+func TestValidateInstance(t *testing.T) {
+	for _, instance := range []string{"blue", "staging-2", "a"} {
+		if err := ValidateInstance(instance); err != nil {
+			t.Errorf("expected %s to be valid, got %v", instance, err)
+		}
+	}
+	for _, instance := range []string{"", "Blue", "-blue", "blue-", "blue_green", "a.b"} {
+		if err := ValidateInstance(instance); err == nil {
+			t.Errorf("expected %s to be invalid", instance)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package webservice
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/flexiant/kdeploy/models"
+	"github.com/flexiant/kdeploy/utils"
+)
+
+// DiscoveryTTL is how long the kinds of resources served by an API server are cached
+var DiscoveryTTL = 10 * time.Minute
+
+// APIResource describes a kind of resource served by the API server
+type APIResource struct {
+	Name       string // plural name used in its REST path, e.g. 'ingresses'
+	Kind       string
+	Namespaced bool
+	Verbs      []string
+}
+
+// discovery holds the resources served by an API server, by group version
+type discovery struct {
+	Endpoint  string
+	Generated time.Time
+	Resources map[string][]APIResource
+	live      bool // read from the API server rather than the cache
+}
+
+func (d *discovery) lookup(kind models.Kind) *APIResource {
+	for _, r := range d.Resources[kind.APIVersion] {
+		if r.Kind == kind.Kind {
+			resource := r
+			return &resource
+		}
+	}
+	return nil
+}
+
+// NamespacedKinds returns the kinds of namespaced resources served by the API server that can be
+// listed, each at a single version of its group, sorted
+func (k *kubeClient) NamespacedKinds() ([]models.Kind, error) {
+	d, err := k.discovered(false)
+	if err != nil {
+		return nil, err
+	}
+	groupVersions := []string{}
+	for gv := range d.Resources {
+		groupVersions = append(groupVersions, gv)
+	}
+	sort.Strings(groupVersions)
+	seen := map[string]bool{}
+	kinds := []models.Kind{}
+	for _, gv := range groupVersions {
+		group := ""
+		if i := strings.Index(gv, "/"); i > -1 {
+			group = gv[:i]
+		}
+		for _, r := range d.Resources[gv] {
+			if !r.Namespaced || !r.listable() || seen[group+"/"+r.Kind] {
+				continue
+			}
+			seen[group+"/"+r.Kind] = true
+			kinds = append(kinds, models.Kind{APIVersion: gv, Kind: r.Kind})
+		}
+	}
+	return kinds, nil
+}
+
+// listable tells if the resource can be listed. Resources cached before verbs were are assumed to be
+func (r APIResource) listable() bool {
+	if r.Verbs == nil {
+		return true
+	}
+	for _, verb := range r.Verbs {
+		if verb == "list" {
+			return true
+		}
+	}
+	return false
+}
+
+// apiPrefix returns the REST path serving an API group version
+func apiPrefix(groupVersion string) string {
+	if !strings.Contains(groupVersion, "/") {
+		return fmt.Sprintf("api/%s", groupVersion)
+	}
+	return fmt.Sprintf("apis/%s", groupVersion)
+}
+
+// resourceFor maps a kind to its REST path prefix and resource. Kinds missing from cached
+// discovery are looked up again in the API server, since they may have been added since
+func (k *kubeClient) resourceFor(kind models.Kind) (string, *APIResource, error) {
+	for _, refresh := range []bool{false, true} {
+		d, err := k.discovered(refresh)
+		if err != nil {
+			return "", nil, err
+		}
+		if r := d.lookup(kind); r != nil {
+			return apiPrefix(kind.APIVersion), r, nil
+		}
+	}
+	return "", nil, fmt.Errorf("kind %s is not served by the API server", kind)
+}
+
+// discovered returns the resources served by the API server, from the cache unless refresh
+// is set
+func (k *kubeClient) discovered(refresh bool) (*discovery, error) {
+	if k.discovery != nil && (!refresh || k.discovery.live) {
+		return k.discovery, nil
+	}
+	cacheFile, err := discoveryCacheFile(k.service.endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if !refresh {
+		if d, err := readDiscovery(cacheFile); err == nil && time.Since(d.Generated) < DiscoveryTTL {
+			k.discovery = d
+			return d, nil
+		}
+	}
+	d, err := k.discover()
+	if err != nil {
+		return nil, err
+	}
+	if err := writeDiscovery(cacheFile, d); err != nil {
+		log.Warnf("Could not cache API discovery: %v", err)
+	}
+	k.discovery = d
+	return d, nil
+}
+
+// discover reads the group versions served by the API server from its discovery endpoints,
+// and the resources served by each of them
+func (k *kubeClient) discover() (*discovery, error) {
+	log.Debugf("Discovering resources served by %s", k.service.endpoint)
+	var core struct{ Versions []string }
+	err := k.getJSON("api", &core)
+	if err != nil {
+		return nil, fmt.Errorf("error discovering API versions: %v", err)
+	}
+	var groups struct {
+		Groups []struct {
+			Versions []struct{ GroupVersion string }
+		}
+	}
+	err = k.getJSON("apis", &groups)
+	if err != nil {
+		return nil, fmt.Errorf("error discovering API groups: %v", err)
+	}
+	groupVersions := core.Versions
+	for _, g := range groups.Groups {
+		for _, v := range g.Versions {
+			groupVersions = append(groupVersions, v.GroupVersion)
+		}
+	}
+
+	d := &discovery{Endpoint: k.service.endpoint, Generated: time.Now(), Resources: map[string][]APIResource{}, live: true}
+	for _, gv := range groupVersions {
+		var list struct{ Resources []APIResource }
+		err := k.getJSON(apiPrefix(gv), &list)
+		if err != nil {
+			// aggregated APIs may be unavailable, which shouldn't prevent using the rest
+			log.Warnf("Skipping API %s: %v", gv, err)
+			continue
+		}
+		for _, r := range list.Resources {
+			// subresources such as 'deployments/scale'
+			if strings.Contains(r.Name, "/") {
+				continue
+			}
+			d.Resources[gv] = append(d.Resources[gv], r)
+		}
+	}
+	return d, nil
+}
+
+func (k *kubeClient) getJSON(path string, v interface{}) error {
+	body, _, err := k.service.Get(path, nil)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, v)
+}
+
+// discoveryCacheFile returns where discovery of an API server is cached
+func discoveryCacheFile(endpoint string) (string, error) {
+	dir, err := utils.KdeployDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "discovery", utils.GetMD5Hash(endpoint)+".json"), nil
+}
+
+func readDiscovery(file string) (*discovery, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var d discovery
+	err = json.Unmarshal(data, &d)
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func writeDiscovery(file string, d *discovery) error {
+	data, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(file), 0700)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, data, 0600)
+}
@@ -0,0 +1,115 @@
+package webservice
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/flexiant/kdeploy/models"
+)
+
+// apiServer fakes the discovery endpoints of an API server, counting the requests received
+func apiServer(requests *int, crd *bool) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests++
+		switch r.URL.Path {
+		case "/api":
+			fmt.Fprint(w, `{"versions":["v1"]}`)
+		case "/apis":
+			groups := `{"groupVersion":"networking.k8s.io/v1"}`
+			if *crd {
+				groups += `]},{"name":"example.com","versions":[{"groupVersion":"example.com/v1"}`
+			}
+			fmt.Fprintf(w, `{"groups":[{"name":"networking.k8s.io","versions":[%s]}]}`, groups)
+		case "/api/v1":
+			fmt.Fprint(w, `{"resources":[{"name":"persistentvolumeclaims","kind":"PersistentVolumeClaim","namespaced":true},{"name":"persistentvolumeclaims/status","kind":"PersistentVolumeClaim","namespaced":true},{"name":"persistentvolumes","kind":"PersistentVolume","namespaced":false}]}`)
+		case "/apis/networking.k8s.io/v1":
+			fmt.Fprint(w, `{"resources":[{"name":"ingresses","kind":"Ingress","namespaced":true}]}`)
+		case "/apis/example.com/v1":
+			fmt.Fprint(w, `{"resources":[{"name":"widgets","kind":"Widget","namespaced":true}]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func TestDiscovery(t *testing.T) {
+	home, err := ioutil.TempDir("", "kdeploy-home")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(home)
+	os.Setenv("KDEPLOY_HOME", home)
+	defer os.Unsetenv("KDEPLOY_HOME")
+
+	requests, crd := 0, false
+	server := apiServer(&requests, &crd)
+	defer server.Close()
+	newClient := func() *kubeClient {
+		return &kubeClient{service: &RestService{client: http.DefaultClient, endpoint: server.URL}}
+	}
+
+	k := newClient()
+	for kind, expected := range map[models.Kind]string{
+		{APIVersion: "v1", Kind: "PersistentVolumeClaim"}:     "api/v1/namespaces/default/persistentvolumeclaims/data",
+		{APIVersion: "v1", Kind: "PersistentVolume"}:          "api/v1/persistentvolumes/data",
+		{APIVersion: "networking.k8s.io/v1", Kind: "Ingress"}: "apis/networking.k8s.io/v1/namespaces/default/ingresses/data",
+	} {
+		path, err := k.kindPath(kind, "default", "data")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if path != expected {
+			t.Errorf("expected path %s for %s, got %s", expected, kind, path)
+		}
+	}
+	if requests != 4 {
+		t.Errorf("expected discovery to be done once, got %d requests", requests)
+	}
+
+	// a new client reads discovery from the cache
+	requests = 0
+	k = newClient()
+	if _, err := k.kindPath(models.Kind{APIVersion: "v1", Kind: "PersistentVolume"}, "", "data"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if requests != 0 {
+		t.Errorf("expected cached discovery to be used, got %d requests", requests)
+	}
+
+	// kinds missing from the cache are discovered again
+	crd = true
+	path, err := k.kindPath(models.Kind{APIVersion: "example.com/v1", Kind: "Widget"}, "default", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if path != "apis/example.com/v1/namespaces/default/widgets" {
+		t.Errorf("unexpected path for custom resource: %s", path)
+	}
+
+	// unknown kinds are only discovered again once
+	requests = 0
+	if _, err := k.kindPath(models.Kind{APIVersion: "example.com/v1", Kind: "Gadget"}, "default", ""); err == nil {
+		t.Errorf("expected an error for a kind not served")
+	}
+	if requests != 0 {
+		t.Errorf("expected discovery not to be repeated, got %d requests", requests)
+	}
+
+	// namespaced kinds are those that can be listed in a namespace, cluster-wide ones aside
+	kinds, err := k.NamespacedKinds()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := fmt.Sprint([]models.Kind{
+		{APIVersion: "example.com/v1", Kind: "Widget"},
+		{APIVersion: "networking.k8s.io/v1", Kind: "Ingress"},
+		{APIVersion: "v1", Kind: "PersistentVolumeClaim"},
+	})
+	if fmt.Sprint(kinds) != expected {
+		t.Errorf("expected namespaced kinds %s, got %s", expected, kinds)
+	}
+}
@@ -16,7 +16,7 @@ var ErrNotFound = errors.New("Object not found")
 
 // KubeClient interface for a custom Kubernetes API client
 type KubeClient interface {
-	FindDeployedKubewareVersion(namespace, kubeName string) (string, error)
+	FindDeployedKubewareVersion(namespace, kubeName, instance string, kinds ...models.Kind) (string, error)
 	GetControllers(labelSelector ...string) (*[]models.ReplicaController, error)                               // GetControllers gets deployed replication controllers that match the labels specified
 	GetControllersForNamespace(namespace string, labelSelector ...string) (*[]models.ReplicaController, error) // GetControllers gets deployed replication controllers that match the labels specified
 	GetServices(labelSelector ...string) (*[]models.Service, error)                                            // GetServices gets deployed services that match the labels specified
@@ -30,17 +30,44 @@ type KubeClient interface {
 	SetSpecReplicas(namespace, rcName string, nreplicas uint) error
 	GetSpecReplicas(namespace, rcName string) (uint, error)
 	GetStatusReplicas(namespace, rcName string) (uint, error)
+	GetReadyReplicas(namespace, rcName string) (uint, error)
 	IsServiceDeployed(namespace, svcName string) (bool, error)
 	ReplaceReplicationController(namespace, rcName, rcJSON string) error
 	ReplaceService(namespace, svcName, svcJSON string) error
 	GetPodsForNamespace(namespace, labelSelector string) (*[]models.Pod, error)
 	GetPodsForController(namespace, rcName string) (*[]models.Pod, error)
 	PatchService(namespace, svcName, svcJSON string) error
+	GetDeployments(labelSelector ...string) (*[]models.Deployment, error)
+	GetDeploymentsForNamespace(namespace string, labelSelector ...string) (*[]models.Deployment, error)
+	CreateDeployment(namespace string, spec []byte) (string, error)
+	CreateDeployments(specs []string) error
+	ReplaceDeployment(namespace, name, spec string) error
+	DeleteDeployment(namespace, name string) error
+	GetConfigMaps(labelSelector ...string) (*[]models.ConfigMap, error)
+	GetConfigMapsForNamespace(namespace string, labelSelector ...string) (*[]models.ConfigMap, error)
+	CreateConfigMap(namespace string, spec []byte) (string, error)
+	CreateConfigMaps(specs []string) error
+	ReplaceConfigMap(namespace, name, spec string) error
+	DeleteConfigMap(namespace, name string) error
+	GetSecrets(labelSelector ...string) (*[]models.Secret, error)
+	GetSecretsForNamespace(namespace string, labelSelector ...string) (*[]models.Secret, error)
+	CreateSecret(namespace string, spec []byte) (string, error)
+	CreateSecrets(specs []string) error
+	ReplaceSecret(namespace, name, spec string) error
+	DeleteSecret(namespace, name string) error
+	GetResourcesForNamespace(namespace string, kind models.Kind, labelSelector ...string) (*[]models.Resource, error)
+	CreateResource(namespace string, spec []byte) (string, error)
+	CreateResources(specs []string) error
+	ReplaceResource(namespace, name, spec string) error
+	DeleteResource(namespace string, kind models.Kind, name string) error
+	GetObject(namespace string, kind models.Kind, name string) ([]byte, error) // GetObject gets the JSON representation of a single object of any kind
+	NamespacedKinds() ([]models.Kind, error)                                   // NamespacedKinds gets the kinds of namespaced resources that can be listed
 }
 
 // kubeClient implements KubeClient interface
 type kubeClient struct {
-	service *RestService
+	service   *RestService
+	discovery *discovery
 }
 
 // NewKubeClient builds a KubeClient object
@@ -234,6 +261,21 @@ func (k *kubeClient) GetStatusReplicas(namespace, controller string) (uint, erro
 	return rc.Status.Replicas, nil
 }
 
+// GetReadyReplicas counts the pods of a replication controller which are ready
+func (k *kubeClient) GetReadyReplicas(namespace, controller string) (uint, error) {
+	pods, err := k.GetPodsForController(namespace, controller)
+	if err != nil {
+		return 0, err
+	}
+	var count uint
+	for _, p := range *pods {
+		if p.IsReady() {
+			count++
+		}
+	}
+	return count, nil
+}
+
 func (k *kubeClient) getController(namespace, controller string) (string, error) {
 	path := fmt.Sprintf("api/v1/namespaces/%s/replicationcontrollers/%s", namespace, controller)
 	rcJSON, status, err := k.service.Get(path, nil)
@@ -309,53 +351,30 @@ func (k *kubeClient) CreateReplicaControllers(rcSpecs []string) error {
 	return nil
 }
 
-func (k *kubeClient) FindDeployedKubewareVersion(namespace, name string) (string, error) {
+// FindDeployedKubewareVersion returns the version of an instance of the kubeware deployed in the
+// namespace, the default one if instance is empty, looking for it among the resources of the
+// given kinds, those the kubeware declares
+func (k *kubeClient) FindDeployedKubewareVersion(namespace, name, instance string, kinds ...models.Kind) (string, error) {
 	kubename, err := utils.NormalizeName(name)
 	if err != nil {
 		return "", err
 	}
-	versions := map[string]string{}
-	services, err := k.GetServicesForNamespace(namespace)
-	if err != nil {
-		return "", err
-	}
-	controllers, err := k.GetControllersForNamespace(namespace)
-	if err != nil {
-		return "", err
-	}
-	// Iterate over services and collect versions
-	for _, s := range *services {
-		n := s.GetKube()
-		v := s.GetVersion()
-		prev, found := versions[n]
-		// Check if version already found
-		if !found {
-			versions[n] = v
-		}
-		// Check if a different version was already found
-		if found && prev != v {
-			return "", fmt.Errorf("found more than one version of the same Kubeware (%s.%s %s/%s)", namespace, kubename, prev, v)
-		}
-	}
-	// Iterate over controllers and collect versions
-	for _, c := range *controllers {
-		n := c.GetKube()
-		v := c.GetVersion()
-		prev, found := versions[n]
-		// Check if version already found
-		if !found {
-			versions[n] = v
+	labelSelector := fmt.Sprintf("kubeware=%s,%s", kubename, models.InstanceSelector(instance))
+	version := ""
+	for _, kind := range kinds {
+		resources, err := k.GetResourcesForNamespace(namespace, kind, labelSelector)
+		if err != nil {
+			return "", err
 		}
-		// Check if a different version was already found
-		if found && prev != v {
-			return "", fmt.Errorf("found more than one version of the same Kubeware (%s.%s %s/%s)", namespace, kubename, prev, v)
+		for _, r := range *resources {
+			// Check if a different version was already found
+			if version != "" && r.GetVersion() != version {
+				return "", fmt.Errorf("found more than one version of the same Kubeware (%s.%s %s/%s)", namespace, kubename, version, r.GetVersion())
+			}
+			version = r.GetVersion()
 		}
 	}
-	v, found := versions[kubename]
-	if !found {
-		return "", nil
-	}
-	return v, nil
+	return version, nil
 }
 
 func (k *kubeClient) IsServiceDeployed(namespace, svcName string) (bool, error) {
@@ -0,0 +1,313 @@
+package webservice
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/flexiant/kdeploy/models"
+)
+
+// API groups serving the kinds of resources a kubeware can hold
+const (
+	coreAPI = "api/v1"
+	appsAPI = "apis/apps/v1"
+)
+
+// resourcePath builds the REST path for a kind of resource, optionally within a namespace and
+// for a single named resource
+func resourcePath(api, resource, namespace, name string) string {
+	path := api
+	if namespace != "" {
+		path = fmt.Sprintf("%s/namespaces/%s", path, namespace)
+	}
+	path = fmt.Sprintf("%s/%s", path, resource)
+	if name != "" {
+		path = fmt.Sprintf("%s/%s", path, name)
+	}
+	return path
+}
+
+func (k *kubeClient) listResources(api, resource, namespace string, labelSelector []string) ([]byte, error) {
+	if len(labelSelector) > 1 {
+		return nil, fmt.Errorf("too many parameters")
+	}
+	params := map[string]string{"pretty": "true"}
+	if len(labelSelector) > 0 {
+		params["labelSelector"] = labelSelector[0]
+	}
+	json, _, err := k.service.Get(resourcePath(api, resource, namespace, ""), params)
+	if err != nil {
+		return nil, fmt.Errorf("error getting %s: %s", resource, err)
+	}
+	return json, nil
+}
+
+func (k *kubeClient) createResource(api, resource, namespace string, spec []byte) (string, error) {
+	json, status, err := k.service.Post(resourcePath(api, resource, namespace, ""), spec)
+	if err != nil {
+		return "", fmt.Errorf("error creating %s: %s", resource, err)
+	}
+	if status != 200 && status != 201 {
+		return "", fmt.Errorf("error creating %s: wrong http status code: %v (body: %s)", resource, status, json)
+	}
+	return string(json), nil
+}
+
+func (k *kubeClient) createResources(api, resource string, specs []string) error {
+	for _, spec := range specs {
+		_, err := k.createResource(api, resource, os.Getenv("KDEPLOY_NAMESPACE"), []byte(spec))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (k *kubeClient) replaceResource(api, resource, namespace, name, spec string) error {
+	body, status, err := k.service.Put(resourcePath(api, resource, namespace, name), []byte(spec))
+	if err != nil {
+		return err
+	}
+	if status == 404 {
+		return ErrNotFound
+	}
+	if status != 200 && status != 201 {
+		return fmt.Errorf("error replacing %s: wrong http status code: %v (body: %s)", resource, status, body)
+	}
+	return nil
+}
+
+func (k *kubeClient) deleteResource(api, resource, namespace, name string) error {
+	_, status, err := k.service.Delete(resourcePath(api, resource, namespace, name))
+	if err != nil {
+		return fmt.Errorf("error deleting %s: %s", resource, err)
+	}
+	if status != 200 && status != 202 {
+		return fmt.Errorf("error deleting %s: wrong http status code: %v", resource, status)
+	}
+	return nil
+}
+
+// GetDeployments retrieves existing deployments in every namespace
+func (k *kubeClient) GetDeployments(labelSelector ...string) (*[]models.Deployment, error) {
+	return k.GetDeploymentsForNamespace("", labelSelector...)
+}
+
+func (k *kubeClient) GetDeploymentsForNamespace(namespace string, labelSelector ...string) (*[]models.Deployment, error) {
+	json, err := k.listResources(appsAPI, "deployments", namespace, labelSelector)
+	if err != nil {
+		return nil, err
+	}
+	return models.NewDeploymentsJSON(string(json))
+}
+
+// CreateDeployment creates a deployment as specified in the json doc received as argument
+func (k *kubeClient) CreateDeployment(namespace string, spec []byte) (string, error) {
+	return k.createResource(appsAPI, "deployments", namespace, spec)
+}
+
+func (k *kubeClient) CreateDeployments(specs []string) error {
+	return k.createResources(appsAPI, "deployments", specs)
+}
+
+// ReplaceDeployment replaces a deployment, which rolls its pods to the new spec
+func (k *kubeClient) ReplaceDeployment(namespace, name, spec string) error {
+	return k.replaceResource(appsAPI, "deployments", namespace, name, spec)
+}
+
+// DeleteDeployment deletes a deployment, along with its replica sets and pods
+func (k *kubeClient) DeleteDeployment(namespace, name string) error {
+	return k.deleteResource(appsAPI, "deployments", namespace, name)
+}
+
+// GetConfigMaps retrieves existing config maps in every namespace
+func (k *kubeClient) GetConfigMaps(labelSelector ...string) (*[]models.ConfigMap, error) {
+	return k.GetConfigMapsForNamespace("", labelSelector...)
+}
+
+func (k *kubeClient) GetConfigMapsForNamespace(namespace string, labelSelector ...string) (*[]models.ConfigMap, error) {
+	json, err := k.listResources(coreAPI, "configmaps", namespace, labelSelector)
+	if err != nil {
+		return nil, err
+	}
+	return models.NewConfigMapsJSON(string(json))
+}
+
+// CreateConfigMap creates a config map as specified in the json doc received as argument
+func (k *kubeClient) CreateConfigMap(namespace string, spec []byte) (string, error) {
+	return k.createResource(coreAPI, "configmaps", namespace, spec)
+}
+
+func (k *kubeClient) CreateConfigMaps(specs []string) error {
+	return k.createResources(coreAPI, "configmaps", specs)
+}
+
+func (k *kubeClient) ReplaceConfigMap(namespace, name, spec string) error {
+	return k.replaceResource(coreAPI, "configmaps", namespace, name, spec)
+}
+
+func (k *kubeClient) DeleteConfigMap(namespace, name string) error {
+	return k.deleteResource(coreAPI, "configmaps", namespace, name)
+}
+
+// GetSecrets retrieves existing secrets in every namespace
+func (k *kubeClient) GetSecrets(labelSelector ...string) (*[]models.Secret, error) {
+	return k.GetSecretsForNamespace("", labelSelector...)
+}
+
+func (k *kubeClient) GetSecretsForNamespace(namespace string, labelSelector ...string) (*[]models.Secret, error) {
+	json, err := k.listResources(coreAPI, "secrets", namespace, labelSelector)
+	if err != nil {
+		return nil, err
+	}
+	return models.NewSecretsJSON(string(json))
+}
+
+// CreateSecret creates a secret as specified in the json doc received as argument
+func (k *kubeClient) CreateSecret(namespace string, spec []byte) (string, error) {
+	return k.createResource(coreAPI, "secrets", namespace, spec)
+}
+
+func (k *kubeClient) CreateSecrets(specs []string) error {
+	return k.createResources(coreAPI, "secrets", specs)
+}
+
+func (k *kubeClient) ReplaceSecret(namespace, name, spec string) error {
+	return k.replaceResource(coreAPI, "secrets", namespace, name, spec)
+}
+
+func (k *kubeClient) DeleteSecret(namespace, name string) error {
+	return k.deleteResource(coreAPI, "secrets", namespace, name)
+}
+
+// kindPath builds the REST path for a kind of resource found through API discovery. The
+// namespace is ignored for cluster scoped kinds
+func (k *kubeClient) kindPath(kind models.Kind, namespace, name string) (string, error) {
+	api, resource, err := k.resourceFor(kind)
+	if err != nil {
+		return "", err
+	}
+	if !resource.Namespaced {
+		namespace = ""
+	}
+	return resourcePath(api, resource.Name, namespace, name), nil
+}
+
+// SpecKind reads the kind of a manifest
+func SpecKind(spec []byte) (models.Kind, error) {
+	var manifest struct {
+		APIVersion string
+		Kind       string
+	}
+	err := json.Unmarshal(spec, &manifest)
+	if err != nil {
+		return models.Kind{}, err
+	}
+	if manifest.APIVersion == "" || manifest.Kind == "" {
+		return models.Kind{}, fmt.Errorf("manifest must have an apiVersion and a kind")
+	}
+	return models.Kind{APIVersion: manifest.APIVersion, Kind: manifest.Kind}, nil
+}
+
+// GetResourcesForNamespace retrieves existing resources of any kind served by the API server
+func (k *kubeClient) GetResourcesForNamespace(namespace string, kind models.Kind, labelSelector ...string) (*[]models.Resource, error) {
+	api, resource, err := k.resourceFor(kind)
+	if err != nil {
+		return nil, err
+	}
+	if !resource.Namespaced {
+		namespace = ""
+	}
+	json, err := k.listResources(api, resource.Name, namespace, labelSelector)
+	if err != nil {
+		return nil, err
+	}
+	return models.NewResourcesJSON(string(json), kind)
+}
+
+// CreateResource creates a resource of the kind given in the json doc received as argument
+func (k *kubeClient) CreateResource(namespace string, spec []byte) (string, error) {
+	kind, err := SpecKind(spec)
+	if err != nil {
+		return "", err
+	}
+	path, err := k.kindPath(kind, namespace, "")
+	if err != nil {
+		return "", err
+	}
+	json, status, err := k.service.Post(path, spec)
+	if err != nil {
+		return "", fmt.Errorf("error creating %s: %s", kind, err)
+	}
+	if status != 200 && status != 201 {
+		return "", fmt.Errorf("error creating %s: wrong http status code: %v (body: %s)", kind, status, json)
+	}
+	return string(json), nil
+}
+
+func (k *kubeClient) CreateResources(specs []string) error {
+	for _, spec := range specs {
+		_, err := k.CreateResource(os.Getenv("KDEPLOY_NAMESPACE"), []byte(spec))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReplaceResource replaces a resource of the kind given in the json doc received as argument
+func (k *kubeClient) ReplaceResource(namespace, name, spec string) error {
+	kind, err := SpecKind([]byte(spec))
+	if err != nil {
+		return err
+	}
+	path, err := k.kindPath(kind, namespace, name)
+	if err != nil {
+		return err
+	}
+	body, status, err := k.service.Put(path, []byte(spec))
+	if err != nil {
+		return err
+	}
+	if status == 404 {
+		return ErrNotFound
+	}
+	if status != 200 && status != 201 {
+		return fmt.Errorf("error replacing %s: wrong http status code: %v (body: %s)", kind, status, body)
+	}
+	return nil
+}
+
+// GetObject retrieves an object of any kind served by the API server, returning ErrNotFound if
+// it doesn't exist
+func (k *kubeClient) GetObject(namespace string, kind models.Kind, name string) ([]byte, error) {
+	path, err := k.kindPath(kind, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	json, status, err := k.service.Get(path, nil)
+	if status == 404 {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting %s %s: %s", kind.Kind, name, err)
+	}
+	return json, nil
+}
+
+// DeleteResource deletes a resource of any kind served by the API server
+func (k *kubeClient) DeleteResource(namespace string, kind models.Kind, name string) error {
+	path, err := k.kindPath(kind, namespace, name)
+	if err != nil {
+		return err
+	}
+	_, status, err := k.service.Delete(path)
+	if err != nil {
+		return fmt.Errorf("error deleting %s: %s", kind, err)
+	}
+	if status != 200 && status != 202 {
+		return fmt.Errorf("error deleting %s: wrong http status code: %v", kind, status)
+	}
+	return nil
+}
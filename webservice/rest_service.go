@@ -20,8 +20,9 @@ import (
 )
 
 type RestService struct {
-	client   *http.Client
-	endpoint string
+	client      *http.Client
+	endpoint    string
+	credentials *utils.HostCredentials
 }
 
 func NewRestService(config utils.Config) (*RestService, error) {
@@ -34,10 +35,26 @@ func NewRestService(config utils.Config) (*RestService, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &RestService{client, endpoint.String()}, nil
+	return &RestService{client: client, endpoint: endpoint.String()}, nil
 }
 
+// NewSimpleWebClient builds a RestService for plain http(s) downloads, authenticated with the
+// credentials found for the URL host, if any
 func NewSimpleWebClient(httpUrl string) (*RestService, error) {
+	parsedUrl, err := url.Parse(httpUrl)
+	if err != nil {
+		return nil, err
+	}
+	credentials, err := utils.CredentialsFor(parsedUrl.Hostname())
+	if err != nil {
+		return nil, fmt.Errorf("could not read credentials for %s: %v", parsedUrl.Hostname(), err)
+	}
+	return NewSimpleWebClientWithCredentials(httpUrl, credentials)
+}
+
+// NewSimpleWebClientWithCredentials builds a RestService for plain http(s) downloads,
+// authenticated with the given credentials
+func NewSimpleWebClientWithCredentials(httpUrl string, credentials *utils.HostCredentials) (*RestService, error) {
 	parsedUrl, err := url.Parse(httpUrl)
 	if err != nil {
 		return nil, err
@@ -45,7 +62,7 @@ func NewSimpleWebClient(httpUrl string) (*RestService, error) {
 	transport := &http.Transport{}
 	client := &http.Client{Transport: transport}
 
-	return &RestService{client, parsedUrl.String()}, nil
+	return &RestService{client: client, endpoint: parsedUrl.String(), credentials: credentials}, nil
 }
 
 func httpClient(config utils.Config) (*http.Client, error) {
@@ -106,10 +123,10 @@ func (r *RestService) Post(urlPath string, json []byte) ([]byte, int, error) {
 	output := strings.NewReader(string(json))
 
 	if os.Getenv("KDEPLOY_DRYRUN") == "1" {
-		log.Infof("Post request url: %s , body:\n%s", loc.String(), string(prettyprint(json)))
+		log.Infof("Post request url: %s , body:\n%s", loc.String(), utils.MaskSecrets(string(prettyprint(json))))
 		return nil, 200, nil
 	} else {
-		log.Debugf("Post request url: %s , body:\n%s", loc.String(), string(prettyprint(json)))
+		log.Debugf("Post request url: %s , body:\n%s", loc.String(), utils.MaskSecrets(string(prettyprint(json))))
 	}
 
 	response, err := r.client.Post(loc.String(), "application/json", output)
@@ -131,10 +148,10 @@ func (r *RestService) Put(urlPath string, json []byte) ([]byte, int, error) {
 	loc.Path = urlPath
 
 	if os.Getenv("KDEPLOY_DRYRUN") == "1" {
-		log.Infof("Put request url: %s , body:\n%s", loc.String(), string(prettyprint(json)))
+		log.Infof("Put request url: %s , body:\n%s", loc.String(), utils.MaskSecrets(string(prettyprint(json))))
 		return nil, 200, nil
 	}
-	log.Debugf("Put request url: %s , body:\n%s", loc.String(), string(prettyprint(json)))
+	log.Debugf("Put request url: %s , body:\n%s", loc.String(), utils.MaskSecrets(string(prettyprint(json))))
 
 	request, err := http.NewRequest("PUT", loc.String(), bytes.NewBuffer(json))
 	if err != nil {
@@ -160,10 +177,10 @@ func (r *RestService) Patch(urlPath string, json []byte) ([]byte, int, error) {
 	loc.Path = urlPath
 
 	if os.Getenv("KDEPLOY_DRYRUN") == "1" {
-		log.Infof("Patch request url: %s , body:\n%s", loc.String(), string(prettyprint(json)))
+		log.Infof("Patch request url: %s , body:\n%s", loc.String(), utils.MaskSecrets(string(prettyprint(json))))
 		return nil, 200, nil
 	} else {
-		log.Debugf("Patch request url: %s , body:\n%s", loc.String(), string(prettyprint(json)))
+		log.Debugf("Patch request url: %s , body:\n%s", loc.String(), utils.MaskSecrets(string(prettyprint(json))))
 	}
 
 	request, err := http.NewRequest("PATCH", loc.String(), bytes.NewBuffer(json))
@@ -251,24 +268,40 @@ func (r *RestService) Get(urlPath string, params map[string]string) ([]byte, int
 
 func (r *RestService) GetFile(urlPath string, directory string) (string, error) {
 	loc, _ := url.Parse(r.endpoint)
-	loc.Path = urlPath
+	// the path may carry a query, such as the signature of a pre-authenticated URL
+	ref, err := url.Parse(urlPath)
+	if err != nil {
+		return "", err
+	}
+	loc.Path, loc.RawPath, loc.RawQuery = ref.Path, ref.RawPath, ref.RawQuery
 
+	// queries may hold credentials too, so they are not logged
+	shown := *loc
+	if shown.RawQuery != "" {
+		shown.RawQuery = "xxxxx"
+	}
 	if os.Getenv("KDEPLOY_DRYRUN") == "1" {
-		log.Infof("Get file request url: %s destination: %s", loc.String(), directory)
+		log.Infof("Get file request url: %s destination: %s", utils.RedactURL(shown.String()), directory)
 	} else {
-		log.Debugf("Get file request url: %s destination: %s", loc.String(), directory)
+		log.Debugf("Get file request url: %s destination: %s", utils.RedactURL(shown.String()), directory)
 	}
 
-	response, err := r.client.Get(loc.String())
+	request, err := http.NewRequest("GET", loc.String(), nil)
 	if err != nil {
 		return "", err
 	}
+	r.authenticate(request)
+	response, err := r.client.Do(request)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
 
 	if response.StatusCode >= 300 {
 		return "", errors.New(fmt.Sprintf("Obtained %d response code for downloading file", response.StatusCode))
 	}
 
-	slice := strings.Split(urlPath, "/")
+	slice := strings.Split(ref.Path, "/")
 	fileName := slice[len(slice)-1:][0]
 
 	filePath := path.Join(directory, fileName)
@@ -279,6 +312,22 @@ func (r *RestService) GetFile(urlPath string, directory string) (string, error)
 	}
 
 	defer out.Close()
-	io.Copy(out, response.Body)
+	_, err = io.Copy(out, response.Body)
+	if err != nil {
+		return "", err
+	}
 	return filePath, nil
 }
+
+// authenticate adds the credentials, if any, to the request. Tokens with a username are sent
+// using basic auth, and as bearer tokens otherwise. They are never logged
+func (r *RestService) authenticate(request *http.Request) {
+	if r.credentials == nil || r.credentials.Token == "" {
+		return
+	}
+	if r.credentials.Username != "" {
+		request.SetBasicAuth(r.credentials.Username, r.credentials.Token)
+	} else {
+		request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", r.credentials.Token))
+	}
+}